@@ -7,7 +7,9 @@ package cmd
 
 import (
 	"fmt"
+	"math/big"
 	"net"
+	"net/netip"
 	"strings"
 
 	"github.com/spf13/cobra"
@@ -20,18 +22,41 @@ var ipCmd = &cobra.Command{
 	Use:   "ip",
 	Short: "CIDR mask conversion",
 	Long: `
-CIDR mask conversion. For example:
+CIDR mask conversion. The mask may be a prefix length, a dotted subnet mask,
+a Cisco-style wildcard mask or a hex mask as printed by ifconfig.
 
-	macconv ip 192.168.1.1/24`,
+Several inputs may be given at once, as arguments, with --file or on stdin
+("-"), one result block per input. Bare addresses are treated as /32 or /128,
+10.0.0.* as the matching prefix, and ranges such as 10.0.0.1-50 or
+10.0.0.1-10.0.3.7 are shown with the CIDR blocks that cover them. For example:
+
+	macconv ip 192.168.1.1/24
+	macconv ip 192.168.1.1/255.255.255.0
+	macconv ip 192.168.1.1 255.255.255.0
+	macconv ip 192.168.1.0 0.0.0.255
+	macconv ip 192.168.1.1 0xffffff00
+	macconv ip 192.168.1.130/25 --binary
+	macconv ip 10.0.0.* 10.0.0.1-50 192.0.2.7 2001:db8::/48
+	cat addresses.txt | macconv ip -`,
 	Run: convertIPAddress,
 }
 
 func init() {
 	rootCmd.AddCommand(ipCmd)
+	ipCmd.Flags().BoolP("binary", "b", false, "Show a binary breakdown with network, subnet and host bits")
+	ipCmd.Flags().StringP("file", "f", "", "Read inputs from a file, one per line (\"-\" for stdin)")
 }
 
 func convertIPAddress(cmd *cobra.Command, args []string) {
-	if len(args) != 1 {
+	file, _ := cmd.Flags().GetString("file")
+	binary, _ := cmd.Flags().GetBool("binary")
+
+	// 单独的 "-" 参数表示从标准输入读取
+	if len(args) == 1 && args[0] == "-" && file == "" {
+		args, file = nil, "-"
+	}
+
+	if len(args) == 0 && file == "" {
 		logger.PrintValidationError("missing CIDR address argument")
 		if err := cmd.Help(); err != nil {
 			logger.PrintErrorWithMessage("failed to show help", err)
@@ -39,38 +64,105 @@ func convertIPAddress(cmd *cobra.Command, args []string) {
 		return
 	}
 
-	cidr := args[0]
-	logger.Debugf("Processing CIDR address: %s", cidr)
+	groups := splitIPArgs(args)
+	inputs := make([]ipInput, 0, len(groups))
+	for _, group := range groups {
+		in, err := parseIPInput(group)
+		if err != nil {
+			logger.PrintErrorWithMessage(fmt.Sprintf("invalid input %s", strings.Join(group, " ")), err)
+			if len(groups) == 1 && file == "" {
+				if err := cmd.Help(); err != nil {
+					logger.PrintErrorWithMessage("failed to show help", err)
+				}
+				return
+			}
+			continue
+		}
+		inputs = append(inputs, in)
+	}
+
+	if file != "" {
+		fromFile, err := readIPInputFile(file)
+		if err != nil {
+			logger.PrintErrorWithMessage("failed to read input list", err)
+			return
+		}
+		inputs = append(inputs, fromFile...)
+	}
 
-	info, err := calculateCIDRInfo(cidr)
+	for i, in := range inputs {
+		if len(inputs) > 1 {
+			if i > 0 {
+				fmt.Println()
+			}
+			fmt.Println("Input:", in)
+		}
+		printIPInput(in, binary)
+	}
+}
+
+// printIPInput 输出一条输入的结果：网段输出完整的 CIDR 信息，地址范围输出覆盖它的 CIDR
+func printIPInput(in ipInput, binary bool) {
+	if in.CIDR == "" {
+		printRangeInfo(in.Range)
+		logger.Infof("Successfully processed address range: %s - %s", in.Range.First, in.Range.Last)
+		return
+	}
+	logger.Debugf("Processing CIDR address: %s", in.CIDR)
+
+	info, err := calculateCIDRInfo(in.CIDR)
 	if err != nil {
 		logger.PrintErrorWithMessage("failed to parse CIDR address", err)
-		if err := cmd.Help(); err != nil {
-			logger.PrintErrorWithMessage("failed to show help", err)
-		}
 		return
 	}
 
+	printCIDRInfo(info)
+	if prefix, err := netip.ParsePrefix(in.CIDR); err == nil {
+		printAddressClass(classifyPrefix(prefix.Masked()))
+		if binary {
+			printBinaryBreakdown(prefix)
+		}
+	}
+
+	logger.Infof("Successfully processed CIDR address: %s", in.CIDR)
+}
+
+func printCIDRInfo(info *CIDRInfo) {
 	fmt.Println("CIDR Address Range:", info.FirstIP, "-", info.LastIP)
 	fmt.Println("Subnet Mask:", info.SubnetMask)
 	fmt.Println("Inverse Mask:", info.InverseMask)
 	fmt.Println("Network ID:", info.NetworkID)
 
 	// IPv6 没有广播地址概念
-	if strings.Contains(info.NetworkID, ":") {
+	if info.IsIPv6 {
 		fmt.Println("Network Type: IPv6")
+		fmt.Println("Subnet-Router Anycast:", info.SubnetRouterAnycast)
+		if info.ReservedAnycastFirst != "" {
+			fmt.Println("Reserved Anycast Range:", info.ReservedAnycastFirst, "-", info.ReservedAnycastLast)
+		}
+		if info.Subnets48 != nil {
+			fmt.Println("/48 Subnets:", formatCount(info.Subnets48))
+		}
+		if info.Subnets64 != nil {
+			fmt.Println("/64 Subnets:", formatCount(info.Subnets64))
+		}
 	} else {
 		fmt.Println("Broadcast Address:", info.BroadcastAddress)
 	}
 
-	// 显示主机数
-	if info.TotalHosts == -1 {
-		fmt.Println("Total Hosts: Very large number (>2^63)")
-	} else {
-		fmt.Println("Total Hosts:", info.TotalHosts)
-	}
+	fmt.Println("Total Addresses:", formatCount(info.TotalAddresses))
+	fmt.Println("Total Hosts:", formatCount(info.TotalHosts))
+}
 
-	logger.Infof("Successfully processed CIDR address: %s", cidr)
+// formatCount 输出精确数值，2 的整数次幂时附带 2^n 形式
+func formatCount(n *big.Int) string {
+	if n.Sign() > 0 && n.BitLen() > 1 {
+		exp := n.BitLen() - 1
+		if n.Cmp(powerOfTwo(exp)) == 0 {
+			return fmt.Sprintf("%s (2^%d)", n.String(), exp)
+		}
+	}
+	return n.String()
 }
 
 // CIDRInfo 包含 CIDR 网络信息
@@ -81,7 +173,17 @@ type CIDRInfo struct {
 	BroadcastAddress string
 	SubnetMask       string
 	InverseMask      string
-	TotalHosts       int
+	PrefixLength     int
+	TotalAddresses   *big.Int
+	TotalHosts       *big.Int
+
+	// 以下字段仅对 IPv6 有效
+	IsIPv6               bool
+	SubnetRouterAnycast  string
+	ReservedAnycastFirst string
+	ReservedAnycastLast  string
+	Subnets48            *big.Int
+	Subnets64            *big.Int
 }
 
 func calculateCIDRInfo(cidr string) (*CIDRInfo, error) {
@@ -96,19 +198,34 @@ func calculateCIDRInfo(cidr string) (*CIDRInfo, error) {
 	// 计算网络号
 	network := ip.Mask(ipnet.Mask)
 
+	// 计算反掩码
+	inverseMask := calculateInverseMask(ipnet.Mask)
+
+	info := &CIDRInfo{
+		NetworkID:      network.String(),
+		SubnetMask:     net.IP(ipnet.Mask).String(),
+		InverseMask:    inverseMask,
+		PrefixLength:   ones,
+		TotalAddresses: powerOfTwo(bits - ones),
+	}
+
+	if bits == 128 {
+		prefix := netip.PrefixFrom(netip.AddrFrom16([16]byte(network.To16())), ones)
+		fillIPv6Info(info, prefix)
+		return info, nil
+	}
+
 	// 计算第一个可用IP
 	firstIP := net.IP(make([]byte, len(network)))
 	copy(firstIP, network)
 
-	if bits == 32 { // IPv4
-		if ones == 32 {
-			// /32 单地址网络，FirstIP = NetworkID
-		} else if ones == 31 {
-			// /31 点对点链路（RFC 3021），两个地址均为主机地址
-		} else {
-			// 其他情况，FirstIP = NetworkID + 1
-			firstIP[3]++
-		}
+	if ones == 32 {
+		// /32 单地址网络，FirstIP = NetworkID
+	} else if ones == 31 {
+		// /31 点对点链路（RFC 3021），两个地址均为主机地址
+	} else {
+		// 其他情况，FirstIP = NetworkID + 1
+		firstIP[3]++
 	}
 
 	// 计算广播地址
@@ -122,55 +239,82 @@ func calculateCIDRInfo(cidr string) (*CIDRInfo, error) {
 	lastIP := net.IP(make([]byte, len(broadcast)))
 	copy(lastIP, broadcast)
 
-	if bits == 32 { // IPv4
-		if ones == 32 {
-			// /32 单地址网络，LastIP = BroadcastAddress
-		} else if ones == 31 {
-			// /31 点对点链路（RFC 3021），两个地址均为主机地址
-		} else {
-			// 其他情况，LastIP = BroadcastAddress - 1
-			lastIP[3]--
-		}
+	if ones == 32 {
+		// /32 单地址网络，LastIP = BroadcastAddress
+	} else if ones == 31 {
+		// /31 点对点链路（RFC 3021），两个地址均为主机地址
+	} else {
+		// 其他情况，LastIP = BroadcastAddress - 1
+		lastIP[3]--
 	}
 
 	// 计算总主机数
-	var totalHosts int
-
-	if bits == 32 { // IPv4
-		if ones == 32 {
-			// /32 网络只有1个主机
-			totalHosts = 1
-		} else if ones == 31 {
-			// /31 网络有2个主机（通常用于点对点连接）
-			totalHosts = 2
-		} else {
-			// 其他情况计算可用主机数
-			totalHosts = 1 << (bits - ones)
-			totalHosts -= 2 // 减去网络地址和广播地址
-		}
-	} else {
-		// IPv6 - 计算主机数
-		hostBits := bits - ones
-		if hostBits > 63 {
-			// 如果主机位数超过63位，使用科学计数法表示
-			totalHosts = -1 // 表示非常大的数
-		} else {
-			totalHosts = 1 << hostBits
-		}
+	totalHosts := new(big.Int).Set(info.TotalAddresses)
+	if ones < 31 {
+		// 减去网络地址和广播地址，/31 和 /32 的所有地址均为主机地址
+		totalHosts.Sub(totalHosts, big.NewInt(2))
 	}
 
-	// 计算反掩码
-	inverseMask := calculateInverseMask(ipnet.Mask)
+	info.FirstIP = firstIP.String()
+	info.LastIP = lastIP.String()
+	info.BroadcastAddress = broadcast.String()
+	info.TotalHosts = totalHosts
 
-	return &CIDRInfo{
-		NetworkID:        network.String(),
-		FirstIP:          firstIP.String(),
-		LastIP:           lastIP.String(),
-		BroadcastAddress: broadcast.String(),
-		SubnetMask:       net.IP(ipnet.Mask).String(),
-		InverseMask:      inverseMask,
-		TotalHosts:       totalHosts,
-	}, nil
+	return info, nil
+}
+
+// fillIPv6Info 计算 IPv6 特有的信息
+// 网络地址是 Subnet-Router 任播地址（RFC 4291），/64 至 /120 的子网中有 128 个
+// 接口标识保留给任播（RFC 2526），单独报告且不计入主机数，地址范围仍到子网末尾；
+// /127 点对点链路两个地址均可用（RFC 6164）
+func fillIPv6Info(info *CIDRInfo, prefix netip.Prefix) {
+	ones := prefix.Bits()
+	network := prefix.Addr()
+	last := prefixLastAddr(prefix)
+
+	info.IsIPv6 = true
+	info.SubnetRouterAnycast = network.String()
+	if ones <= 48 {
+		info.Subnets48 = powerOfTwo(48 - ones)
+	}
+	if ones <= 64 {
+		info.Subnets64 = powerOfTwo(64 - ones)
+	}
+
+	first := network
+	totalHosts := new(big.Int).Set(info.TotalAddresses)
+
+	if ones < 127 {
+		first = network.Next()
+		totalHosts.Sub(totalHosts, big.NewInt(1))
+	}
+
+	if ones >= 64 && ones <= 120 {
+		reservedFirst, reservedLast := reservedAnycastRange(prefix)
+		info.ReservedAnycastFirst = reservedFirst.String()
+		info.ReservedAnycastLast = reservedLast.String()
+		totalHosts.Sub(totalHosts, big.NewInt(reservedAnycastCount))
+	}
+
+	info.FirstIP = first.String()
+	info.LastIP = last.String()
+	info.TotalHosts = totalHosts
+}
+
+// reservedAnycastCount RFC 2526 为每个子网保留的任播接口标识数量
+const reservedAnycastCount = 128
+
+// reservedAnycastRange 返回 RFC 2526 保留任播地址范围
+// /64 使用 EUI-64 格式接口标识 FDFF:FFFF:FFFF:FF80-FF，其余长度使用子网最高 128 个地址
+func reservedAnycastRange(prefix netip.Prefix) (netip.Addr, netip.Addr) {
+	last := prefixLastAddr(prefix)
+	if prefix.Bits() == 64 {
+		b := last.As16()
+		b[8] = 0xfd
+		last = netip.AddrFrom16(b)
+	}
+	first, _ := addrAddInt64(last, -(reservedAnycastCount - 1))
+	return first, last
 }
 
 // calculateInverseMask 计算反掩码（通配符掩码）
@@ -179,10 +323,14 @@ func calculateInverseMask(mask net.IPMask) string {
 		return ""
 	}
 
-	inverse := make(net.IPMask, len(mask))
-	for i := 0; i < len(mask); i++ {
-		inverse[i] = ^mask[i]
-	}
+	return net.IP(invertMask(mask)).String()
+}
 
-	return net.IP(inverse).String()
+// invertMask 按位取反，在子网掩码和通配符掩码之间转换
+func invertMask(mask []byte) []byte {
+	inverse := make([]byte, len(mask))
+	for i, b := range mask {
+		inverse[i] = ^b
+	}
+	return inverse
 }
@@ -0,0 +1,1140 @@
+/*
+Copyright © 2024-2025 Auska <luodan0709@live.cn>
+
+*/
+
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/netip"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"macconv/pkg/errors"
+	"macconv/pkg/logger"
+)
+
+// ACL 配置文本格式
+const (
+	aclFormatAuto   = "auto"
+	aclFormatCisco  = "cisco"
+	aclFormatHuawei = "huawei"
+)
+
+var ipACLCmd = &cobra.Command{
+	Use:   "acl",
+	Short: "Evaluate a Cisco or Huawei ACL against a packet and find shadowed rules",
+	Long: `
+Parse access lists and report which rule first matches a packet, and which
+rules can never match because an earlier rule already matches everything
+they match. Supported input:
+
+	cisco   access-list N ..., ip access-list standard|extended NAME,
+	        ipv6 access-list NAME and "show access-lists" output
+	huawei  acl [ipv6] number N / acl name NAME and "display acl" output
+
+Addresses may use any, host, wildcard masks (including non-contiguous ones)
+and prefixes; ports may use eq, neq, lt, gt and range with numbers or names.
+Packet fields that are not given, and match conditions that are not modelled
+(ICMP types, DSCP, time ranges, object groups), are reported as possible
+matches instead of being guessed. Without --src and --dst only the shadowed
+rules are reported. For example:
+
+	macconv ip acl -f edge.cfg --src 10.1.1.1 --dst 192.0.2.10 --proto tcp --dport 443
+	macconv ip acl -f acl3000.txt --acl 3000 --src 10.1.1.1 --dst 10.2.2.2 --proto udp --dport domain
+	macconv ip acl -f edge.cfg`,
+	Run: evaluateACLs,
+}
+
+func init() {
+	ipCmd.AddCommand(ipACLCmd)
+	ipACLCmd.Flags().StringP("file", "f", "", "ACL configuration or show/display output, - for stdin")
+	ipACLCmd.Flags().String("format", aclFormatAuto, "Input format: auto, cisco or huawei")
+	ipACLCmd.Flags().String("acl", "", "Only evaluate the ACL with this name or number")
+	ipACLCmd.Flags().String("src", "", "Source address of the packet")
+	ipACLCmd.Flags().String("dst", "", "Destination address of the packet")
+	ipACLCmd.Flags().String("proto", "", "Protocol name or number (tcp, udp, icmp, 47, ...)")
+	ipACLCmd.Flags().String("sport", "", "Source port number or name")
+	ipACLCmd.Flags().String("dport", "", "Destination port number or name")
+	ipACLCmd.Flags().Bool("established", false, "The packet belongs to an established TCP connection (ACK or RST set)")
+}
+
+// aclProtocols 协议名称与编号，"ip" 和 "ipv6" 表示任意协议
+var aclProtocols = map[string]int{
+	"ip": -1, "ipv6": -1, "icmp": 1, "igmp": 2, "ipinip": 4, "tcp": 6, "udp": 17, "gre": 47,
+	"esp": 50, "ahp": 51, "ah": 51, "icmpv6": 58, "eigrp": 88, "ospf": 89, "nos": 94,
+	"pim": 103, "pcp": 108, "vrrp": 112, "sctp": 132,
+}
+
+// aclPorts Cisco 和华为 ACL 中可用的端口名称
+var aclPorts = map[string]int{
+	"echo": 7, "discard": 9, "daytime": 13, "chargen": 19, "ftp-data": 20, "ftp": 21, "ssh": 22,
+	"telnet": 23, "smtp": 25, "time": 37, "whois": 43, "tacacs": 49, "domain": 53, "dns": 53,
+	"bootps": 67, "bootpc": 68, "tftp": 69, "gopher": 70, "finger": 79, "www": 80, "http": 80,
+	"hostname": 101, "pop2": 109, "pop3": 110, "sunrpc": 111, "ident": 113, "nntp": 119,
+	"ntp": 123, "netbios-ns": 137, "netbios-dgm": 138, "netbios-ss": 139, "snmp": 161,
+	"snmptrap": 162, "bgp": 179, "irc": 194, "ldap": 389, "https": 443, "isakmp": 500,
+	"biff": 512, "exec": 512, "login": 513, "who": 513, "cmd": 514, "syslog": 514, "lpd": 515,
+	"talk": 517, "rip": 520, "uucp": 540, "klogin": 543, "kshell": 544, "non500-isakmp": 4500,
+}
+
+// aclPortOperators 端口比较运算符
+var aclPortOperators = map[string]bool{"eq": true, "neq": true, "lt": true, "gt": true, "range": true}
+
+// aclAddr ACL 的地址条件，Wildcard 中为 1 的位不参与比较，因此也支持不连续的通配符掩码
+type aclAddr struct {
+	Any      bool
+	Addr     netip.Addr
+	Wildcard []byte
+}
+
+// hostACLAddr 返回只匹配单个地址的条件
+func hostACLAddr(addr netip.Addr) aclAddr {
+	return aclAddr{Addr: addr, Wildcard: make([]byte, addr.BitLen()/8)}
+}
+
+// prefixACLAddr 返回匹配整个网段的条件
+func prefixACLAddr(prefix netip.Prefix) aclAddr {
+	bitLen := prefix.Addr().BitLen()
+	return aclAddr{Addr: prefix.Masked().Addr(), Wildcard: invertMask(net.CIDRMask(prefix.Bits(), bitLen))}
+}
+
+// match 判断地址是否满足条件
+func (a aclAddr) match(addr netip.Addr) bool {
+	if a.Any {
+		return true
+	}
+	if addr.BitLen() != a.Addr.BitLen() {
+		return false
+	}
+	x, base := addr.AsSlice(), a.Addr.AsSlice()
+	for i := range x {
+		if (x[i]^base[i])&^a.Wildcard[i] != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// contains 判断 a 匹配的地址是否包含 b 匹配的全部地址：
+// b 不关心的位 a 也不能关心，且两者的基地址在 a 关心的位上相同
+func (a aclAddr) contains(b aclAddr) bool {
+	if a.Any {
+		return true
+	}
+	if b.Any || a.Addr.BitLen() != b.Addr.BitLen() {
+		return false
+	}
+	x, y := a.Addr.AsSlice(), b.Addr.AsSlice()
+	for i := range x {
+		if b.Wildcard[i]&^a.Wildcard[i] != 0 || (x[i]^y[i])&^a.Wildcard[i] != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// portRange 端口区间 [Lo, Hi]
+type portRange struct {
+	Lo, Hi int
+}
+
+// portsMatch 判断端口是否在任一区间内，ranges 为 nil 表示任意端口
+func portsMatch(ranges []portRange, port int) bool {
+	if ranges == nil {
+		return true
+	}
+	for _, r := range ranges {
+		if port >= r.Lo && port <= r.Hi {
+			return true
+		}
+	}
+	return false
+}
+
+// portsContain 判断 a 允许的端口是否包含 b 允许的全部端口
+func portsContain(a, b []portRange) bool {
+	if a == nil {
+		return true
+	}
+	// gt 65535、range 100 50 等条件不匹配任何端口，也就不覆盖任何规则
+	if len(a) == 0 {
+		return false
+	}
+	if b == nil {
+		b = []portRange{{0, 65535}}
+	}
+
+	merged := append([]portRange(nil), a...)
+	sort.Slice(merged, func(i, j int) bool { return merged[i].Lo < merged[j].Lo })
+	n := 0
+	for _, r := range merged[1:] {
+		if r.Lo <= merged[n].Hi+1 {
+			merged[n].Hi = max(merged[n].Hi, r.Hi)
+			continue
+		}
+		n++
+		merged[n] = r
+	}
+	merged = merged[:n+1]
+
+	for _, r := range b {
+		covered := false
+		for _, m := range merged {
+			if r.Lo >= m.Lo && r.Hi <= m.Hi {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			return false
+		}
+	}
+	return true
+}
+
+// parseACLPort 解析端口号或端口名称
+func parseACLPort(s string) (int, error) {
+	if port, ok := aclPorts[strings.ToLower(s)]; ok {
+		return port, nil
+	}
+	port, err := strconv.Atoi(s)
+	if err != nil || port < 0 || port > 65535 {
+		return 0, errors.New(errors.ParseError, fmt.Sprintf("invalid port %q", s))
+	}
+	return port, nil
+}
+
+// parsePortCondition 解析 tokens[i] 处的端口运算符及其参数，返回端口区间和消耗的标记数
+// eq 后可跟多个端口（Cisco IOS 允许 eq 80 443）
+func parsePortCondition(tokens []string, i int) ([]portRange, int, error) {
+	op := tokens[i]
+	args := tokens[i+1:]
+	if len(args) == 0 {
+		return nil, 0, errors.New(errors.ParseError, fmt.Sprintf("missing port after %s", op))
+	}
+	first, err := parseACLPort(args[0])
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var ranges []portRange
+	consumed := 2
+	switch op {
+	case "eq":
+		ranges = append(ranges, portRange{first, first})
+		for _, arg := range args[1:] {
+			port, err := parseACLPort(arg)
+			if err != nil {
+				break
+			}
+			ranges = append(ranges, portRange{port, port})
+			consumed++
+		}
+	case "neq":
+		ranges = []portRange{{0, first - 1}, {first + 1, 65535}}
+	case "lt":
+		ranges = []portRange{{0, first - 1}}
+	case "gt":
+		ranges = []portRange{{first + 1, 65535}}
+	case "range":
+		if len(args) < 2 {
+			return nil, 0, errors.New(errors.ParseError, "missing upper port of range")
+		}
+		last, err := parseACLPort(args[1])
+		if err != nil {
+			return nil, 0, err
+		}
+		ranges = []portRange{{first, last}}
+		consumed++
+	}
+
+	valid := ranges[:0]
+	for _, r := range ranges {
+		if r.Lo <= r.Hi {
+			valid = append(valid, r)
+		}
+	}
+	return valid, consumed, nil
+}
+
+// parseACLProtocol 解析协议名称或编号，IPv6 ACL 中的 icmp 指 ICMPv6；-1 表示任意协议
+func parseACLProtocol(s string, ipv6 bool) (int, error) {
+	s = strings.ToLower(s)
+	if ipv6 && s == "icmp" {
+		return aclProtocols["icmpv6"], nil
+	}
+	if proto, ok := aclProtocols[s]; ok {
+		return proto, nil
+	}
+	proto, err := strconv.Atoi(s)
+	if err != nil || proto < 0 || proto > 255 {
+		return 0, errors.New(errors.ParseError, fmt.Sprintf("unknown protocol %q", s))
+	}
+	return proto, nil
+}
+
+// parseACLAddr 解析 tokens[i] 处的地址条件，返回消耗的标记数。支持 any、host X、X/len、
+// X 通配符掩码、华为的 X 0（主机）和 IPv6 X len，地址后没有掩码时视为主机
+func parseACLAddr(tokens []string, i int) (aclAddr, int, error) {
+	if i >= len(tokens) {
+		return aclAddr{}, 0, errors.New(errors.ParseError, "missing address")
+	}
+
+	switch tok := tokens[i]; {
+	case tok == "any":
+		return aclAddr{Any: true}, 1, nil
+	case tok == "host":
+		if i+1 >= len(tokens) {
+			return aclAddr{}, 0, errors.New(errors.ParseError, "missing address after host")
+		}
+		addr, err := parseAddr(tokens[i+1])
+		if err != nil {
+			return aclAddr{}, 0, err
+		}
+		return hostACLAddr(addr), 2, nil
+	case tok == "object-group" || tok == "addrgroup" || tok == "addrset":
+		return aclAddr{}, 0, errors.New(errors.ValidationError, "object groups are not supported")
+	case strings.Contains(tok, "/"):
+		prefix, err := netip.ParsePrefix(tok)
+		if err != nil {
+			return aclAddr{}, 0, errors.Wrap(errors.ParseError, fmt.Sprintf("invalid prefix %s", tok), err)
+		}
+		return prefixACLAddr(prefix), 1, nil
+	}
+
+	addr, err := parseAddr(tokens[i])
+	if err != nil {
+		return aclAddr{}, 0, err
+	}
+	if i+1 >= len(tokens) {
+		return hostACLAddr(addr), 1, nil
+	}
+
+	next := tokens[i+1]
+	if addr.Is4() {
+		if next == "0" {
+			return hostACLAddr(addr), 2, nil
+		}
+		if wildcard, err := netip.ParseAddr(next); err == nil && wildcard.Is4() {
+			return aclAddr{Addr: addr, Wildcard: wildcard.AsSlice()}, 2, nil
+		}
+	} else if bits, err := strconv.Atoi(next); err == nil && bits >= 0 && bits <= 128 {
+		return prefixACLAddr(netip.PrefixFrom(addr, bits)), 2, nil
+	}
+	return hostACLAddr(addr), 1, nil
+}
+
+// aclRule ACL 中的一条规则
+type aclRule struct {
+	Line        int // 在输入中的行号
+	Seq         int // 序号或规则编号，未写出时为 -1
+	Text        string
+	Permit      bool
+	Protocol    int // -1 表示任意协议
+	Src, Dst    aclAddr
+	SrcPorts    []portRange // nil 表示任意端口
+	DstPorts    []portRange
+	Established bool
+	Options     []string // 未建模的匹配条件，如 ICMP 类型、DSCP、time-range
+	Err         error    // 无法解析的规则，匹配结果总是不确定
+}
+
+func (r aclRule) action() string {
+	if r.Permit {
+		return "permit"
+	}
+	return "deny"
+}
+
+// acl 一个访问控制列表
+type acl struct {
+	Name   string
+	Vendor string
+	Type   string // standard、extended、basic 或 advanced
+	IPv6   bool
+	Rules  []aclRule
+}
+
+// String 返回 "名称 (厂商 类型)" 形式的描述
+func (a *acl) String() string {
+	family := ""
+	if a.IPv6 {
+		family = " IPv6"
+	}
+	return fmt.Sprintf("%s (%s%s %s, %d rules)", a.Name, a.Vendor, family, a.Type, len(a.Rules))
+}
+
+// newACLRule 创建规则，默认匹配任意报文
+func newACLRule(lineNo int, text string) aclRule {
+	return aclRule{
+		Line: lineNo, Seq: -1, Text: strings.Join(strings.Fields(text), " "),
+		Protocol: -1, Src: aclAddr{Any: true}, Dst: aclAddr{Any: true},
+	}
+}
+
+// parseACLAction 解析 permit/deny
+func parseACLAction(tok string) (bool, error) {
+	switch tok {
+	case "permit":
+		return true, nil
+	case "deny":
+		return false, nil
+	default:
+		return false, errors.New(errors.ParseError, fmt.Sprintf("expected permit or deny, got %q", tok))
+	}
+}
+
+// aclLogKeywords 只影响日志、不影响匹配的关键字
+var aclLogKeywords = map[string]bool{"log": true, "log-input": true, "logging": true, "counting": true}
+
+// parseCiscoRule 解析 permit/deny 开头的 Cisco 规则
+// 标准 ACL 只有源地址；扩展 ACL 为 协议 源 [端口] 目的 [端口] [选项]
+func parseCiscoRule(rule *aclRule, tokens []string, standard, ipv6 bool) error {
+	permit, err := parseACLAction(tokens[0])
+	if err != nil {
+		return err
+	}
+	rule.Permit = permit
+
+	i := 1
+	if !standard {
+		if i >= len(tokens) {
+			return errors.New(errors.ParseError, "missing protocol")
+		}
+		if rule.Protocol, err = parseACLProtocol(tokens[i], ipv6); err != nil {
+			return err
+		}
+		i++
+	}
+
+	var n int
+	if rule.Src, n, err = parseACLAddr(tokens, i); err != nil {
+		return err
+	}
+	i += n
+	if standard {
+		for _, tok := range tokens[i:] {
+			if !aclLogKeywords[tok] {
+				return errors.New(errors.ParseError, fmt.Sprintf("unexpected %q in standard ACL", tok))
+			}
+		}
+		return nil
+	}
+
+	if i < len(tokens) && aclPortOperators[tokens[i]] {
+		if rule.SrcPorts, n, err = parsePortCondition(tokens, i); err != nil {
+			return err
+		}
+		i += n
+	}
+	if rule.Dst, n, err = parseACLAddr(tokens, i); err != nil {
+		return err
+	}
+	i += n
+	if i < len(tokens) && aclPortOperators[tokens[i]] {
+		if rule.DstPorts, n, err = parsePortCondition(tokens, i); err != nil {
+			return err
+		}
+		i += n
+	}
+
+	for _, tok := range tokens[i:] {
+		switch {
+		case tok == "established":
+			rule.Established = true
+		case !aclLogKeywords[tok]:
+			rule.Options = append(rule.Options, tok)
+		}
+	}
+	return nil
+}
+
+// huaweiRuleKeywords 华为规则中带参数的关键字
+var huaweiRuleKeywords = map[string]bool{
+	"source": true, "destination": true, "source-port": true, "destination-port": true,
+}
+
+// parseHuaweiRule 解析 rule 之后的华为规则：[编号] permit|deny [协议] 及关键字形式的条件
+func parseHuaweiRule(rule *aclRule, tokens []string, basic, ipv6 bool) error {
+	i := 0
+	if len(tokens) > 0 && isDecimal(tokens[0]) {
+		rule.Seq, _ = strconv.Atoi(tokens[0])
+		i++
+	}
+	if i >= len(tokens) {
+		return errors.New(errors.ParseError, "missing permit or deny")
+	}
+	permit, err := parseACLAction(tokens[i])
+	if err != nil {
+		return err
+	}
+	rule.Permit = permit
+	i++
+
+	if !basic && i < len(tokens) && !huaweiRuleKeywords[tokens[i]] && !aclLogKeywords[tokens[i]] {
+		if rule.Protocol, err = parseACLProtocol(tokens[i], ipv6); err != nil {
+			return err
+		}
+		i++
+	}
+
+	var option []string
+	flushOption := func() {
+		if len(option) > 0 {
+			rule.Options = append(rule.Options, strings.Join(option, " "))
+			option = nil
+		}
+	}
+	for i < len(tokens) {
+		tok := tokens[i]
+		var n int
+		switch {
+		case tok == "source" || tok == "destination":
+			flushOption()
+			var addr aclAddr
+			if addr, n, err = parseACLAddr(tokens, i+1); err != nil {
+				return err
+			}
+			if tok == "source" {
+				rule.Src = addr
+			} else {
+				rule.Dst = addr
+			}
+			i += n + 1
+		case tok == "source-port" || tok == "destination-port":
+			flushOption()
+			if i+1 >= len(tokens) || !aclPortOperators[tokens[i+1]] {
+				return errors.New(errors.ParseError, fmt.Sprintf("missing port operator after %s", tok))
+			}
+			var ports []portRange
+			if ports, n, err = parsePortCondition(tokens, i+1); err != nil {
+				return err
+			}
+			if tok == "source-port" {
+				rule.SrcPorts = ports
+			} else {
+				rule.DstPorts = ports
+			}
+			i += n + 1
+		case tok == "established":
+			flushOption()
+			rule.Established = true
+			i++
+		case tok == "tcp-flag" && i+1 < len(tokens) && tokens[i+1] == "established":
+			flushOption()
+			rule.Established = true
+			i += 2
+		case aclLogKeywords[tok]:
+			flushOption()
+			i++
+		default:
+			option = append(option, tok)
+			i++
+		}
+	}
+	flushOption()
+
+	if basic && (!rule.Dst.Any || rule.SrcPorts != nil || rule.DstPorts != nil) {
+		return errors.New(errors.ParseError, "basic ACLs only match the source address")
+	}
+	return nil
+}
+
+var (
+	ciscoACLHeaderPattern      = regexp.MustCompile(`^(ip|ipv6) access-list\s+(?:(standard|extended)\s+)?(\S+)`)
+	ciscoShowACLHeaderPattern  = regexp.MustCompile(`^(?:(Standard|Extended) IP|(IPv6)) access list (\S+)`)
+	huaweiACLHeaderPattern     = regexp.MustCompile(`^acl\s+(ipv6\s+)?(?:number\s+(\d+)|name\s+(\S+)(?:\s+(basic|advance|\d+))?|(\d+))`)
+	huaweiShowACLHeaderPattern = regexp.MustCompile(`^(Basic|Advanced)\s+(IPv6\s+)?ACL\s+(?:name\s+(\S+)\s+)?(\d+)?`)
+	huaweiACLDetectPattern     = regexp.MustCompile(`(?m)^\s*(?:acl\s|rule\s+\d*\s*(?:permit|deny)|(?:Basic|Advanced)\s+(?:IPv6\s+)?ACL\s)`)
+	aclMatchCountPattern       = regexp.MustCompile(`\s*\(\d+ (?:times )?match\w*\)\s*$`)
+	ciscoTrailingSeqPattern    = regexp.MustCompile(`\s+sequence\s+(\d+)\s*$`)
+	ciscoWildcardBitsPattern   = regexp.MustCompile(`,\s*wildcard bits\s+`)
+)
+
+// detectACLFormat 根据特征文本判断 ACL 格式
+func detectACLFormat(text string) string {
+	if huaweiACLDetectPattern.MatchString(text) {
+		return aclFormatHuawei
+	}
+	return aclFormatCisco
+}
+
+// parseACLs 按格式解析 ACL 文本
+func parseACLs(text, format string) ([]*acl, error) {
+	if format == aclFormatAuto {
+		format = detectACLFormat(text)
+		logger.Debugf("Detected ACL format: %s", format)
+	}
+
+	var acls []*acl
+	var err error
+	switch format {
+	case aclFormatCisco:
+		acls, err = parseCiscoACLs(text)
+	case aclFormatHuawei:
+		acls, err = parseHuaweiACLs(text)
+	default:
+		return nil, errors.New(errors.ValidationError, fmt.Sprintf("unknown ACL format %q", format))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, a := range acls {
+		sortACLRules(a)
+	}
+	return acls, nil
+}
+
+// sortACLRules 按序号排序，与设备的匹配顺序一致
+// 未写出序号的规则按设备的规则编号：Cisco 为当前最大序号加 10，华为为大于最大编号的下一个 5 的倍数
+func sortACLRules(a *acl) {
+	maxSeq := 0
+	for i := range a.Rules {
+		if a.Rules[i].Seq < 0 {
+			if a.Vendor == aclFormatHuawei {
+				a.Rules[i].Seq = (maxSeq/5 + 1) * 5
+			} else {
+				a.Rules[i].Seq = maxSeq + 10
+			}
+		}
+		maxSeq = max(maxSeq, a.Rules[i].Seq)
+	}
+	sort.SliceStable(a.Rules, func(i, j int) bool { return a.Rules[i].Seq < a.Rules[j].Seq })
+}
+
+// aclSet 按出现顺序收集 ACL，同名 ACL 的规则合并到一起
+type aclSet struct {
+	list   []*acl
+	byName map[string]*acl
+}
+
+func (s *aclSet) get(name, vendor, typ string, ipv6 bool) *acl {
+	key := name
+	if ipv6 {
+		key = "ipv6 " + name
+	}
+	if a, ok := s.byName[key]; ok {
+		return a
+	}
+	if s.byName == nil {
+		s.byName = make(map[string]*acl)
+	}
+	a := &acl{Name: name, Vendor: vendor, Type: typ, IPv6: ipv6}
+	s.byName[key] = a
+	s.list = append(s.list, a)
+	return a
+}
+
+// addRule 解析规则并加入 ACL，无法解析的规则保留为不确定的规则并给出警告
+func addRule(a *acl, rule aclRule, err error) {
+	if err != nil {
+		logger.Warnf("line %d: %v; the rule is treated as a possible match", rule.Line, err)
+		rule.Err = err
+	}
+	a.Rules = append(a.Rules, rule)
+}
+
+// ciscoNumberedType 按编号范围返回 Cisco 编号 ACL 的类型
+func ciscoNumberedType(number int) (string, bool) {
+	switch {
+	case number >= 1 && number <= 99, number >= 1300 && number <= 1999:
+		return "standard", true
+	case number >= 100 && number <= 199, number >= 2000 && number <= 2699:
+		return "extended", true
+	default:
+		return "", false
+	}
+}
+
+// parseCiscoACLs 解析 Cisco IOS 配置或 show access-lists 输出
+func parseCiscoACLs(text string) ([]*acl, error) {
+	var set aclSet
+	var current *acl
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	lineNo := 0
+
+	for scanner.Scan() {
+		lineNo++
+		raw := scanner.Text()
+		// show ipv6 access-list 把序号放在行尾：permit tcp any any eq www (5 matches) sequence 10
+		trailingSeq := -1
+		if m := ciscoTrailingSeqPattern.FindStringSubmatchIndex(raw); m != nil {
+			trailingSeq, _ = strconv.Atoi(raw[m[2]:m[3]])
+			raw = raw[:m[0]]
+		}
+		line := strings.TrimSpace(aclMatchCountPattern.ReplaceAllString(raw, ""))
+		line = ciscoWildcardBitsPattern.ReplaceAllString(line, " ")
+		if line == "" || strings.HasPrefix(line, "!") {
+			continue
+		}
+
+		if m := ciscoACLHeaderPattern.FindStringSubmatch(line); m != nil {
+			typ := m[2]
+			if typ == "" {
+				typ = "extended"
+			}
+			current = set.get(m[3], aclFormatCisco, typ, m[1] == "ipv6")
+			continue
+		}
+		if m := ciscoShowACLHeaderPattern.FindStringSubmatch(line); m != nil {
+			typ := strings.ToLower(m[1])
+			if typ == "" {
+				typ = "extended"
+			}
+			current = set.get(m[3], aclFormatCisco, typ, m[2] != "")
+			continue
+		}
+
+		tokens := strings.Fields(line)
+		if tokens[0] == "access-list" && len(tokens) >= 3 {
+			number, err := strconv.Atoi(tokens[1])
+			typ, ok := ciscoNumberedType(number)
+			if err != nil || !ok {
+				return nil, errors.New(errors.ParseError, fmt.Sprintf("line %d: unsupported access-list number %s", lineNo, tokens[1]))
+			}
+			current = nil
+			if tokens[2] == "remark" {
+				continue
+			}
+			a := set.get(tokens[1], aclFormatCisco, typ, false)
+			rule := newACLRule(lineNo, line)
+			err = parseCiscoRule(&rule, tokens[2:], typ == "standard", false)
+			addRule(a, rule, err)
+			continue
+		}
+
+		if current == nil {
+			continue
+		}
+		rule := newACLRule(lineNo, line)
+		if trailingSeq >= 0 {
+			rule.Seq = trailingSeq
+		}
+		switch {
+		case isDecimal(tokens[0]) && len(tokens) > 1:
+			rule.Seq, _ = strconv.Atoi(tokens[0])
+			tokens = tokens[1:]
+		case tokens[0] == "sequence" && len(tokens) > 2:
+			rule.Seq, _ = strconv.Atoi(tokens[1])
+			tokens = tokens[2:]
+		}
+		switch tokens[0] {
+		case "remark", "description", "statistics":
+			continue
+		case "permit", "deny":
+			err := parseCiscoRule(&rule, tokens, current.Type == "standard", current.IPv6)
+			addRule(current, rule, err)
+		default:
+			if raw == strings.TrimLeft(raw, " \t") {
+				current = nil
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(errors.FileSystemError, "failed to read ACL", err)
+	}
+	return set.list, nil
+}
+
+// huaweiNumberedType 按编号范围返回华为 ACL 的类型
+func huaweiNumberedType(number int) (string, bool) {
+	switch {
+	case number >= 2000 && number <= 2999:
+		return "basic", true
+	case number >= 3000 && number <= 3999:
+		return "advanced", true
+	default:
+		return "", false
+	}
+}
+
+// parseHuaweiACLs 解析华为 VRP 配置或 display acl 输出
+func parseHuaweiACLs(text string) ([]*acl, error) {
+	var set aclSet
+	var current *acl
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	lineNo := 0
+
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(aclMatchCountPattern.ReplaceAllString(scanner.Text(), ""))
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		name, typ, ipv6, isHeader := "", "", false, false
+		if m := huaweiACLHeaderPattern.FindStringSubmatch(line); m != nil {
+			isHeader, ipv6 = true, m[1] != ""
+			name = m[2] + m[5]
+			switch {
+			case m[3] != "":
+				name, typ = m[3], "advanced"
+				if m[4] == "basic" {
+					typ = "basic"
+				} else if number, err := strconv.Atoi(m[4]); err == nil {
+					typ, _ = huaweiNumberedType(number)
+				}
+			default:
+				number, _ := strconv.Atoi(name)
+				typ, _ = huaweiNumberedType(number)
+			}
+			if strings.Contains(line, "match-order auto") {
+				logger.Warnf("line %d: ACL %s uses match-order auto; rules are evaluated in rule ID order", lineNo, name)
+			}
+		} else if m := huaweiShowACLHeaderPattern.FindStringSubmatch(line); m != nil {
+			isHeader, ipv6 = true, m[2] != ""
+			name, typ = m[4], "basic"
+			if m[3] != "" {
+				name = m[3]
+			}
+			if m[1] == "Advanced" {
+				typ = "advanced"
+			}
+		}
+		if isHeader {
+			if typ == "" {
+				return nil, errors.New(errors.ParseError, fmt.Sprintf("line %d: unsupported ACL type: %s", lineNo, line))
+			}
+			current = set.get(name, aclFormatHuawei, typ, ipv6)
+			continue
+		}
+
+		tokens := strings.Fields(line)
+		if current == nil || tokens[0] != "rule" {
+			if tokens[0] != "description" && tokens[0] != "step" && !strings.HasPrefix(tokens[0], "Acl's") {
+				current = nil
+			}
+			continue
+		}
+		rule := newACLRule(lineNo, line)
+		err := parseHuaweiRule(&rule, tokens[1:], current.Type == "basic", current.IPv6)
+		addRule(current, rule, err)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(errors.FileSystemError, "failed to read ACL", err)
+	}
+	return set.list, nil
+}
+
+// aclVerdict 规则对报文的匹配结果
+type aclVerdict int
+
+const (
+	aclNoMatch aclVerdict = iota
+	aclMatch
+	aclMaybe // 取决于未给出的报文字段或未建模的条件
+)
+
+// aclPacket 待评估的报文，未给出的字段为无效地址或 -1
+type aclPacket struct {
+	Src, Dst         netip.Addr
+	Protocol         int
+	SrcPort, DstPort int
+	Established      bool
+}
+
+// String 返回 "协议 源[:端口] -> 目的[:端口]" 形式的描述
+func (p aclPacket) String() string {
+	endpoint := func(addr netip.Addr, port int) string {
+		switch {
+		case !addr.IsValid() && port < 0:
+			return "any"
+		case !addr.IsValid():
+			return "any:" + strconv.Itoa(port)
+		case port < 0:
+			return addr.String()
+		}
+		return netip.AddrPortFrom(addr, uint16(port)).String()
+	}
+
+	proto := "any protocol"
+	if p.Protocol >= 0 {
+		proto = strconv.Itoa(p.Protocol)
+		for name, number := range aclProtocols {
+			if number == p.Protocol && name != "ahp" {
+				proto = name
+			}
+		}
+	}
+	s := fmt.Sprintf("%s %s -> %s", proto, endpoint(p.Src, p.SrcPort), endpoint(p.Dst, p.DstPort))
+	if p.Established {
+		s += " established"
+	}
+	return s
+}
+
+// evaluate 判断规则是否匹配报文，结果不确定时同时返回所依赖的条件
+func (r aclRule) evaluate(p aclPacket) (aclVerdict, []string) {
+	if r.Err != nil {
+		return aclMaybe, []string{"unsupported syntax"}
+	}
+
+	var unknown []string
+	check := func(ok, known bool, field string) bool {
+		if !known {
+			unknown = append(unknown, field)
+			return true
+		}
+		return ok
+	}
+
+	if !r.Src.Any && !check(r.Src.match(p.Src), p.Src.IsValid(), "source address") {
+		return aclNoMatch, nil
+	}
+	if !r.Dst.Any && !check(r.Dst.match(p.Dst), p.Dst.IsValid(), "destination address") {
+		return aclNoMatch, nil
+	}
+	if r.Protocol >= 0 && !check(r.Protocol == p.Protocol, p.Protocol >= 0, "protocol") {
+		return aclNoMatch, nil
+	}
+	if r.SrcPorts != nil && !check(portsMatch(r.SrcPorts, p.SrcPort), p.SrcPort >= 0, "source port") {
+		return aclNoMatch, nil
+	}
+	if r.DstPorts != nil && !check(portsMatch(r.DstPorts, p.DstPort), p.DstPort >= 0, "destination port") {
+		return aclNoMatch, nil
+	}
+	if r.Established && !p.Established {
+		return aclNoMatch, nil
+	}
+
+	for _, option := range r.Options {
+		unknown = append(unknown, strconv.Quote(option))
+	}
+	if len(unknown) > 0 {
+		return aclMaybe, unknown
+	}
+	return aclMatch, nil
+}
+
+// covers 判断规则 a 是否匹配规则 b 能匹配的全部报文
+func (a aclRule) covers(b aclRule) bool {
+	if a.Err != nil || b.Err != nil || len(a.Options) > 0 {
+		return false
+	}
+	if a.Protocol >= 0 && a.Protocol != b.Protocol {
+		return false
+	}
+	if a.Established && !b.Established {
+		return false
+	}
+	return a.Src.contains(b.Src) && a.Dst.contains(b.Dst) &&
+		portsContain(a.SrcPorts, b.SrcPorts) && portsContain(a.DstPorts, b.DstPorts)
+}
+
+// aclStep 报文评估过程中匹配或可能匹配的一条规则
+type aclStep struct {
+	Rule    aclRule
+	Verdict aclVerdict
+	Depends []string
+}
+
+// evaluateACL 按顺序评估规则，返回第一条确定匹配的规则之前（含）所有匹配或可能匹配的规则
+func evaluateACL(a *acl, p aclPacket) []aclStep {
+	var steps []aclStep
+	for _, rule := range a.Rules {
+		verdict, depends := rule.evaluate(p)
+		if verdict == aclNoMatch {
+			continue
+		}
+		steps = append(steps, aclStep{Rule: rule, Verdict: verdict, Depends: depends})
+		if verdict == aclMatch {
+			break
+		}
+	}
+	return steps
+}
+
+// aclShadow 永远不会被匹配到的规则及覆盖它的前序规则
+type aclShadow struct {
+	Rule aclRule
+	By   aclRule
+}
+
+// findShadowedRules 找出被某一条前序规则完全覆盖的规则
+// 只比较单条规则，被多条前序规则共同覆盖的情况不会被报告
+func findShadowedRules(rules []aclRule) []aclShadow {
+	var shadows []aclShadow
+	for j, rule := range rules {
+		for _, earlier := range rules[:j] {
+			if earlier.covers(rule) {
+				shadows = append(shadows, aclShadow{Rule: rule, By: earlier})
+				break
+			}
+		}
+	}
+	return shadows
+}
+
+// parseACLPacket 由命令行参数构造报文
+func parseACLPacket(src, dst, proto, sport, dport string, established bool) (aclPacket, error) {
+	p := aclPacket{Protocol: -1, SrcPort: -1, DstPort: -1, Established: established}
+	var err error
+	if src != "" {
+		if p.Src, err = parseAddr(src); err != nil {
+			return p, err
+		}
+	}
+	if dst != "" {
+		if p.Dst, err = parseAddr(dst); err != nil {
+			return p, err
+		}
+	}
+	if p.Src.IsValid() && p.Dst.IsValid() && p.Src.Is4() != p.Dst.Is4() {
+		return p, errors.New(errors.ValidationError, "source and destination must be the same address family")
+	}
+	if proto != "" {
+		ipv6 := p.Src.Is6() || p.Dst.Is6()
+		if p.Protocol, err = parseACLProtocol(proto, ipv6); err != nil {
+			return p, err
+		}
+	}
+	if sport != "" {
+		if p.SrcPort, err = parseACLPort(sport); err != nil {
+			return p, err
+		}
+	}
+	if dport != "" {
+		if p.DstPort, err = parseACLPort(dport); err != nil {
+			return p, err
+		}
+	}
+	if (p.SrcPort >= 0 || p.DstPort >= 0) && p.Protocol >= 0 && p.Protocol != 6 && p.Protocol != 17 && p.Protocol != 132 {
+		return p, errors.New(errors.ValidationError, "ports are only valid for tcp, udp and sctp")
+	}
+	return p, nil
+}
+
+func printACLEvaluation(a *acl, p aclPacket) {
+	steps := evaluateACL(a, p)
+	for _, step := range steps {
+		if step.Verdict == aclMaybe {
+			fmt.Printf("Possible Match: line %d (depends on %s): %s\n", step.Rule.Line, strings.Join(step.Depends, ", "), step.Rule.Text)
+		}
+	}
+
+	if n := len(steps); n > 0 && steps[n-1].Verdict == aclMatch {
+		rule := steps[n-1].Rule
+		fmt.Printf("Matched: line %d: %s\n", rule.Line, rule.Text)
+		if n > 1 {
+			fmt.Printf("Result: %s (unless a possible match above applies first)\n", rule.action())
+		} else {
+			fmt.Println("Result:", rule.action())
+		}
+		return
+	}
+
+	switch {
+	case len(steps) > 0:
+		fmt.Println("Result: undetermined, depends on the possible matches above")
+	case a.Vendor == aclFormatCisco:
+		fmt.Println("Result: deny (implicit deny)")
+	default:
+		fmt.Println("Result: no rule matched (the default action depends on where the ACL is applied)")
+	}
+}
+
+func printShadowedRules(a *acl) {
+	shadows := findShadowedRules(a.Rules)
+	if len(shadows) == 0 {
+		fmt.Println("Shadowed Rules: none")
+		return
+	}
+
+	fmt.Println("Shadowed Rules:")
+	for _, s := range shadows {
+		kind := "redundant"
+		if s.Rule.Permit != s.By.Permit {
+			kind = "conflicting action"
+		}
+		fmt.Printf("  line %d: %s\n", s.Rule.Line, s.Rule.Text)
+		fmt.Printf("    covered by line %d (%s): %s\n", s.By.Line, kind, s.By.Text)
+	}
+}
+
+func evaluateACLs(cmd *cobra.Command, args []string) {
+	file, _ := cmd.Flags().GetString("file")
+	format, _ := cmd.Flags().GetString("format")
+	name, _ := cmd.Flags().GetString("acl")
+	src, _ := cmd.Flags().GetString("src")
+	dst, _ := cmd.Flags().GetString("dst")
+	proto, _ := cmd.Flags().GetString("proto")
+	sport, _ := cmd.Flags().GetString("sport")
+	dport, _ := cmd.Flags().GetString("dport")
+	established, _ := cmd.Flags().GetBool("established")
+
+	if file == "" {
+		logger.PrintValidationError("--file is required")
+		if err := cmd.Help(); err != nil {
+			logger.PrintErrorWithMessage("failed to show help", err)
+		}
+		return
+	}
+
+	packet, err := parseACLPacket(src, dst, proto, sport, dport, established)
+	if err != nil {
+		logger.PrintErrorWithMessage("invalid packet", err)
+		return
+	}
+	evaluate := packet.Src.IsValid() || packet.Dst.IsValid()
+
+	text, err := readTextInput(file)
+	if err != nil {
+		logger.PrintErrorWithMessage("failed to read ACL", err)
+		return
+	}
+	acls, err := parseACLs(text, format)
+	if err != nil {
+		logger.PrintErrorWithMessage("failed to parse ACL", err)
+		return
+	}
+
+	var selected []*acl
+	for _, a := range acls {
+		if name != "" && a.Name != name {
+			continue
+		}
+		if name == "" && evaluate {
+			addr := packet.Src
+			if !addr.IsValid() {
+				addr = packet.Dst
+			}
+			if a.IPv6 != addr.Is6() {
+				continue
+			}
+		}
+		selected = append(selected, a)
+	}
+	if len(selected) == 0 {
+		logger.PrintValidationError("no matching ACL found in " + file)
+		return
+	}
+
+	if evaluate {
+		fmt.Println("Packet:", packet)
+	}
+	for i, a := range selected {
+		if i > 0 || evaluate {
+			fmt.Println()
+		}
+		fmt.Println("ACL:", a)
+		if evaluate {
+			if a.IPv6 != (packet.Src.Is6() || packet.Dst.Is6()) {
+				logger.Warnf("ACL %s does not match the address family of the packet", a.Name)
+			}
+			printACLEvaluation(a, packet)
+		}
+		printShadowedRules(a)
+	}
+
+	logger.Infof("Successfully evaluated %d ACLs", len(selected))
+}
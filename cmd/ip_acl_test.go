@@ -0,0 +1,459 @@
+//go:build unit
+
+/*
+Copyright © 2024-2025 Auska <luodan0709@live.cn>
+
+*/
+
+package cmd
+
+import (
+	"net/netip"
+	"reflect"
+	"testing"
+)
+
+const sampleCiscoACL = `hostname edge
+!
+access-list 10 remark mgmt
+access-list 10 permit 10.0.0.0 0.0.0.255
+access-list 10 permit host 10.0.0.5
+access-list 10 deny   any log
+!
+ip access-list extended WEB
+ 20 permit tcp 10.0.0.0 0.255.0.255 any eq 22
+ 10 permit tcp any host 192.0.2.10 eq www 443
+ 30 deny   ip 10.1.0.0 0.0.255.255 any
+ 40 permit tcp 10.1.2.0 0.0.0.255 any eq 22
+ 50 permit icmp any any echo
+ 60 permit tcp any any established
+ 70 permit tcp any object-group SRV eq 80
+ 80 deny ip any any log
+!
+ipv6 access-list V6
+ sequence 10 permit tcp 2001:db8::/32 any eq 22
+ sequence 20 deny ipv6 any any
+!
+interface Gi0/1
+ ip access-group WEB in
+`
+
+const sampleHuaweiACL = `#
+acl number 2000
+ rule 5 permit source 10.0.0.0 0.0.0.255
+ rule 10 deny source 10.0.0.7 0
+#
+acl number 3000
+ description edge filter
+ rule 20 deny ip source 10.1.0.0 0.0.255.255
+ rule 5 permit tcp source 10.0.0.0 0.0.0.255 destination 192.0.2.10 0 destination-port eq 443
+ rule 10 permit udp destination-port range 53 54 logging
+ rule 30 permit icmp icmp-type echo
+#
+acl ipv6 name V6 advance
+ rule 5 permit tcp source 2001:db8:: 32 destination-port eq 22
+#
+interface GigabitEthernet0/0/1
+ traffic-filter inbound acl 3000
+`
+
+func mustParseACLs(t *testing.T, text string) map[string]*acl {
+	t.Helper()
+	acls, err := parseACLs(text, aclFormatAuto)
+	if err != nil {
+		t.Fatalf("parseACLs() error = %v", err)
+	}
+	byName := make(map[string]*acl)
+	for _, a := range acls {
+		byName[a.Name] = a
+	}
+	return byName
+}
+
+func TestACLAddr(t *testing.T) {
+	parse := func(s ...string) aclAddr {
+		addr, _, err := parseACLAddr(s, 0)
+		if err != nil {
+			t.Fatalf("parseACLAddr(%v) error = %v", s, err)
+		}
+		return addr
+	}
+
+	tests := []struct {
+		name     string
+		cond     aclAddr
+		addr     string
+		expected bool
+	}{
+		{"Wildcard match", parse("10.0.0.0", "0.0.0.255"), "10.0.0.77", true},
+		{"Wildcard miss", parse("10.0.0.0", "0.0.0.255"), "10.0.1.77", false},
+		{"Non-contiguous match", parse("10.0.0.1", "0.255.0.0"), "10.42.0.1", true},
+		{"Non-contiguous miss", parse("10.0.0.1", "0.255.0.0"), "10.42.1.1", false},
+		{"Host", parse("host", "192.0.2.1"), "192.0.2.1", true},
+		{"Huawei host", parse("192.0.2.1", "0"), "192.0.2.2", false},
+		{"IPv6 prefix", parse("2001:db8::/32"), "2001:db8:ffff::1", true},
+		{"IPv6 prefix length", parse("2001:db8::", "48"), "2001:db8:1::1", false},
+		{"Any", parse("any"), "2001:db8::1", true},
+		{"Family mismatch", parse("10.0.0.0", "0.0.0.255"), "::ffff:10.0.0.1", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := tt.cond.match(netip.MustParseAddr(tt.addr)); result != tt.expected {
+				t.Errorf("match(%s) = %v, want %v", tt.addr, result, tt.expected)
+			}
+		})
+	}
+
+	containTests := []struct {
+		name     string
+		a, b     aclAddr
+		expected bool
+	}{
+		{"Larger contains smaller", parse("10.0.0.0", "0.0.255.255"), parse("10.0.1.0", "0.0.0.255"), true},
+		{"Smaller does not contain larger", parse("10.0.1.0", "0.0.0.255"), parse("10.0.0.0", "0.0.255.255"), false},
+		{"Non-contiguous contains host", parse("10.0.0.1", "0.255.0.0"), parse("host", "10.9.0.1"), true},
+		{"Non-contiguous does not contain subnet", parse("10.0.0.1", "0.255.0.0"), parse("10.9.0.0", "0.0.0.255"), false},
+		{"Any contains all", parse("any"), parse("2001:db8::/32"), true},
+		{"Nothing contains any", parse("0.0.0.0", "255.255.255.255"), parse("any"), false},
+	}
+
+	for _, tt := range containTests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := tt.a.contains(tt.b); result != tt.expected {
+				t.Errorf("contains() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestPortConditions(t *testing.T) {
+	parse := func(s ...string) []portRange {
+		ports, n, err := parsePortCondition(s, 0)
+		if err != nil {
+			t.Fatalf("parsePortCondition(%v) error = %v", s, err)
+		}
+		if n != len(s) {
+			t.Fatalf("parsePortCondition(%v) consumed %d tokens, want %d", s, n, len(s))
+		}
+		return ports
+	}
+
+	if got := parse("eq", "www", "443"); !reflect.DeepEqual(got, []portRange{{80, 80}, {443, 443}}) {
+		t.Errorf("eq www 443 = %v", got)
+	}
+	if got := parse("neq", "0"); !reflect.DeepEqual(got, []portRange{{1, 65535}}) {
+		t.Errorf("neq 0 = %v", got)
+	}
+	if got := parse("range", "1024", "65535"); !reflect.DeepEqual(got, []portRange{{1024, 65535}}) {
+		t.Errorf("range 1024 65535 = %v", got)
+	}
+	if _, _, err := parsePortCondition([]string{"eq", "nosuchport"}, 0); err == nil {
+		t.Error("parsePortCondition() with unknown port name should fail")
+	}
+
+	tests := []struct {
+		name     string
+		a, b     []portRange
+		expected bool
+	}{
+		{"Any contains range", nil, []portRange{{22, 22}}, true},
+		{"Range does not contain any", []portRange{{0, 1023}}, nil, false},
+		{"Split ranges cover any", parse("neq", "80"), parse("eq", "22", "443"), true},
+		{"Gap not covered", parse("neq", "80"), parse("range", "79", "81"), false},
+		{"Adjacent ranges merge", []portRange{{0, 1023}, {1024, 65535}}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := portsContain(tt.a, tt.b); result != tt.expected {
+				t.Errorf("portsContain() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseCiscoACLs(t *testing.T) {
+	acls := mustParseACLs(t, sampleCiscoACL)
+	if len(acls) != 3 {
+		t.Fatalf("parsed %d ACLs, want 3", len(acls))
+	}
+
+	std := acls["10"]
+	if std == nil || std.Type != "standard" || len(std.Rules) != 3 {
+		t.Fatalf("ACL 10 = %+v, want standard ACL with 3 rules", std)
+	}
+
+	web := acls["WEB"]
+	if web == nil || web.Type != "extended" || len(web.Rules) != 8 {
+		t.Fatalf("ACL WEB = %+v, want extended ACL with 8 rules", web)
+	}
+	if web.Rules[0].Seq != 10 || web.Rules[1].Seq != 20 {
+		t.Errorf("rules are not sorted by sequence number: %d, %d", web.Rules[0].Seq, web.Rules[1].Seq)
+	}
+	if !reflect.DeepEqual(web.Rules[0].DstPorts, []portRange{{80, 80}, {443, 443}}) {
+		t.Errorf("rule 10 destination ports = %v", web.Rules[0].DstPorts)
+	}
+	if !web.Rules[5].Established {
+		t.Errorf("rule 60 should be established only")
+	}
+	if web.Rules[6].Err == nil {
+		t.Errorf("rule 70 with object-group should be marked unsupported")
+	}
+
+	v6 := acls["V6"]
+	if v6 == nil || !v6.IPv6 || len(v6.Rules) != 2 || v6.Rules[0].Seq != 10 {
+		t.Errorf("ACL V6 = %+v, want IPv6 ACL with 2 rules", v6)
+	}
+}
+
+func TestParseCiscoShowIPv6ACL(t *testing.T) {
+	acls := mustParseACLs(t, `IPv6 access list V6OUT
+    permit tcp any any eq www (12 matches) sequence 20
+    permit tcp 2001:db8::/32 any eq 22 sequence 10
+    permit tcp any any eq 80 sequence 25
+    deny ipv6 any any sequence 30
+`)
+	v6 := acls["V6OUT"]
+	if v6 == nil || !v6.IPv6 || len(v6.Rules) != 4 {
+		t.Fatalf("ACL V6OUT = %+v, want IPv6 ACL with 4 rules", v6)
+	}
+	var seqs []int
+	for _, rule := range v6.Rules {
+		seqs = append(seqs, rule.Seq)
+		if len(rule.Options) > 0 {
+			t.Errorf("sequence %d options = %v, want none", rule.Seq, rule.Options)
+		}
+	}
+	if !reflect.DeepEqual(seqs, []int{10, 20, 25, 30}) {
+		t.Errorf("sequence numbers = %v, want [10 20 25 30]", seqs)
+	}
+
+	packet, _ := parseACLPacket("2001:db8:1::1", "2001:db8:2::1", "tcp", "", "80", false)
+	steps := evaluateACL(v6, packet)
+	if len(steps) != 1 || steps[0].Verdict != aclMatch || steps[0].Rule.Line != 2 {
+		t.Errorf("evaluateACL() = %+v, want a single match on line 2", steps)
+	}
+	shadowed := findShadowedRules(v6.Rules)
+	if len(shadowed) != 1 || shadowed[0].Rule.Line != 4 || shadowed[0].By.Line != 2 {
+		t.Errorf("findShadowedRules() = %+v, want line 4 shadowed by line 2", shadowed)
+	}
+}
+
+func TestUnnumberedACLRules(t *testing.T) {
+	tests := []struct {
+		name   string
+		text   string
+		acl    string
+		seqs   []int
+		lines  []int
+		permit bool
+	}{
+		{"Cisco appends after the last sequence", "ip access-list extended X\n 10 permit tcp any any eq 22\n deny ip any any\n",
+			"X", []int{10, 20}, []int{2, 3}, true},
+		{"Cisco numbers from the highest sequence",
+			"ip access-list extended X\n 15 deny tcp any any eq 23\n permit tcp any any eq 22\n 5 deny ip any any\n",
+			"X", []int{5, 15, 25}, []int{4, 2, 3}, false},
+		{"Huawei uses the next multiple of the step",
+			"acl number 3000\n rule 7 deny tcp destination-port eq 23\n rule permit tcp destination-port eq 22\n rule 20 deny ip\n",
+			"3000", []int{7, 10, 20}, []int{2, 3, 4}, true},
+		{"Huawei sorts numbered and unnumbered rules",
+			"acl number 3000\n rule 10 deny ip\n rule 5 permit tcp destination-port eq 22\n rule deny udp\n",
+			"3000", []int{5, 10, 15}, []int{3, 2, 4}, true},
+	}
+
+	packet, _ := parseACLPacket("10.0.0.1", "10.0.0.2", "tcp", "", "22", false)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := mustParseACLs(t, tt.text)[tt.acl]
+			if a == nil {
+				t.Fatalf("ACL %s not parsed", tt.acl)
+			}
+			var seqs, lines []int
+			for _, rule := range a.Rules {
+				seqs = append(seqs, rule.Seq)
+				lines = append(lines, rule.Line)
+			}
+			if !reflect.DeepEqual(seqs, tt.seqs) || !reflect.DeepEqual(lines, tt.lines) {
+				t.Errorf("rules = seq %v lines %v, want seq %v lines %v", seqs, lines, tt.seqs, tt.lines)
+			}
+			steps := evaluateACL(a, packet)
+			if len(steps) == 0 || steps[len(steps)-1].Rule.Permit != tt.permit {
+				t.Errorf("evaluateACL() = %+v, want permit %v", steps, tt.permit)
+			}
+		})
+	}
+}
+
+func TestParseHuaweiACLs(t *testing.T) {
+	if format := detectACLFormat(sampleHuaweiACL); format != aclFormatHuawei {
+		t.Fatalf("detectACLFormat() = %s, want huawei", format)
+	}
+	acls := mustParseACLs(t, sampleHuaweiACL)
+
+	basic := acls["2000"]
+	if basic == nil || basic.Type != "basic" || len(basic.Rules) != 2 {
+		t.Fatalf("ACL 2000 = %+v, want basic ACL with 2 rules", basic)
+	}
+
+	adv := acls["3000"]
+	if adv == nil || adv.Type != "advanced" || len(adv.Rules) != 4 {
+		t.Fatalf("ACL 3000 = %+v, want advanced ACL with 4 rules", adv)
+	}
+	var seqs []int
+	for _, rule := range adv.Rules {
+		seqs = append(seqs, rule.Seq)
+	}
+	if !reflect.DeepEqual(seqs, []int{5, 10, 20, 30}) {
+		t.Errorf("rule IDs = %v, want sorted", seqs)
+	}
+	if rule := adv.Rules[0]; rule.Protocol != 6 || rule.Dst.Wildcard == nil || !reflect.DeepEqual(rule.DstPorts, []portRange{{443, 443}}) {
+		t.Errorf("rule 5 = %+v", rule)
+	}
+	if rule := adv.Rules[3]; !reflect.DeepEqual(rule.Options, []string{"icmp-type echo"}) {
+		t.Errorf("rule 30 options = %v, want [icmp-type echo]", rule.Options)
+	}
+
+	v6 := acls["V6"]
+	if v6 == nil || !v6.IPv6 || v6.Type != "advanced" || len(v6.Rules) != 1 {
+		t.Errorf("ACL V6 = %+v, want IPv6 advanced ACL with 1 rule", v6)
+	}
+}
+
+func TestEvaluateACL(t *testing.T) {
+	acls := mustParseACLs(t, sampleCiscoACL)
+	tests := []struct {
+		name     string
+		acl      string
+		packet   []string // src dst proto sport dport
+		wantLine int
+		verdict  aclVerdict
+		maybes   int
+	}{
+		{"Web traffic", "WEB", []string{"198.51.100.1", "192.0.2.10", "tcp", "", "443"}, 10, aclMatch, 0},
+		{"SSH from non-contiguous range", "WEB", []string{"10.7.0.9", "192.0.2.1", "tcp", "", "22"}, 9, aclMatch, 0},
+		{"Denied before shadowed permit", "WEB", []string{"10.1.2.3", "192.0.2.1", "tcp", "", "22"}, 11, aclMatch, 0},
+		{"Unknown protocol", "WEB", []string{"198.51.100.1", "192.0.2.1", "", "", ""}, 16, aclMatch, 2},
+		{"ICMP depends on type", "WEB", []string{"198.51.100.1", "192.0.2.1", "icmp", "", ""}, 16, aclMatch, 2},
+		{"Standard ACL", "10", []string{"10.0.0.5", "", "", "", ""}, 4, aclMatch, 0},
+		{"IPv6", "V6", []string{"2001:db8::1", "2001:db8:1::1", "tcp", "", "ssh"}, 19, aclMatch, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := tt.packet
+			packet, err := parseACLPacket(p[0], p[1], p[2], p[3], p[4], false)
+			if err != nil {
+				t.Fatalf("parseACLPacket() error = %v", err)
+			}
+			steps := evaluateACL(acls[tt.acl], packet)
+			if len(steps) == 0 {
+				t.Fatal("evaluateACL() matched nothing")
+			}
+			last := steps[len(steps)-1]
+			if last.Rule.Line != tt.wantLine || last.Verdict != tt.verdict {
+				t.Errorf("last step = line %d verdict %d, want line %d verdict %d", last.Rule.Line, last.Verdict, tt.wantLine, tt.verdict)
+			}
+			maybes := 0
+			for _, step := range steps {
+				if step.Verdict == aclMaybe {
+					maybes++
+				}
+			}
+			if maybes != tt.maybes {
+				t.Errorf("possible matches = %d, want %d", maybes, tt.maybes)
+			}
+		})
+	}
+
+	packet, _ := parseACLPacket("198.51.100.1", "192.0.2.1", "tcp", "", "25", true)
+	steps := evaluateACL(acls["WEB"], packet)
+	if last := steps[len(steps)-1]; last.Rule.Line != 14 {
+		t.Errorf("established packet matched line %d, want 14", last.Rule.Line)
+	}
+
+	huawei := mustParseACLs(t, sampleHuaweiACL)
+	packet, _ = parseACLPacket("172.16.0.1", "8.8.8.8", "icmp", "", "", false)
+	steps = evaluateACL(huawei["3000"], packet)
+	if len(steps) != 1 || steps[0].Verdict != aclMaybe || !reflect.DeepEqual(steps[0].Depends, []string{`"icmp-type echo"`}) {
+		t.Errorf("evaluateACL() = %+v, want only a possible match on icmp-type echo", steps)
+	}
+}
+
+func TestFindShadowedRules(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		acl      string
+		expected map[int]int // 被覆盖的行 -> 覆盖它的行
+	}{
+		{"Cisco standard", sampleCiscoACL, "10", map[int]int{5: 4}},
+		{"Cisco extended", sampleCiscoACL, "WEB", map[int]int{12: 11}},
+		{"Huawei basic host", sampleHuaweiACL, "2000", map[int]int{4: 3}},
+		{"Huawei advanced", sampleHuaweiACL, "3000", map[int]int{}},
+		{"Port list covers name", "ip access-list extended X\n permit tcp any any eq 22 80\n permit tcp host 10.0.0.1 any eq www\n",
+			"X", map[int]int{3: 2}},
+		{"Empty port range covers nothing",
+			"ip access-list extended E\n permit tcp any any gt 65535\n permit tcp any any eq 80\n", "E", map[int]int{}},
+		{"Established does not cover new connections", "ip access-list extended X\n permit tcp any any established\n deny tcp any any\n",
+			"X", map[int]int{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			acls := mustParseACLs(t, tt.text)
+			result := make(map[int]int)
+			for _, s := range findShadowedRules(acls[tt.acl].Rules) {
+				result[s.Rule.Line] = s.By.Line
+			}
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("findShadowedRules() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseACLPacketErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		packet []string
+	}{
+		{"Invalid address", []string{"10.0.0.300", "", "", "", ""}},
+		{"Mixed families", []string{"10.0.0.1", "2001:db8::1", "", "", ""}},
+		{"Unknown protocol", []string{"10.0.0.1", "", "bogus", "", ""}},
+		{"Invalid port", []string{"10.0.0.1", "", "tcp", "", "70000"}},
+		{"Ports with ICMP", []string{"10.0.0.1", "", "icmp", "", "22"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := tt.packet
+			if _, err := parseACLPacket(p[0], p[1], p[2], p[3], p[4], false); err == nil {
+				t.Error("parseACLPacket() should fail")
+			}
+		})
+	}
+}
+
+func TestACLPacketString(t *testing.T) {
+	tests := []struct {
+		packet   []string // src dst proto sport dport
+		expected string
+	}{
+		{[]string{"10.0.0.1", "192.0.2.1", "tcp", "1000", "443"}, "tcp 10.0.0.1:1000 -> 192.0.2.1:443"},
+		{[]string{"", "10.0.0.1", "tcp", "1000", ""}, "tcp any:1000 -> 10.0.0.1"},
+		{[]string{"2001:db8::1", "", "udp", "", "53"}, "udp 2001:db8::1 -> any:53"},
+		{[]string{"", "", "", "", ""}, "any protocol any -> any"},
+	}
+
+	for _, tt := range tests {
+		p := tt.packet
+		packet, err := parseACLPacket(p[0], p[1], p[2], p[3], p[4], false)
+		if err != nil {
+			t.Fatalf("parseACLPacket(%v) error = %v", p, err)
+		}
+		if result := packet.String(); result != tt.expected {
+			t.Errorf("String() = %q, want %q", result, tt.expected)
+		}
+	}
+}
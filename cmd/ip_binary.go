@@ -0,0 +1,173 @@
+/*
+Copyright © 2024-2025 Auska <luodan0709@live.cn>
+
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+)
+
+// ipv6SiteBits IPv6 中全局路由前缀与子网 ID 的常见分界（/48）
+const ipv6SiteBits = 48
+
+// 二进制视图中标记每一位角色的字符
+const (
+	bitNetwork byte = 'n'
+	bitSubnet  byte = 's'
+	bitHost    byte = 'h'
+)
+
+// classfulBits 返回 IPv4 地址的有类网络位数和类别，D/E 类没有网络位划分，返回 0
+func classfulBits(addr netip.Addr) (int, string) {
+	first := addr.As4()[0]
+	switch {
+	case first < 128:
+		return 8, "A"
+	case first < 192:
+		return 16, "B"
+	case first < 224:
+		return 24, "C"
+	case first < 240:
+		return 0, "D"
+	default:
+		return 0, "E"
+	}
+}
+
+// baseBits 返回划分网络位和子网位的基准长度：IPv4 为有类网络长度，IPv6 为 /48
+func baseBits(addr netip.Addr) (int, string) {
+	if addr.Is4() {
+		bits, class := classfulBits(addr)
+		return bits, "class " + class
+	}
+	return ipv6SiteBits, "routing prefix"
+}
+
+// bitRoles 返回每一位的角色：基准长度之前为网络位，基准长度到前缀长度之间为子网位，其余为主机位
+func bitRoles(bitLen, prefixBits, base int) string {
+	var sb strings.Builder
+	sb.Grow(bitLen)
+	for i := 0; i < bitLen; i++ {
+		switch {
+		case i >= prefixBits:
+			sb.WriteByte(bitHost)
+		case i >= base && base > 0:
+			sb.WriteByte(bitSubnet)
+		default:
+			sb.WriteByte(bitNetwork)
+		}
+	}
+	return sb.String()
+}
+
+// groupString 每 size 个字符插入分隔符，与 formatBits 的分组方式一致
+func groupString(s string, size int, sep string) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i += size {
+		if i > 0 {
+			sb.WriteString(sep)
+		}
+		sb.WriteString(s[i:min(i+size, len(s))])
+	}
+	return sb.String()
+}
+
+// magicNumber 描述“关键字节”：前缀边界所在的 IPv4 字节或 IPv6 十六位段
+type magicNumber struct {
+	Group     int  // 从 1 开始的字节/段序号
+	MaskValue uint // 该字节/段的掩码值
+	Magic     uint // 2^位宽 - 掩码值，即子网在该字节/段上的步长
+	Start     uint // 本子网在该字节/段上的起始值
+	End       uint // 本子网在该字节/段上的结束值
+}
+
+// findMagicNumber 计算前缀的关键字节和魔数，/0 没有关键字节
+func findMagicNumber(prefix netip.Prefix) (magicNumber, bool) {
+	if prefix.Bits() == 0 {
+		return magicNumber{}, false
+	}
+
+	width := 8
+	if prefix.Addr().Is6() {
+		width = 16
+	}
+	index := (prefix.Bits() - 1) / width
+	ones := prefix.Bits() - index*width
+
+	full := uint(1) << width
+	maskValue := full - uint(1)<<(width-ones)
+	value := groupValue(prefix.Masked().Addr(), index, width)
+
+	return magicNumber{
+		Group:     index + 1,
+		MaskValue: maskValue,
+		Magic:     full - maskValue,
+		Start:     value,
+		End:       value + full - maskValue - 1,
+	}, true
+}
+
+// groupValue 返回地址中第 index 个宽度为 width 位的分组的值
+func groupValue(addr netip.Addr, index, width int) uint {
+	b := addr.AsSlice()
+	if width == 8 {
+		return uint(b[index])
+	}
+	return uint(b[index*2])<<8 | uint(b[index*2+1])
+}
+
+// printBinaryBreakdown 输出地址、掩码、网络地址的二进制形式及位边界
+func printBinaryBreakdown(prefix netip.Prefix) {
+	addr := prefix.Addr()
+	bitLen := addr.BitLen()
+	group, sep, groupName := 8, ".", "Octet"
+	if addr.Is6() {
+		group, sep, groupName = 16, ":", "Hextet"
+	}
+
+	mask, _ := netip.AddrFromSlice(net.CIDRMask(prefix.Bits(), bitLen))
+	network := prefix.Masked().Addr()
+	last := prefixLastAddr(prefix)
+
+	base, baseName := baseBits(addr)
+	roles := bitRoles(bitLen, prefix.Bits(), base)
+
+	lastLabel := "Binary Broadcast:"
+	if addr.Is6() {
+		lastLabel = "Binary Last:"
+	}
+	fmt.Printf("%-18s%s\n", "Binary Address:", formatBits(addr.AsSlice(), group, sep))
+	fmt.Printf("%-18s%s\n", "Binary Mask:", formatBits(mask.AsSlice(), group, sep))
+	fmt.Printf("%-18s%s\n", "Binary Network:", formatBits(network.AsSlice(), group, sep))
+	fmt.Printf("%-18s%s\n", lastLabel, formatBits(last.AsSlice(), group, sep))
+	fmt.Printf("%-18s%s\n", "Bit Roles:", groupString(roles, group, sep))
+
+	networkBits := strings.Count(roles, string(bitNetwork))
+	subnetBits := strings.Count(roles, string(bitSubnet))
+	hostBits := strings.Count(roles, string(bitHost))
+	if base > 0 {
+		baseName = fmt.Sprintf("%s /%d", baseName, base)
+	}
+	fmt.Printf("Network Bits: %d (%s), Subnet Bits: %d, Host Bits: %d\n", networkBits, baseName, subnetBits, hostBits)
+	if base > 0 && prefix.Bits() < base {
+		fmt.Printf("Supernet: aggregates %s /%d networks\n", formatCount(powerOfTwo(base-prefix.Bits())), base)
+	}
+
+	if magic, ok := findMagicNumber(prefix); ok {
+		full := uint(1) << group
+		if addr.Is4() {
+			fmt.Printf("Interesting %s: %d (mask %d)\n", groupName, magic.Group, magic.MaskValue)
+			fmt.Printf("%s Range: %d - %d\n", groupName, magic.Start, magic.End)
+		} else {
+			fmt.Printf("Interesting %s: %d (mask %x)\n", groupName, magic.Group, magic.MaskValue)
+			fmt.Printf("%s Range: %x - %x\n", groupName, magic.Start, magic.End)
+		}
+		fmt.Printf("Magic Number: %d (%d - %d)\n", magic.Magic, full, magic.MaskValue)
+	}
+	fmt.Println("Block Size:", formatCount(prefixSize(prefix)))
+}
@@ -0,0 +1,93 @@
+//go:build unit
+
+/*
+Copyright © 2024-2025 Auska <luodan0709@live.cn>
+
+*/
+
+package cmd
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestClassfulBits(t *testing.T) {
+	tests := []struct {
+		addr  string
+		bits  int
+		class string
+	}{
+		{"10.1.2.3", 8, "A"},
+		{"127.0.0.1", 8, "A"},
+		{"172.16.0.1", 16, "B"},
+		{"192.168.1.1", 24, "C"},
+		{"224.0.0.5", 0, "D"},
+		{"240.0.0.1", 0, "E"},
+	}
+
+	for _, tt := range tests {
+		bits, class := classfulBits(netip.MustParseAddr(tt.addr))
+		if bits != tt.bits || class != tt.class {
+			t.Errorf("classfulBits(%s) = %d, %s, want %d, %s", tt.addr, bits, class, tt.bits, tt.class)
+		}
+	}
+}
+
+func TestBitRoles(t *testing.T) {
+	tests := []struct {
+		name       string
+		bitLen     int
+		prefixBits int
+		base       int
+		expected   string
+	}{
+		{"Subnetted class C", 32, 26, 24, "nnnnnnnnnnnnnnnnnnnnnnnnsshhhhhh"},
+		{"Supernet", 32, 6, 8, "nnnnnnhhhhhhhhhhhhhhhhhhhhhhhhhh"},
+		{"No classful base", 32, 4, 0, "nnnnhhhhhhhhhhhhhhhhhhhhhhhhhhhh"},
+		{"Host route", 32, 32, 8, "nnnnnnnnssssssssssssssssssssssss"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := bitRoles(tt.bitLen, tt.prefixBits, tt.base); result != tt.expected {
+				t.Errorf("bitRoles() = %s, want %s", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGroupString(t *testing.T) {
+	if result := groupString("nnnnnnnnsshhhhhh", 8, "."); result != "nnnnnnnn.sshhhhhh" {
+		t.Errorf("groupString() = %s", result)
+	}
+	if result := groupString("abcde", 2, ":"); result != "ab:cd:e" {
+		t.Errorf("groupString() = %s", result)
+	}
+}
+
+func TestFindMagicNumber(t *testing.T) {
+	tests := []struct {
+		prefix   string
+		expected magicNumber
+	}{
+		{"192.168.1.130/25", magicNumber{Group: 4, MaskValue: 128, Magic: 128, Start: 128, End: 255}},
+		{"172.16.5.4/22", magicNumber{Group: 3, MaskValue: 252, Magic: 4, Start: 4, End: 7}},
+		{"10.0.0.0/8", magicNumber{Group: 1, MaskValue: 255, Magic: 1, Start: 10, End: 10}},
+		{"10.0.0.1/32", magicNumber{Group: 4, MaskValue: 255, Magic: 1, Start: 1, End: 1}},
+		{"2001:db8:abcd:12::/62", magicNumber{Group: 4, MaskValue: 0xfffc, Magic: 4, Start: 0x10, End: 0x13}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.prefix, func(t *testing.T) {
+			result, ok := findMagicNumber(netip.MustParsePrefix(tt.prefix))
+			if !ok || result != tt.expected {
+				t.Errorf("findMagicNumber() = %+v, %v, want %+v", result, ok, tt.expected)
+			}
+		})
+	}
+
+	if _, ok := findMagicNumber(netip.MustParsePrefix("0.0.0.0/0")); ok {
+		t.Error("findMagicNumber(/0) should report no interesting octet")
+	}
+}
@@ -0,0 +1,244 @@
+/*
+Copyright © 2024-2025 Auska <luodan0709@live.cn>
+
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"math/big"
+	"net/netip"
+	"strconv"
+
+	"github.com/spf13/cobra"
+	"macconv/pkg/errors"
+	"macconv/pkg/logger"
+)
+
+var ipCalcCmd = &cobra.Command{
+	Use:   "calc",
+	Short: "Address arithmetic and prefix comparison",
+	Long: `
+Arithmetic on IPv4 and IPv6 addresses and prefixes. For example:
+
+	macconv ip calc add 10.0.0.1 5
+	macconv ip calc sub 2001:db8::10 16
+	macconv ip calc distance 10.0.0.1 10.0.1.0
+	macconv ip calc next 10.0.0.0/31 4
+	macconv ip calc prev 2001:db8:1::/48
+	macconv ip calc supernet 10.1.2.0/24 16
+	macconv ip calc relation 10.0.0.0/24 10.0.1.0/24`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := cmd.Help(); err != nil {
+			logger.PrintErrorWithMessage("failed to show help", err)
+		}
+	},
+}
+
+var ipCalcAddCmd = &cobra.Command{
+	Use:   "add IP N",
+	Short: "Add N to an address",
+	Run:   func(cmd *cobra.Command, args []string) { runAddrOffset(cmd, args, 1) },
+}
+
+var ipCalcSubCmd = &cobra.Command{
+	Use:   "sub IP N",
+	Short: "Subtract N from an address",
+	Run:   func(cmd *cobra.Command, args []string) { runAddrOffset(cmd, args, -1) },
+}
+
+var ipCalcDistanceCmd = &cobra.Command{
+	Use:   "distance IP IP",
+	Short: "Distance between two addresses",
+	Run:   runAddrDistance,
+}
+
+var ipCalcNextCmd = &cobra.Command{
+	Use:   "next CIDR [COUNT]",
+	Short: "Next subnets of the same size",
+	Run:   func(cmd *cobra.Command, args []string) { runAdjacentPrefixes(cmd, args, 1) },
+}
+
+var ipCalcPrevCmd = &cobra.Command{
+	Use:   "prev CIDR [COUNT]",
+	Short: "Previous subnets of the same size",
+	Run:   func(cmd *cobra.Command, args []string) { runAdjacentPrefixes(cmd, args, -1) },
+}
+
+var ipCalcSupernetCmd = &cobra.Command{
+	Use:   "supernet CIDR LENGTH",
+	Short: "Parent prefix at a given length",
+	Run:   runSupernet,
+}
+
+var ipCalcRelationCmd = &cobra.Command{
+	Use:   "relation CIDR CIDR",
+	Short: "Relationship between two prefixes",
+	Run:   runPrefixRelation,
+}
+
+func init() {
+	ipCmd.AddCommand(ipCalcCmd)
+	ipCalcCmd.AddCommand(ipCalcAddCmd, ipCalcSubCmd, ipCalcDistanceCmd, ipCalcNextCmd,
+		ipCalcPrevCmd, ipCalcSupernetCmd, ipCalcRelationCmd)
+}
+
+// offsetPrefix 返回与 prefix 大小相同、向后（n > 0）或向前（n < 0）偏移 n 个的网段
+func offsetPrefix(prefix netip.Prefix, n int64) (netip.Prefix, error) {
+	prefix = prefix.Masked()
+	delta := new(big.Int).Mul(prefixSize(prefix), big.NewInt(n))
+	addr, ok := addrAdd(prefix.Addr(), delta)
+	if !ok {
+		return netip.Prefix{}, errors.New(errors.ValidationError, fmt.Sprintf("offset %d from %s leaves the address space", n, prefix))
+	}
+	return netip.PrefixFrom(addr, prefix.Bits()), nil
+}
+
+// supernetOf 返回 prefix 在指定长度上的父网段
+func supernetOf(prefix netip.Prefix, bits int) (netip.Prefix, error) {
+	if bits < 0 || bits > prefix.Bits() {
+		return netip.Prefix{}, errors.New(errors.ValidationError, fmt.Sprintf("supernet length must be between 0 and %d", prefix.Bits()))
+	}
+	return netip.PrefixFrom(prefix.Addr(), bits).Masked(), nil
+}
+
+// addrDistance 返回 b - a
+func addrDistance(a, b netip.Addr) (*big.Int, error) {
+	if a.BitLen() != b.BitLen() {
+		return nil, errors.New(errors.ValidationError, "addresses belong to different families")
+	}
+	return new(big.Int).Sub(addrToInt(b), addrToInt(a)), nil
+}
+
+func calcArgs(cmd *cobra.Command, args []string, min, max int) bool {
+	if len(args) < min || len(args) > max {
+		logger.PrintValidationError(fmt.Sprintf("invalid number of arguments for %s", cmd.Name()))
+		if err := cmd.Help(); err != nil {
+			logger.PrintErrorWithMessage("failed to show help", err)
+		}
+		return false
+	}
+	return true
+}
+
+func runAddrOffset(cmd *cobra.Command, args []string, sign int64) {
+	if !calcArgs(cmd, args, 2, 2) {
+		return
+	}
+	addr, err := parseAddr(args[0])
+	if err != nil {
+		logger.PrintErrorWithMessage("failed to parse address", err)
+		return
+	}
+	n, ok := new(big.Int).SetString(args[1], 0)
+	if !ok {
+		logger.PrintValidationError(fmt.Sprintf("invalid number: %s", args[1]))
+		return
+	}
+
+	n.Mul(n, big.NewInt(sign))
+	result, ok := addrAdd(addr, n)
+	if !ok {
+		logger.PrintValidationError(fmt.Sprintf("%s%+d leaves the address space", args[0], n))
+		return
+	}
+	fmt.Println(result)
+}
+
+func runAddrDistance(cmd *cobra.Command, args []string) {
+	if !calcArgs(cmd, args, 2, 2) {
+		return
+	}
+	a, err := parseAddr(args[0])
+	if err != nil {
+		logger.PrintErrorWithMessage("failed to parse address", err)
+		return
+	}
+	b, err := parseAddr(args[1])
+	if err != nil {
+		logger.PrintErrorWithMessage("failed to parse address", err)
+		return
+	}
+
+	distance, err := addrDistance(a, b)
+	if err != nil {
+		logger.PrintErrorWithMessage("failed to compute distance", err)
+		return
+	}
+	inclusive := new(big.Int).Abs(distance)
+	inclusive.Add(inclusive, big.NewInt(1))
+
+	fmt.Println("Distance:", distance)
+	fmt.Println("Addresses (inclusive):", inclusive)
+}
+
+func runAdjacentPrefixes(cmd *cobra.Command, args []string, direction int64) {
+	if !calcArgs(cmd, args, 1, 2) {
+		return
+	}
+	prefix, err := parsePrefix(args[0])
+	if err != nil {
+		logger.PrintErrorWithMessage("failed to parse CIDR address", err)
+		return
+	}
+
+	count := int64(1)
+	if len(args) == 2 {
+		count, err = strconv.ParseInt(args[1], 10, 64)
+		if err != nil || count < 1 {
+			logger.PrintValidationError(fmt.Sprintf("invalid count: %s", args[1]))
+			return
+		}
+	}
+
+	for i := int64(1); i <= count; i++ {
+		next, err := offsetPrefix(prefix, direction*i)
+		if err != nil {
+			logger.PrintErrorWithMessage("stopped", err)
+			return
+		}
+		fmt.Println(next)
+	}
+}
+
+func runSupernet(cmd *cobra.Command, args []string) {
+	if !calcArgs(cmd, args, 2, 2) {
+		return
+	}
+	prefix, err := parsePrefix(args[0])
+	if err != nil {
+		logger.PrintErrorWithMessage("failed to parse CIDR address", err)
+		return
+	}
+	bits, err := strconv.Atoi(args[1])
+	if err != nil {
+		logger.PrintValidationError(fmt.Sprintf("invalid prefix length: %s", args[1]))
+		return
+	}
+
+	parent, err := supernetOf(prefix, bits)
+	if err != nil {
+		logger.PrintErrorWithMessage("failed to compute supernet", err)
+		return
+	}
+	fmt.Println(parent)
+}
+
+func runPrefixRelation(cmd *cobra.Command, args []string) {
+	if !calcArgs(cmd, args, 2, 2) {
+		return
+	}
+	a, err := parsePrefix(args[0])
+	if err != nil {
+		logger.PrintErrorWithMessage("failed to parse CIDR address", err)
+		return
+	}
+	b, err := parsePrefix(args[1])
+	if err != nil {
+		logger.PrintErrorWithMessage("failed to parse CIDR address", err)
+		return
+	}
+
+	fmt.Printf("%s %s %s\n", a, prefixRange(a).relate(prefixRange(b)), b)
+}
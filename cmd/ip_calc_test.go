@@ -0,0 +1,121 @@
+//go:build unit
+
+/*
+Copyright © 2024-2025 Auska <luodan0709@live.cn>
+
+*/
+
+package cmd
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestOffsetPrefix(t *testing.T) {
+	tests := []struct {
+		name     string
+		prefix   string
+		n        int64
+		expected string
+		wantErr  bool
+	}{
+		{"Next /24", "10.0.0.0/24", 1, "10.0.1.0/24", false},
+		{"Next /31 link", "10.0.0.4/31", 3, "10.0.0.10/31", false},
+		{"Previous /24", "10.0.1.0/24", -1, "10.0.0.0/24", false},
+		{"Host bits are masked", "10.0.0.77/24", 1, "10.0.1.0/24", false},
+		{"IPv6 /48", "2001:db8:ffff::/48", 1, "2001:db9::/48", false},
+		{"IPv6 loopback /128", "2001:db8::1/128", 2, "2001:db8::3/128", false},
+		{"Past the end", "255.255.255.0/24", 1, "", true},
+		{"Before the start", "0.0.0.0/8", -1, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := offsetPrefix(netip.MustParsePrefix(tt.prefix), tt.n)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("offsetPrefix() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && result.String() != tt.expected {
+				t.Errorf("offsetPrefix() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSupernetOf(t *testing.T) {
+	tests := []struct {
+		prefix   string
+		bits     int
+		expected string
+		wantErr  bool
+	}{
+		{"10.1.2.0/24", 16, "10.1.0.0/16", false},
+		{"10.1.2.0/24", 23, "10.1.2.0/23", false},
+		{"10.1.3.0/24", 23, "10.1.2.0/23", false},
+		{"10.1.2.0/24", 24, "10.1.2.0/24", false},
+		{"2001:db8:1234::/48", 32, "2001:db8::/32", false},
+		{"10.1.2.0/24", 25, "", true},
+		{"10.1.2.0/24", -1, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.prefix, func(t *testing.T) {
+			result, err := supernetOf(netip.MustParsePrefix(tt.prefix), tt.bits)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("supernetOf() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && result.String() != tt.expected {
+				t.Errorf("supernetOf() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestAddrDistance(t *testing.T) {
+	d, err := addrDistance(netip.MustParseAddr("10.0.0.1"), netip.MustParseAddr("10.0.1.0"))
+	if err != nil || d.Int64() != 255 {
+		t.Errorf("addrDistance() = %v, %v, want 255", d, err)
+	}
+
+	d, err = addrDistance(netip.MustParseAddr("2001:db8::10"), netip.MustParseAddr("2001:db8::1"))
+	if err != nil || d.Int64() != -15 {
+		t.Errorf("addrDistance() = %v, %v, want -15", d, err)
+	}
+
+	if _, err := addrDistance(netip.MustParseAddr("10.0.0.1"), netip.MustParseAddr("::1")); err == nil {
+		t.Error("addrDistance() expected error for mixed families")
+	}
+}
+
+func TestRangeRelation(t *testing.T) {
+	tests := []struct {
+		a        string
+		b        string
+		expected rangeRelation
+	}{
+		{"10.0.0.0/24", "10.0.0.0/24", relationEqual},
+		{"10.0.0.0/16", "10.0.5.0/24", relationContains},
+		{"10.0.5.0/24", "10.0.0.0/16", relationContained},
+		{"10.0.0.0/24", "10.0.1.0/24", relationAdjacent},
+		{"10.0.1.0/24", "10.0.0.0/24", relationAdjacent},
+		{"10.0.0.0/24", "10.0.2.0/24", relationDisjoint},
+		{"10.0.0.0/24", "2001:db8::/32", relationDisjoint},
+		{"2001:db8::/33", "2001:db8:8000::/33", relationAdjacent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+" "+tt.b, func(t *testing.T) {
+			result := prefixRange(netip.MustParsePrefix(tt.a)).relate(prefixRange(netip.MustParsePrefix(tt.b)))
+			if result != tt.expected {
+				t.Errorf("relate() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+
+	overlapping := addrRange{First: netip.MustParseAddr("10.0.0.0"), Last: netip.MustParseAddr("10.0.0.10")}
+	other := addrRange{First: netip.MustParseAddr("10.0.0.5"), Last: netip.MustParseAddr("10.0.0.20")}
+	if result := overlapping.relate(other); result != relationOverlap {
+		t.Errorf("relate() = %v, want %v", result, relationOverlap)
+	}
+}
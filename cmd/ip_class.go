@@ -0,0 +1,309 @@
+/*
+Copyright © 2024-2025 Auska <luodan0709@live.cn>
+
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"net/netip"
+
+	"github.com/spf13/cobra"
+	"macconv/pkg/logger"
+)
+
+var ipClassifyCmd = &cobra.Command{
+	Use:   "classify",
+	Short: "Classify addresses using the IANA special-purpose registries",
+	Long: `
+Classify IP addresses or prefixes against the IANA IPv4/IPv6 special-purpose
+address registries (private, CGNAT, loopback, link-local, documentation,
+benchmarking, multicast scopes, ULA, 6to4, Teredo, ...) and flag bogons.
+For example:
+
+	macconv ip classify 10.1.2.3 100.64.0.1 2001:db8::1 ff02::1
+	macconv ip classify --file firewall-objects.txt --bogons`,
+	Run: classifyAddresses,
+}
+
+func init() {
+	ipCmd.AddCommand(ipClassifyCmd)
+	ipClassifyCmd.Flags().StringP("file", "f", "", "Read addresses or prefixes from file (\"-\" for stdin)")
+	ipClassifyCmd.Flags().Bool("bogons", false, "Only print bogon or partially bogon entries")
+}
+
+// tristate 注册表中 True / False / N/A 三种取值
+type tristate int
+
+const (
+	triNo tristate = iota
+	triYes
+	triNA
+)
+
+func (t tristate) String() string {
+	switch t {
+	case triYes:
+		return "yes"
+	case triNA:
+		return "n/a"
+	default:
+		return "no"
+	}
+}
+
+// specialPurposeBlock IANA 特殊用途地址注册表中的一项
+type specialPurposeBlock struct {
+	Prefix      netip.Prefix
+	Name        string
+	RFC         string
+	Source      tristate
+	Destination tristate
+	Forwardable tristate
+	Global      tristate
+	Multicast   bool
+}
+
+func block(prefix, name, rfc string, src, dst, fwd, global tristate) specialPurposeBlock {
+	return specialPurposeBlock{
+		Prefix:      netip.MustParsePrefix(prefix),
+		Name:        name,
+		RFC:         rfc,
+		Source:      src,
+		Destination: dst,
+		Forwardable: fwd,
+		Global:      global,
+	}
+}
+
+func multicastBlock(prefix, name, rfc string, fwd, global tristate) specialPurposeBlock {
+	b := block(prefix, name, rfc, triNo, triYes, fwd, global)
+	b.Multicast = true
+	return b
+}
+
+// specialPurposeBlocks IANA IPv4/IPv6 特殊用途地址注册表及组播地址分配
+var specialPurposeBlocks = []specialPurposeBlock{
+	block("0.0.0.0/8", "This network", "RFC 791", triYes, triNo, triNo, triNo),
+	block("0.0.0.0/32", "This host on this network", "RFC 1122", triYes, triNo, triNo, triNo),
+	block("10.0.0.0/8", "Private-Use", "RFC 1918", triYes, triYes, triYes, triNo),
+	block("100.64.0.0/10", "Shared Address Space (CGNAT)", "RFC 6598", triYes, triYes, triYes, triNo),
+	block("127.0.0.0/8", "Loopback", "RFC 1122", triNo, triNo, triNo, triNo),
+	block("169.254.0.0/16", "Link-Local", "RFC 3927", triYes, triYes, triNo, triNo),
+	block("172.16.0.0/12", "Private-Use", "RFC 1918", triYes, triYes, triYes, triNo),
+	block("192.0.0.0/24", "IETF Protocol Assignments", "RFC 6890", triNo, triNo, triNo, triNo),
+	block("192.0.0.0/29", "IPv4 Service Continuity Prefix", "RFC 7335", triYes, triYes, triYes, triNo),
+	block("192.0.0.8/32", "IPv4 dummy address", "RFC 7600", triYes, triNo, triNo, triNo),
+	block("192.0.0.9/32", "Port Control Protocol Anycast", "RFC 7723", triYes, triYes, triYes, triYes),
+	block("192.0.0.10/32", "Traversal Using Relays around NAT Anycast", "RFC 8155", triYes, triYes, triYes, triYes),
+	block("192.0.0.170/32", "NAT64/DNS64 Discovery", "RFC 8880", triNo, triNo, triNo, triNo),
+	block("192.0.0.171/32", "NAT64/DNS64 Discovery", "RFC 8880", triNo, triNo, triNo, triNo),
+	block("192.0.2.0/24", "Documentation (TEST-NET-1)", "RFC 5737", triNo, triNo, triNo, triNo),
+	block("192.31.196.0/24", "AS112-v4", "RFC 7535", triYes, triYes, triYes, triYes),
+	block("192.52.193.0/24", "AMT", "RFC 7450", triYes, triYes, triYes, triYes),
+	block("192.88.99.0/24", "Deprecated (6to4 Relay Anycast)", "RFC 7526", triNo, triNo, triNo, triNo),
+	block("192.168.0.0/16", "Private-Use", "RFC 1918", triYes, triYes, triYes, triNo),
+	block("192.175.48.0/24", "Direct Delegation AS112 Service", "RFC 7534", triYes, triYes, triYes, triYes),
+	block("198.18.0.0/15", "Benchmarking", "RFC 2544", triYes, triYes, triYes, triNo),
+	block("198.51.100.0/24", "Documentation (TEST-NET-2)", "RFC 5737", triNo, triNo, triNo, triNo),
+	block("203.0.113.0/24", "Documentation (TEST-NET-3)", "RFC 5737", triNo, triNo, triNo, triNo),
+	block("240.0.0.0/4", "Reserved", "RFC 1112", triNo, triNo, triNo, triNo),
+	block("255.255.255.255/32", "Limited Broadcast", "RFC 919", triNo, triYes, triNo, triNo),
+
+	multicastBlock("224.0.0.0/4", "Multicast", "RFC 5771", triYes, triYes),
+	multicastBlock("224.0.0.0/24", "Multicast Local Network Control Block", "RFC 5771", triNo, triNo),
+	multicastBlock("224.0.1.0/24", "Multicast Internetwork Control Block", "RFC 5771", triYes, triYes),
+	multicastBlock("232.0.0.0/8", "Source-Specific Multicast", "RFC 4607", triYes, triYes),
+	multicastBlock("233.0.0.0/8", "GLOP Multicast", "RFC 3180", triYes, triYes),
+	multicastBlock("239.0.0.0/8", "Administratively Scoped Multicast", "RFC 2365", triYes, triNo),
+	multicastBlock("239.255.0.0/16", "IPv4 Local Scope Multicast", "RFC 2365", triYes, triNo),
+
+	block("::/128", "Unspecified Address", "RFC 4291", triYes, triNo, triNo, triNo),
+	block("::1/128", "Loopback Address", "RFC 4291", triNo, triNo, triNo, triNo),
+	block("::/96", "Deprecated (IPv4-compatible Address)", "RFC 4291", triNo, triNo, triNo, triNo),
+	block("::ffff:0:0/96", "IPv4-mapped Address", "RFC 4291", triNo, triNo, triNo, triNo),
+	block("64:ff9b::/96", "IPv4-IPv6 Translation (NAT64)", "RFC 6052", triYes, triYes, triYes, triYes),
+	block("64:ff9b:1::/48", "IPv4-IPv6 Local-Use Translation", "RFC 8215", triYes, triYes, triYes, triNo),
+	block("100::/64", "Discard-Only Address Block", "RFC 6666", triYes, triYes, triYes, triNo),
+	block("2001::/23", "IETF Protocol Assignments", "RFC 2928", triNo, triNo, triNo, triNo),
+	block("2001::/32", "Teredo", "RFC 4380", triYes, triYes, triYes, triNA),
+	block("2001:1::1/128", "Port Control Protocol Anycast", "RFC 7723", triYes, triYes, triYes, triYes),
+	block("2001:1::2/128", "Traversal Using Relays around NAT Anycast", "RFC 8155", triYes, triYes, triYes, triYes),
+	block("2001:1::3/128", "DNS-SD Service Registration Protocol Anycast", "RFC 9665", triYes, triYes, triYes, triYes),
+	block("2001:2::/48", "Benchmarking", "RFC 5180", triYes, triYes, triYes, triNo),
+	block("2001:3::/32", "AMT", "RFC 7450", triYes, triYes, triYes, triYes),
+	block("2001:4:112::/48", "AS112-v6", "RFC 7535", triYes, triYes, triYes, triYes),
+	block("2001:10::/28", "Deprecated (previously ORCHID)", "RFC 4843", triNo, triNo, triNo, triNo),
+	block("2001:20::/28", "ORCHIDv2", "RFC 7343", triYes, triYes, triYes, triYes),
+	block("2001:30::/28", "Drone Remote ID Protocol Entity Tags (DETs)", "RFC 9374", triYes, triYes, triYes, triYes),
+	block("2001:db8::/32", "Documentation", "RFC 3849", triNo, triNo, triNo, triNo),
+	block("2002::/16", "6to4", "RFC 3056", triYes, triYes, triYes, triNA),
+	block("2620:4f:8000::/48", "Direct Delegation AS112 Service", "RFC 7534", triYes, triYes, triYes, triYes),
+	block("3fff::/20", "Documentation", "RFC 9637", triNo, triNo, triNo, triNo),
+	block("5f00::/16", "Segment Routing (SRv6) SIDs", "RFC 9602", triYes, triYes, triYes, triNo),
+	block("fc00::/7", "Unique-Local", "RFC 4193", triYes, triYes, triYes, triNo),
+	block("fe80::/10", "Link-Local Unicast", "RFC 4291", triYes, triYes, triNo, triNo),
+	block("fec0::/10", "Deprecated (Site-Local Unicast)", "RFC 3879", triNo, triNo, triNo, triNo),
+
+	multicastBlock("ff00::/8", "Multicast", "RFC 4291", triYes, triYes),
+	multicastBlock("ff02::1:ff00:0/104", "Solicited-Node Multicast", "RFC 4291", triNo, triNo),
+}
+
+var (
+	ipv4GlobalUnicast = block("0.0.0.0/0", "Global Unicast", "RFC 791", triYes, triYes, triYes, triYes)
+	ipv6GlobalUnicast = block("2000::/3", "Global Unicast", "RFC 4291", triYes, triYes, triYes, triYes)
+	ipv6Unassigned    = block("::/0", "Reserved by IETF (unassigned)", "RFC 4291", triNo, triNo, triNo, triNo)
+)
+
+// ipv6MulticastScopes RFC 7346 定义的组播范围
+var ipv6MulticastScopes = map[byte]string{
+	0x1: "interface-local",
+	0x2: "link-local",
+	0x3: "realm-local",
+	0x4: "admin-local",
+	0x5: "site-local",
+	0x8: "organization-local",
+	0xe: "global",
+}
+
+// addressClass 地址分类结果
+type addressClass struct {
+	Block specialPurposeBlock
+	// Mixed 为 true 表示被分类的网段中还包含更具体的特殊用途地址块
+	Mixed bool
+	// Scope 组播地址的范围描述
+	Scope string
+}
+
+// Bogon 判断地址是否不应出现在公网路由中
+// 全局可达为 N/A 的块（Teredo、6to4）取决于嵌入的 IPv4 地址，不视为 bogon
+func (c addressClass) Bogon() bool {
+	return c.Block.Global == triNo || c.Block.Multicast
+}
+
+// classifyPrefix 查找完整覆盖该网段的最具体的特殊用途地址块
+func classifyPrefix(prefix netip.Prefix) addressClass {
+	addr := prefix.Addr()
+
+	var result addressClass
+	var best *specialPurposeBlock
+	for i := range specialPurposeBlocks {
+		b := &specialPurposeBlocks[i]
+		if b.Prefix.Bits() > prefix.Bits() {
+			if prefix.Contains(b.Prefix.Addr()) {
+				result.Mixed = true
+			}
+			continue
+		}
+		if b.Prefix.Contains(addr) && (best == nil || b.Prefix.Bits() > best.Prefix.Bits()) {
+			best = b
+		}
+	}
+
+	switch {
+	case best != nil:
+		result.Block = *best
+	case addr.Is4():
+		result.Block = ipv4GlobalUnicast
+	case ipv6GlobalUnicast.Prefix.Contains(addr):
+		result.Block = ipv6GlobalUnicast
+	default:
+		result.Block = ipv6Unassigned
+	}
+
+	if addr.Is6() && result.Block.Multicast {
+		scope := addr.As16()[1] & 0x0f
+		result.Scope = describeIPv6MulticastScope(scope)
+		// 组播地址能否转发取决于范围：接口本地和链路本地不可转发，只有全局范围可全局到达
+		if scope <= 0x2 {
+			result.Block.Forwardable = triNo
+		}
+		if scope != 0xe {
+			result.Block.Global = triNo
+		}
+	}
+
+	return result
+}
+
+// describeIPv6MulticastScope 描述 IPv6 组播地址第二个字节低 4 位的范围
+func describeIPv6MulticastScope(scope byte) string {
+	if name, ok := ipv6MulticastScopes[scope]; ok {
+		return fmt.Sprintf("%s (%x)", name, scope)
+	}
+	return fmt.Sprintf("unassigned (%x)", scope)
+}
+
+// printAddressClass 在 ip 命令输出中追加分类信息
+func printAddressClass(class addressClass) {
+	name := class.Block.Name
+	if class.Mixed {
+		name += ", includes special-purpose ranges"
+	}
+	fmt.Printf("Address Class: %s (%s, %s)\n", name, class.Block.RFC, class.Block.Prefix)
+	if class.Scope != "" {
+		fmt.Println("Multicast Scope:", class.Scope)
+	}
+	fmt.Println("Globally Reachable:", class.Block.Global)
+	fmt.Println("Forwardable:", class.Block.Forwardable)
+	switch {
+	case class.Bogon():
+		fmt.Println("Bogon: yes")
+	case class.Mixed:
+		fmt.Println("Bogon: partially")
+	default:
+		fmt.Println("Bogon: no")
+	}
+}
+
+func classifyAddresses(cmd *cobra.Command, args []string) {
+	file, _ := cmd.Flags().GetString("file")
+	bogonsOnly, _ := cmd.Flags().GetBool("bogons")
+
+	prefixes, err := collectPrefixes(args, file)
+	if err != nil {
+		logger.PrintErrorWithMessage("failed to load addresses", err)
+		return
+	}
+	if len(prefixes) == 0 {
+		logger.PrintValidationError("no addresses given, pass them as arguments or use --file")
+		if err := cmd.Help(); err != nil {
+			logger.PrintErrorWithMessage("failed to show help", err)
+		}
+		return
+	}
+
+	bogons := 0
+	for _, lp := range prefixes {
+		class := classifyPrefix(lp.Prefix)
+		if class.Bogon() || class.Mixed {
+			bogons++
+		} else if bogonsOnly {
+			continue
+		}
+
+		name := class.Block.Name
+		if class.Scope != "" {
+			name += ", scope " + class.Scope
+		}
+		if class.Mixed {
+			name += ", includes special-purpose ranges"
+		}
+		fmt.Printf("%-24s %-6s global=%-3s forwardable=%-3s %s [%s] %s\n",
+			lp.Prefix, bogonLabel(class), class.Block.Global, class.Block.Forwardable, name, class.Block.RFC, lp.Label)
+	}
+
+	logger.Infof("Classified %d entries, %d bogons", len(prefixes), bogons)
+}
+
+func bogonLabel(class addressClass) string {
+	switch {
+	case class.Bogon():
+		return "BOGON"
+	case class.Mixed:
+		return "MIXED"
+	default:
+		return "ok"
+	}
+}
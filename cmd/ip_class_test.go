@@ -0,0 +1,88 @@
+//go:build unit
+
+/*
+Copyright © 2024-2025 Auska <luodan0709@live.cn>
+
+*/
+
+package cmd
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestClassifyPrefix(t *testing.T) {
+	tests := []struct {
+		name      string
+		prefix    string
+		wantName  string
+		wantBogon bool
+		wantMixed bool
+		wantScope string
+	}{
+		{"RFC 1918", "10.1.2.3/32", "Private-Use", true, false, ""},
+		{"RFC 1918 network", "172.16.0.0/12", "Private-Use", true, false, ""},
+		{"CGNAT", "100.64.0.1/32", "Shared Address Space (CGNAT)", true, false, ""},
+		{"Loopback", "127.0.0.1/32", "Loopback", true, false, ""},
+		{"Link-local", "169.254.10.1/32", "Link-Local", true, false, ""},
+		{"Documentation", "198.51.100.7/32", "Documentation (TEST-NET-2)", true, false, ""},
+		{"Benchmarking", "198.19.0.1/32", "Benchmarking", true, false, ""},
+		{"Most specific wins", "192.0.0.9/32", "Port Control Protocol Anycast", false, false, ""},
+		{"Public IPv4", "8.8.8.8/32", "Global Unicast", false, false, ""},
+		{"Local network control multicast", "224.0.0.5/32", "Multicast Local Network Control Block", true, false, ""},
+		{"Admin scoped multicast", "239.1.1.1/32", "Administratively Scoped Multicast", true, false, ""},
+		{"Limited broadcast", "255.255.255.255/32", "Limited Broadcast", true, false, ""},
+		{"Reserved", "240.0.0.1/32", "Reserved", true, false, ""},
+		{"Prefix covering special ranges", "0.0.0.0/0", "Global Unicast", false, true, ""},
+		{"IPv6 loopback", "::1/128", "Loopback Address", true, false, ""},
+		{"IPv6 ULA", "fd12:3456:789a::1/128", "Unique-Local", true, false, ""},
+		{"IPv6 link-local", "fe80::1/128", "Link-Local Unicast", true, false, ""},
+		{"IPv6 documentation", "2001:db8::/32", "Documentation", true, false, ""},
+		{"Teredo", "2001:0:4136:e378::1/128", "Teredo", false, false, ""},
+		{"6to4", "2002:c000:0204::1/128", "6to4", false, false, ""},
+		{"IPv4-mapped", "::ffff:192.0.2.1/128", "IPv4-mapped Address", true, false, ""},
+		{"NAT64", "64:ff9b::c000:201/128", "IPv4-IPv6 Translation (NAT64)", false, false, ""},
+		{"IPv6 global unicast", "2400:cb00::1/128", "Global Unicast", false, false, ""},
+		{"IPv6 unassigned", "4000::1/128", "Reserved by IETF (unassigned)", true, false, ""},
+		{"IPv6 link-local multicast", "ff02::1/128", "Multicast", true, false, "link-local (2)"},
+		{"IPv6 global multicast", "ff0e::101/128", "Multicast", true, false, "global (e)"},
+		{"Solicited-node multicast", "ff02::1:ff00:1/128", "Solicited-Node Multicast", true, false, "link-local (2)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			class := classifyPrefix(netip.MustParsePrefix(tt.prefix))
+			if class.Block.Name != tt.wantName {
+				t.Errorf("classifyPrefix() name = %v, want %v", class.Block.Name, tt.wantName)
+			}
+			if class.Bogon() != tt.wantBogon {
+				t.Errorf("classifyPrefix() bogon = %v, want %v", class.Bogon(), tt.wantBogon)
+			}
+			if class.Mixed != tt.wantMixed {
+				t.Errorf("classifyPrefix() mixed = %v, want %v", class.Mixed, tt.wantMixed)
+			}
+			if class.Scope != tt.wantScope {
+				t.Errorf("classifyPrefix() scope = %v, want %v", class.Scope, tt.wantScope)
+			}
+		})
+	}
+}
+
+func TestClassifyMulticastReachability(t *testing.T) {
+	linkLocal := classifyPrefix(netip.MustParsePrefix("ff02::1/128"))
+	if linkLocal.Block.Forwardable != triNo || linkLocal.Block.Global != triNo {
+		t.Errorf("ff02::1 forwardable=%v global=%v, want no/no", linkLocal.Block.Forwardable, linkLocal.Block.Global)
+	}
+
+	global := classifyPrefix(netip.MustParsePrefix("ff0e::1/128"))
+	if global.Block.Forwardable != triYes || global.Block.Global != triYes {
+		t.Errorf("ff0e::1 forwardable=%v global=%v, want yes/yes", global.Block.Forwardable, global.Block.Global)
+	}
+
+	// 修改副本不能影响注册表本身
+	again := classifyPrefix(netip.MustParsePrefix("ff0e::1/128"))
+	if again.Block.Global != triYes {
+		t.Errorf("registry entry was modified by a previous classification")
+	}
+}
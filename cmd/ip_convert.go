@@ -0,0 +1,344 @@
+/*
+Copyright © 2024-2025 Auska <luodan0709@live.cn>
+
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"math/big"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"macconv/pkg/errors"
+	"macconv/pkg/logger"
+)
+
+const (
+	ipv4PTRSuffix = ".in-addr.arpa"
+	ipv6PTRSuffix = ".ip6.arpa"
+)
+
+var ipConvertCmd = &cobra.Command{
+	Use:   "convert",
+	Short: "Convert an IP address between representations",
+	Long: `
+Convert an IP address to integer, hex, binary, octal, dotted-hex, IPv4-mapped
+and IPv4-compatible IPv6 and reverse-DNS (PTR) forms. Any of these forms is
+accepted as input. For example:
+
+	macconv ip convert 192.168.1.1
+	macconv ip convert 3232235777
+	macconv ip convert 0xc0a80101
+	macconv ip convert 0300.0250.01.01
+	macconv ip convert 1.1.168.192.in-addr.arpa
+	macconv ip convert 2001:db8::1
+	macconv ip convert 42540766411282592856903984951653826561 --ipv6`,
+	Run: convertAddressRepresentation,
+}
+
+func init() {
+	ipCmd.AddCommand(ipConvertCmd)
+	ipConvertCmd.Flags().BoolP("ipv6", "6", false, "Treat integer, hex, binary and octal input as IPv6")
+}
+
+// parseAddrRepresentation 识别各种地址写法并解析为 IP 地址，同时返回识别出的格式名称
+func parseAddrRepresentation(s string, forceIPv6 bool) (netip.Addr, string, error) {
+	s = strings.TrimSpace(s)
+	lower := strings.ToLower(strings.TrimSuffix(s, "."))
+
+	if addr, err := netip.ParseAddr(s); err == nil {
+		return addr, "IP address", nil
+	}
+
+	switch {
+	case strings.HasSuffix(lower, ipv4PTRSuffix):
+		addr, err := parseIPv4PTR(strings.TrimSuffix(lower, ipv4PTRSuffix))
+		return addr, "in-addr.arpa PTR name", err
+	case strings.HasSuffix(lower, ipv6PTRSuffix):
+		addr, err := parseIPv6PTR(strings.TrimSuffix(lower, ipv6PTRSuffix))
+		return addr, "ip6.arpa PTR name", err
+	case strings.Count(lower, ".") == 3:
+		addr, format, err := parseDottedIPv4(lower)
+		return addr, format, err
+	}
+
+	if addr, ok := parseBinaryAddr(lower, forceIPv6); ok {
+		return addr, "binary", nil
+	}
+
+	n, format, ok := parseInteger(lower)
+	if !ok {
+		return netip.Addr{}, "", errors.New(errors.ParseError, fmt.Sprintf("unrecognized address representation: %s", s))
+	}
+
+	bitLen := 32
+	if forceIPv6 || n.BitLen() > 32 {
+		bitLen = 128
+	}
+	addr, ok := intToAddr(n, bitLen)
+	if !ok {
+		return netip.Addr{}, "", errors.New(errors.ValidationError, fmt.Sprintf("value %s is out of range for an IP address", s))
+	}
+	return addr, format, nil
+}
+
+// parseBinaryAddr 解析不带 0b 前缀的 32 位或 128 位二进制，以及按 16 位用冒号分组的 IPv6 二进制
+// 与 Binary 输出格式对应；32 位 0/1 串优先视为二进制而非十进制整数
+func parseBinaryAddr(s string, forceIPv6 bool) (netip.Addr, bool) {
+	digits := s
+	if strings.Contains(s, ":") {
+		groups := strings.Split(s, ":")
+		if len(groups) != 8 {
+			return netip.Addr{}, false
+		}
+		for _, g := range groups {
+			if len(g) != 16 {
+				return netip.Addr{}, false
+			}
+		}
+		digits = strings.Join(groups, "")
+	}
+	if (len(digits) != 32 && len(digits) != 128) || strings.Trim(digits, "01") != "" {
+		return netip.Addr{}, false
+	}
+
+	n, _ := new(big.Int).SetString(digits, 2)
+	bitLen := len(digits)
+	if forceIPv6 {
+		bitLen = 128
+	}
+	return intToAddr(n, bitLen)
+}
+
+// parseInteger 解析十进制、0x 十六进制、0b 二进制和 0o / 前导 0 八进制整数
+func parseInteger(s string) (*big.Int, string, bool) {
+	type base struct {
+		prefix string
+		radix  int
+		name   string
+	}
+	bases := []base{
+		{"0x", 16, "hex"},
+		{"0b", 2, "binary"},
+		{"0o", 8, "octal"},
+	}
+
+	for _, b := range bases {
+		if strings.HasPrefix(s, b.prefix) && len(s) > len(b.prefix) {
+			n, ok := new(big.Int).SetString(s[len(b.prefix):], b.radix)
+			return n, b.name, ok
+		}
+	}
+
+	if len(s) > 1 && s[0] == '0' {
+		n, ok := new(big.Int).SetString(s[1:], 8)
+		return n, "octal", ok
+	}
+
+	n, ok := new(big.Int).SetString(s, 10)
+	return n, "integer", ok
+}
+
+// parseDottedIPv4 解析点分二进制、点分八进制、点分十六进制的 IPv4 地址
+// 每段按 inet_aton 规则单独识别进制，全部由 8 位 0/1 组成时视为二进制
+func parseDottedIPv4(s string) (netip.Addr, string, error) {
+	parts := strings.Split(s, ".")
+	var b [4]byte
+	format := "dotted"
+
+	allBinary := true
+	for _, part := range parts {
+		if len(part) != 8 || strings.Trim(part, "01") != "" {
+			allBinary = false
+			break
+		}
+	}
+
+	for i, part := range parts {
+		var value uint64
+		var err error
+		switch {
+		case allBinary:
+			value, err = strconv.ParseUint(part, 2, 8)
+			format = "dotted binary"
+		case strings.HasPrefix(part, "0x"):
+			value, err = strconv.ParseUint(part[2:], 16, 8)
+			format = "dotted hex"
+		case len(part) > 1 && part[0] == '0':
+			value, err = strconv.ParseUint(part[1:], 8, 8)
+			format = "dotted octal"
+		default:
+			value, err = strconv.ParseUint(part, 10, 8)
+		}
+		if err != nil {
+			return netip.Addr{}, "", errors.Wrap(errors.ParseError, fmt.Sprintf("invalid octet %q", part), err)
+		}
+		b[i] = byte(value)
+	}
+
+	return netip.AddrFrom4(b), format, nil
+}
+
+// parseIPv4PTR 解析 d.c.b.a（in-addr.arpa 之前的部分）
+func parseIPv4PTR(s string) (netip.Addr, error) {
+	labels := strings.Split(s, ".")
+	if len(labels) != 4 {
+		return netip.Addr{}, errors.New(errors.ParseError, "in-addr.arpa name must have 4 labels")
+	}
+	for i, j := 0, len(labels)-1; i < j; i, j = i+1, j-1 {
+		labels[i], labels[j] = labels[j], labels[i]
+	}
+	addr, err := netip.ParseAddr(strings.Join(labels, "."))
+	if err != nil || !addr.Is4() {
+		return netip.Addr{}, errors.New(errors.ParseError, fmt.Sprintf("invalid in-addr.arpa name: %s", s))
+	}
+	return addr, nil
+}
+
+// parseIPv6PTR 解析 32 个半字节标签（ip6.arpa 之前的部分）
+func parseIPv6PTR(s string) (netip.Addr, error) {
+	labels := strings.Split(s, ".")
+	if len(labels) != 32 {
+		return netip.Addr{}, errors.New(errors.ParseError, "ip6.arpa name must have 32 nibble labels")
+	}
+
+	var b [16]byte
+	for i, label := range labels {
+		nibble, err := strconv.ParseUint(label, 16, 4)
+		if err != nil || len(label) != 1 {
+			return netip.Addr{}, errors.New(errors.ParseError, fmt.Sprintf("invalid nibble %q in ip6.arpa name", label))
+		}
+		pos := 31 - i
+		b[pos/2] |= byte(nibble) << (4 * (1 - pos%2))
+	}
+	return netip.AddrFrom16(b), nil
+}
+
+// reversePointerName 返回反向解析域名（以 . 结尾的 FQDN）
+func reversePointerName(addr netip.Addr) string {
+	if addr.Is4() {
+		b := addr.As4()
+		return fmt.Sprintf("%d.%d.%d.%d%s.", b[3], b[2], b[1], b[0], ipv4PTRSuffix)
+	}
+
+	b := addr.As16()
+	var sb strings.Builder
+	sb.Grow(64 + len(ipv6PTRSuffix) + 1)
+	for i := len(b) - 1; i >= 0; i-- {
+		fmt.Fprintf(&sb, "%x.%x.", b[i]&0x0f, b[i]>>4)
+	}
+	sb.WriteString(ipv6PTRSuffix[1:])
+	sb.WriteString(".")
+	return sb.String()
+}
+
+// formatBits 输出定长二进制，sep 非空时每 group 位插入分隔符
+func formatBits(b []byte, group int, sep string) string {
+	var sb strings.Builder
+	for i, v := range b {
+		if sep != "" && i > 0 && (i*8)%group == 0 {
+			sb.WriteString(sep)
+		}
+		fmt.Fprintf(&sb, "%08b", v)
+	}
+	return sb.String()
+}
+
+// ipv4Representations 返回 IPv4 地址的各种表示形式
+func ipv4Representations(addr netip.Addr) [][2]string {
+	b := addr.As4()
+	n := addrToInt(addr)
+	mapped := netip.AddrFrom16(addr.As16())
+
+	var compat [16]byte
+	copy(compat[12:], b[:])
+	compatible := netip.AddrFrom16(compat)
+
+	return [][2]string{
+		{"Address", addr.String()},
+		{"Integer", n.String()},
+		{"Hex", fmt.Sprintf("0x%08x", n)},
+		{"Binary", formatBits(b[:], 32, "")},
+		{"Dotted Binary", formatBits(b[:], 8, ".")},
+		{"Octal", fmt.Sprintf("0%o", n)},
+		{"Dotted Octal", fmt.Sprintf("0%o.0%o.0%o.0%o", b[0], b[1], b[2], b[3])},
+		{"Dotted Hex", fmt.Sprintf("0x%02x.0x%02x.0x%02x.0x%02x", b[0], b[1], b[2], b[3])},
+		{"IPv4-mapped IPv6", fmt.Sprintf("%s (::ffff:%x:%x)", mapped, uint16(b[0])<<8|uint16(b[1]), uint16(b[2])<<8|uint16(b[3]))},
+		{"IPv4-compatible IPv6", fmt.Sprintf("::%s (%s)", addr, compatible)},
+		{"PTR", reversePointerName(addr)},
+	}
+}
+
+// ipv6Representations 返回 IPv6 地址的各种表示形式
+func ipv6Representations(addr netip.Addr) [][2]string {
+	b := addr.As16()
+	n := addrToInt(addr)
+
+	reps := [][2]string{
+		{"Compressed", addr.WithZone("").String()},
+		{"Expanded", addr.StringExpanded()},
+		{"Integer", n.String()},
+		{"Hex", fmt.Sprintf("0x%032x", n)},
+		{"Binary", formatBits(b[:], 16, ":")},
+		{"Octal", fmt.Sprintf("0%o", n)},
+		{"PTR", reversePointerName(addr)},
+	}
+
+	if addr.Is4In6() {
+		reps = append(reps, [2]string{"Embedded IPv4 (mapped)", addr.Unmap().String()})
+	} else if isIPv4Compatible(addr) {
+		v4 := netip.AddrFrom4([4]byte(b[12:]))
+		reps = append(reps, [2]string{"Embedded IPv4 (compatible)", v4.String()})
+	}
+
+	return reps
+}
+
+// isIPv4Compatible 判断是否为已废弃的 IPv4-compatible 地址（::a.b.c.d，排除 :: 和 ::1）
+func isIPv4Compatible(addr netip.Addr) bool {
+	b := addr.As16()
+	for _, v := range b[:12] {
+		if v != 0 {
+			return false
+		}
+	}
+	return b[12] != 0 || b[13] != 0 || b[14] != 0 || b[15] > 1
+}
+
+func convertAddressRepresentation(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		logger.PrintValidationError("missing address argument")
+		if err := cmd.Help(); err != nil {
+			logger.PrintErrorWithMessage("failed to show help", err)
+		}
+		return
+	}
+
+	forceIPv6, _ := cmd.Flags().GetBool("ipv6")
+
+	addr, format, err := parseAddrRepresentation(args[0], forceIPv6)
+	if err != nil {
+		logger.PrintErrorWithMessage("failed to parse address", err)
+		if err := cmd.Help(); err != nil {
+			logger.PrintErrorWithMessage("failed to show help", err)
+		}
+		return
+	}
+	logger.Debugf("Parsed %s as %s", args[0], format)
+
+	fmt.Println("Input Format:", format)
+
+	reps := ipv4Representations
+	if addr.Is6() {
+		reps = ipv6Representations
+	}
+	for _, rep := range reps(addr) {
+		fmt.Printf("%s: %s\n", rep[0], rep[1])
+	}
+
+	logger.Infof("Successfully converted address: %s", args[0])
+}
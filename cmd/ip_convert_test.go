@@ -0,0 +1,177 @@
+//go:build unit
+
+/*
+Copyright © 2024-2025 Auska <luodan0709@live.cn>
+
+*/
+
+package cmd
+
+import (
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+func TestParseAddrRepresentation(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		forceIPv6  bool
+		expected   string
+		wantFormat string
+		wantErr    bool
+	}{
+		{"Dotted decimal", "192.168.1.1", false, "192.168.1.1", "IP address", false},
+		{"Integer", "3232235777", false, "192.168.1.1", "integer", false},
+		{"Hex", "0xC0A80101", false, "192.168.1.1", "hex", false},
+		{"Binary", "0b11000000101010000000000100000001", false, "192.168.1.1", "binary", false},
+		{"Bare binary", "11000000101010000000000100000001", false, "192.168.1.1", "binary", false},
+		{"Bare binary forced to IPv6", "00000000000000000000000000000001", true, "::1", "binary", false},
+		{
+			"Grouped IPv6 binary",
+			"0010000000000001:0000110110111000:0000000000000000:0000000000000000:" +
+				"0000000000000000:0000000000000000:0000000000000000:0000000000000001",
+			false, "2001:db8::1", "binary", false,
+		},
+		{"Octal", "030052000401", false, "192.168.1.1", "octal", false},
+		{"Octal with 0o prefix", "0o30052000401", false, "192.168.1.1", "octal", false},
+		{"Dotted octal", "0300.0250.01.01", false, "192.168.1.1", "dotted octal", false},
+		{"Dotted hex", "0xc0.0xa8.0x01.0x01", false, "192.168.1.1", "dotted hex", false},
+		{"Dotted binary", "11000000.10101000.00000001.00000001", false, "192.168.1.1", "dotted binary", false},
+		{"IPv4 PTR", "1.1.168.192.in-addr.arpa.", false, "192.168.1.1", "in-addr.arpa PTR name", false},
+		{"IPv4 PTR without trailing dot", "4.3.2.1.IN-ADDR.ARPA", false, "1.2.3.4", "in-addr.arpa PTR name", false},
+		{
+			"IPv6 PTR",
+			"1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa.",
+			false, "2001:db8::1", "ip6.arpa PTR name", false,
+		},
+		{"IPv6", "2001:DB8:0:0:0:0:0:1", false, "2001:db8::1", "IP address", false},
+		{"Small integer forced to IPv6", "1", true, "::1", "integer", false},
+		{"Large integer becomes IPv6", "42540766411282592856903984951653826561", false, "2001:db8::1", "integer", false},
+		{"Hex IPv6", "0x20010db8000000000000000000000001", false, "2001:db8::1", "hex", false},
+		{"Integer too large", "340282366920938463463374607431768211456", false, "", "", true},
+		{"Bad octet", "0x1ff.0.0.1", false, "", "", true},
+		{"Short PTR", "1.168.192.in-addr.arpa", false, "", "", true},
+		{"Garbage", "hello", false, "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr, format, err := parseAddrRepresentation(tt.input, tt.forceIPv6)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseAddrRepresentation() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if addr.String() != tt.expected {
+				t.Errorf("parseAddrRepresentation() = %v, want %v", addr, tt.expected)
+			}
+			if format != tt.wantFormat {
+				t.Errorf("parseAddrRepresentation() format = %v, want %v", format, tt.wantFormat)
+			}
+		})
+	}
+}
+
+func TestReversePointerName(t *testing.T) {
+	tests := []struct {
+		addr     string
+		expected string
+	}{
+		{"192.168.1.1", "1.1.168.192.in-addr.arpa."},
+		{"10.0.0.254", "254.0.0.10.in-addr.arpa."},
+		{"2001:db8::1", "1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			result := reversePointerName(netip.MustParseAddr(tt.addr))
+			if result != tt.expected {
+				t.Errorf("reversePointerName() = %v, want %v", result, tt.expected)
+			}
+
+			back, _, err := parseAddrRepresentation(result, false)
+			if err != nil || back.String() != tt.addr {
+				t.Errorf("round trip = %v, %v, want %v", back, err, tt.addr)
+			}
+		})
+	}
+}
+
+func TestIPv4Representations(t *testing.T) {
+	reps := ipv4Representations(netip.MustParseAddr("192.168.1.1"))
+	expected := map[string]string{
+		"Integer":              "3232235777",
+		"Hex":                  "0xc0a80101",
+		"Dotted Binary":        "11000000.10101000.00000001.00000001",
+		"Octal":                "030052000401",
+		"Dotted Octal":         "0300.0250.01.01",
+		"Dotted Hex":           "0xc0.0xa8.0x01.0x01",
+		"IPv4-mapped IPv6":     "::ffff:192.168.1.1 (::ffff:c0a8:101)",
+		"IPv4-compatible IPv6": "::192.168.1.1 (::c0a8:101)",
+	}
+
+	for _, rep := range reps {
+		if want, ok := expected[rep[0]]; ok && rep[1] != want {
+			t.Errorf("%s = %v, want %v", rep[0], rep[1], want)
+		}
+	}
+}
+
+func TestIPv6Representations(t *testing.T) {
+	reps := ipv6Representations(netip.MustParseAddr("2001:db8::1"))
+	expected := map[string]string{
+		"Compressed": "2001:db8::1",
+		"Expanded":   "2001:0db8:0000:0000:0000:0000:0000:0001",
+		"Hex":        "0x20010db8000000000000000000000001",
+	}
+
+	for _, rep := range reps {
+		if want, ok := expected[rep[0]]; ok && rep[1] != want {
+			t.Errorf("%s = %v, want %v", rep[0], rep[1], want)
+		}
+	}
+
+	mapped := ipv6Representations(netip.MustParseAddr("::ffff:10.0.0.1"))
+	last := mapped[len(mapped)-1]
+	if last[0] != "Embedded IPv4 (mapped)" || last[1] != "10.0.0.1" {
+		t.Errorf("mapped address embedded IPv4 = %v, want 10.0.0.1", last)
+	}
+}
+
+func TestRepresentationsRoundTrip(t *testing.T) {
+	inputs := []string{"192.168.1.1", "0.0.0.1", "255.255.255.255", "2001:db8::1", "::1", "::ffff:10.0.0.1", "::a00:1"}
+	for _, input := range inputs {
+		addr := netip.MustParseAddr(input)
+		var reps [][2]string
+		if addr.Is4() {
+			reps = ipv4Representations(addr)
+		} else {
+			reps = ipv6Representations(addr)
+		}
+
+		for _, rep := range reps {
+			// 内嵌 IPv4 是从地址中提取的另一个地址，不是该地址的表示形式
+			if strings.HasPrefix(rep[0], "Embedded IPv4") {
+				continue
+			}
+			value, _, _ := strings.Cut(rep[1], " (")
+			back, _, err := parseAddrRepresentation(value, addr.Is6())
+			if err != nil {
+				t.Errorf("%s %s = %q does not parse: %v", input, rep[0], value, err)
+				continue
+			}
+			switch rep[0] {
+			case "IPv4-mapped IPv6":
+				back = back.Unmap()
+			case "IPv4-compatible IPv6":
+				back = netip.AddrFrom4([4]byte(back.AsSlice()[12:]))
+			}
+			if back != addr {
+				t.Errorf("%s %s = %q parses back to %v", input, rep[0], value, back)
+			}
+		}
+	}
+}
@@ -0,0 +1,159 @@
+/*
+Copyright © 2024-2025 Auska <luodan0709@live.cn>
+
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"math/big"
+	"net/netip"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"macconv/pkg/logger"
+)
+
+var ipDiffCmd = &cobra.Command{
+	Use:   "diff OLD NEW",
+	Short: "Compare the address space covered by two prefix lists",
+	Long: `
+Compare two prefix lists by the addresses they cover rather than by their
+text. Address space covered only by NEW is reported as added and space
+covered only by OLD as removed, each as the fewest CIDRs. Splitting,
+aggregating or reordering prefixes therefore does not show up as a change.
+
+OLD and NEW are files with one CIDR, range or 10.0.0.* glob per line
+(optional label columns, "-" for stdin). For example:
+
+	macconv ip diff allowlist-yesterday.txt allowlist-today.txt
+	curl -s https://example.com/ranges.txt | macconv ip diff ranges.txt - --exit-code
+
+With --exit-code the command exits with status 1 when the coverage differs,
+so it can be used as a CI check. As with diff(1), status 2 means an error,
+such as a file that cannot be read or parsed, regardless of --exit-code.`,
+	Run: diffPrefixes,
+}
+
+func init() {
+	ipCmd.AddCommand(ipDiffCmd)
+	ipDiffCmd.Flags().Bool("exit-code", false, "Exit with status 1 when the coverage differs")
+}
+
+// 与 diff(1) 一致的退出状态：1 表示覆盖不同（--exit-code），2 表示出错
+const (
+	diffExitDiffers = 1
+	diffExitError   = 2
+)
+
+// prefixDiff 两个网段列表的覆盖差异
+type prefixDiff struct {
+	Added     []addrRange
+	Removed   []addrRange
+	Unchanged []addrRange
+}
+
+// coverage 将网段列表合并为互不重叠的地址范围
+func coverage(prefixes []labeledPrefix) []addrRange {
+	ranges := make([]addrRange, len(prefixes))
+	for i, lp := range prefixes {
+		ranges[i] = prefixRange(lp.Prefix)
+	}
+	return mergeRanges(ranges)
+}
+
+// diffCoverage 按地址覆盖比较两个网段列表
+func diffCoverage(oldPrefixes, newPrefixes []labeledPrefix) prefixDiff {
+	oldRanges, newRanges := coverage(oldPrefixes), coverage(newPrefixes)
+	removed := subtractRanges(oldRanges, newRanges)
+	return prefixDiff{
+		Added:     subtractRanges(newRanges, oldRanges),
+		Removed:   removed,
+		Unchanged: subtractRanges(oldRanges, mergeRanges(removed)),
+	}
+}
+
+// rangesToPrefixes 将地址范围列表拆分为最少的 CIDR
+func rangesToPrefixes(ranges []addrRange) []netip.Prefix {
+	var prefixes []netip.Prefix
+	for _, r := range ranges {
+		prefixes = append(prefixes, rangeToPrefixes(r)...)
+	}
+	return prefixes
+}
+
+// describeCoverage 按地址族统计地址数量，如 "768 IPv4 addresses, 2^64 IPv6 addresses"
+func describeCoverage(ranges []addrRange) string {
+	v4, v6 := new(big.Int), new(big.Int)
+	for _, r := range ranges {
+		if r.First.Is4() {
+			v4.Add(v4, r.size())
+		} else {
+			v6.Add(v6, r.size())
+		}
+	}
+
+	var parts []string
+	if v4.Sign() > 0 {
+		parts = append(parts, formatCount(v4)+" IPv4 addresses")
+	}
+	if v6.Sign() > 0 {
+		parts = append(parts, formatCount(v6)+" IPv6 addresses")
+	}
+	if len(parts) == 0 {
+		return "none"
+	}
+	return strings.Join(parts, ", ")
+}
+
+func diffPrefixes(cmd *cobra.Command, args []string) {
+	exitCode, _ := cmd.Flags().GetBool("exit-code")
+
+	if len(args) != 2 {
+		logger.PrintValidationError("expected two prefix list files")
+		if err := cmd.Help(); err != nil {
+			logger.PrintErrorWithMessage("failed to show help", err)
+		}
+		os.Exit(diffExitError)
+	}
+	if args[0] == "-" && args[1] == "-" {
+		logger.PrintValidationError("only one of OLD and NEW can be read from stdin")
+		os.Exit(diffExitError)
+	}
+
+	oldPrefixes, err := readPrefixFile(args[0])
+	if err != nil {
+		logger.PrintErrorWithMessage("failed to load "+args[0], err)
+		os.Exit(diffExitError)
+	}
+	newPrefixes, err := readPrefixFile(args[1])
+	if err != nil {
+		logger.PrintErrorWithMessage("failed to load "+args[1], err)
+		os.Exit(diffExitError)
+	}
+	logger.Debugf("Comparing %d old and %d new prefixes", len(oldPrefixes), len(newPrefixes))
+
+	diff := diffCoverage(oldPrefixes, newPrefixes)
+	added, removed := rangesToPrefixes(diff.Added), rangesToPrefixes(diff.Removed)
+	for _, prefix := range added {
+		fmt.Println("+", prefix)
+	}
+	for _, prefix := range removed {
+		fmt.Println("-", prefix)
+	}
+
+	fmt.Printf("Added: %d CIDRs, %s\n", len(added), describeCoverage(diff.Added))
+	fmt.Printf("Removed: %d CIDRs, %s\n", len(removed), describeCoverage(diff.Removed))
+	fmt.Printf("Unchanged: %s\n", describeCoverage(diff.Unchanged))
+
+	if len(added)+len(removed) > 0 {
+		logger.Infof("Coverage differs: %d CIDRs added, %d removed", len(added), len(removed))
+		if exitCode {
+			os.Exit(diffExitDiffers)
+		}
+		return
+	}
+	logger.Infof("Successfully compared %s and %s, coverage is identical", args[0], args[1])
+}
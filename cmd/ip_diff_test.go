@@ -0,0 +1,94 @@
+//go:build unit
+
+/*
+Copyright © 2024-2025 Auska <luodan0709@live.cn>
+
+*/
+
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func prefixStrings(ranges []addrRange) []string {
+	var out []string
+	for _, prefix := range rangesToPrefixes(ranges) {
+		out = append(out, prefix.String())
+	}
+	return out
+}
+
+func TestDiffCoverage(t *testing.T) {
+	tests := []struct {
+		name      string
+		old       []string
+		new       []string
+		added     []string
+		removed   []string
+		unchanged []string
+	}{
+		{
+			name:      "Aggregation is not a change",
+			old:       []string{"10.0.0.0/24", "10.0.1.0/24"},
+			new:       []string{"10.0.0.0/23"},
+			unchanged: []string{"10.0.0.0/23"},
+		},
+		{
+			name:      "Added and removed",
+			old:       []string{"10.0.0.0/24", "192.168.0.0/24"},
+			new:       []string{"10.0.0.0/23", "192.168.0.128/25"},
+			added:     []string{"10.0.1.0/24"},
+			removed:   []string{"192.168.0.0/25"},
+			unchanged: []string{"10.0.0.0/24", "192.168.0.128/25"},
+		},
+		{
+			name:      "Hole punched in IPv6",
+			old:       []string{"2001:db8::/32"},
+			new:       []string{"2001:db8::/33", "2001:db8:c000::/34"},
+			removed:   []string{"2001:db8:8000::/34"},
+			unchanged: []string{"2001:db8::/33", "2001:db8:c000::/34"},
+		},
+		{
+			name:  "Empty old list",
+			new:   []string{"10.0.0.0/8"},
+			added: []string{"10.0.0.0/8"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff := diffCoverage(labeledPrefixes(tt.old...), labeledPrefixes(tt.new...))
+			if got := prefixStrings(diff.Added); !reflect.DeepEqual(got, tt.added) {
+				t.Errorf("Added = %v, want %v", got, tt.added)
+			}
+			if got := prefixStrings(diff.Removed); !reflect.DeepEqual(got, tt.removed) {
+				t.Errorf("Removed = %v, want %v", got, tt.removed)
+			}
+			if got := prefixStrings(diff.Unchanged); !reflect.DeepEqual(got, tt.unchanged) {
+				t.Errorf("Unchanged = %v, want %v", got, tt.unchanged)
+			}
+		})
+	}
+}
+
+func TestDescribeCoverage(t *testing.T) {
+	tests := []struct {
+		name     string
+		ranges   []string
+		expected string
+	}{
+		{"Empty", nil, "none"},
+		{"IPv4", []string{"10.0.0.0-10.0.0.9"}, "10 IPv4 addresses"},
+		{"Mixed", []string{"10.0.0.0-10.0.0.255", "2001:db8::-2001:db8::ffff"}, "256 (2^8) IPv4 addresses, 65536 (2^16) IPv6 addresses"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := describeCoverage(parseRanges(t, tt.ranges...)); result != tt.expected {
+				t.Errorf("describeCoverage() = %q, want %q", result, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,391 @@
+/*
+Copyright © 2024-2025 Auska <luodan0709@live.cn>
+
+*/
+
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"macconv/pkg/errors"
+	"macconv/pkg/logger"
+)
+
+var ipExportCmd = &cobra.Command{
+	Use:   "export [cidr|range...]",
+	Short: "Render a list of CIDRs as firewall, ACL or prefix-list syntax",
+	Long: `
+Convert CIDRs, bare addresses and address ranges (first-last) into
+ready-to-paste configuration. Ranges are split into the fewest CIDRs that
+cover them. Supported formats:
+
+	cisco-acl           standard ACL lines with wildcard masks (IPv6: ipv6 access-list)
+	cisco-prefix-list   ip/ipv6 prefix-list with optional ge/le
+	huawei-prefix-list  ip ip-prefix / ip ipv6-prefix with greater-equal/less-equal
+	juniper             set policy-options prefix-list
+	iptables            iptables/ip6tables rules
+	ipset               ipset restore input (hash:net)
+	nftables            nft set definition with interval elements
+	aws                 IpRanges/Ipv6Ranges JSON for security group rules
+
+For example:
+
+	macconv ip export 10.0.0.0/8 192.168.1.10-192.168.1.20 --format cisco-acl --name MGMT
+	macconv ip export -f office.txt --format cisco-prefix-list --name OFFICE --le 24
+	macconv ip export -f office.txt --format nftables --name office --table "inet filter"
+	macconv ip export -f office.txt --format aws`,
+	Run: exportPrefixes,
+}
+
+func init() {
+	ipCmd.AddCommand(ipExportCmd)
+	ipExportCmd.Flags().StringP("format", "F", "cisco-acl", "Output format: "+strings.Join(exportFormatNames(), ", "))
+	ipExportCmd.Flags().StringP("file", "f", "", "Read CIDRs or ranges from a file (one per line, optional label column), - for stdin")
+	ipExportCmd.Flags().StringP("name", "n", "ADDRESS-GROUP", "ACL, prefix-list, set or chain name")
+	ipExportCmd.Flags().String("action", "permit", "permit or deny")
+	ipExportCmd.Flags().Int("ge", 0, "Prefix-list minimum length (ge / greater-equal)")
+	ipExportCmd.Flags().Int("le", 0, "Prefix-list maximum length (le / less-equal)")
+	ipExportCmd.Flags().Int("seq", 5, "Prefix-list sequence/index step")
+	ipExportCmd.Flags().String("chain", "INPUT", "iptables chain")
+	ipExportCmd.Flags().String("table", "inet filter", "nftables family and table")
+}
+
+// exportOptions 导出格式的公共参数
+type exportOptions struct {
+	Name   string
+	Permit bool
+	GE     int
+	LE     int
+	Seq    int
+	Chain  string
+	Table  string
+}
+
+// exportFormatter 将网段列表写为某种配置语法
+type exportFormatter func(w io.Writer, prefixes []labeledPrefix, opts exportOptions) error
+
+var exportFormats = map[string]exportFormatter{
+	"cisco-acl":          exportCiscoACL,
+	"cisco-prefix-list":  exportCiscoPrefixList,
+	"huawei-prefix-list": exportHuaweiPrefixList,
+	"juniper":            exportJuniper,
+	"iptables":           exportIptables,
+	"ipset":              exportIpset,
+	"nftables":           exportNftables,
+	"aws":                exportAWS,
+}
+
+func exportFormatNames() []string {
+	names := make([]string, 0, len(exportFormats))
+	for name := range exportFormats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// wildcardMask 返回 IPv4 网段的通配符掩码
+func wildcardMask(prefix netip.Prefix) string {
+	return calculateInverseMask(net.CIDRMask(prefix.Bits(), prefix.Addr().BitLen()))
+}
+
+// prefixLengthRange 校验 ge/le 并返回对该网段实际生效的值，0 表示省略
+// Cisco 要求 len < ge <= le <= 最大长度，ge 等于网段长度时省略
+func prefixLengthRange(prefix netip.Prefix, ge, le int) (int, int, error) {
+	maxBits := prefix.Addr().BitLen()
+	if ge == prefix.Bits() {
+		ge = 0
+	}
+	if ge != 0 && (ge < prefix.Bits() || ge > maxBits) {
+		return 0, 0, errors.New(errors.ValidationError, fmt.Sprintf("ge %d is not valid for %s", ge, prefix))
+	}
+	if le != 0 && (le < prefix.Bits() || le > maxBits || (ge != 0 && le < ge)) {
+		return 0, 0, errors.New(errors.ValidationError, fmt.Sprintf("le %d is not valid for %s", le, prefix))
+	}
+	return ge, le, nil
+}
+
+// splitFamilies 按地址族拆分网段列表
+func splitFamilies(prefixes []labeledPrefix) (v4, v6 []labeledPrefix) {
+	for _, p := range prefixes {
+		if p.Prefix.Addr().Is4() {
+			v4 = append(v4, p)
+		} else {
+			v6 = append(v6, p)
+		}
+	}
+	return v4, v6
+}
+
+// familySetName 同时存在两个地址族时为集合名称加上 _v4 / _v6 后缀
+func familySetName(name string, v4, v6 []labeledPrefix, ipv6 bool) string {
+	if len(v4) == 0 || len(v6) == 0 {
+		return name
+	}
+	if ipv6 {
+		return name + "_v6"
+	}
+	return name + "_v4"
+}
+
+func (o exportOptions) action() string {
+	if o.Permit {
+		return "permit"
+	}
+	return "deny"
+}
+
+func exportCiscoACL(w io.Writer, prefixes []labeledPrefix, opts exportOptions) error {
+	v4, v6 := splitFamilies(prefixes)
+
+	if len(v4) > 0 {
+		fmt.Fprintf(w, "ip access-list standard %s\n", opts.Name)
+		for _, p := range v4 {
+			if p.Label != "" {
+				fmt.Fprintf(w, " remark %s\n", p.Label)
+			}
+			if p.Prefix.IsSingleIP() {
+				fmt.Fprintf(w, " %s host %s\n", opts.action(), p.Prefix.Addr())
+			} else {
+				fmt.Fprintf(w, " %s %s %s\n", opts.action(), p.Prefix.Addr(), wildcardMask(p.Prefix))
+			}
+		}
+	}
+
+	if len(v6) > 0 {
+		fmt.Fprintf(w, "ipv6 access-list %s\n", opts.Name)
+		for _, p := range v6 {
+			if p.Label != "" {
+				fmt.Fprintf(w, " remark %s\n", p.Label)
+			}
+			if p.Prefix.IsSingleIP() {
+				fmt.Fprintf(w, " %s ipv6 host %s any\n", opts.action(), p.Prefix.Addr())
+			} else {
+				fmt.Fprintf(w, " %s ipv6 %s any\n", opts.action(), p.Prefix)
+			}
+		}
+	}
+	return nil
+}
+
+func exportCiscoPrefixList(w io.Writer, prefixes []labeledPrefix, opts exportOptions) error {
+	for i, p := range prefixes {
+		ge, le, err := prefixLengthRange(p.Prefix, opts.GE, opts.LE)
+		if err != nil {
+			return err
+		}
+
+		family := "ip"
+		if p.Prefix.Addr().Is6() {
+			family = "ipv6"
+		}
+		line := fmt.Sprintf("%s prefix-list %s seq %d %s %s", family, opts.Name, (i+1)*opts.Seq, opts.action(), p.Prefix)
+		if ge != 0 {
+			line += fmt.Sprintf(" ge %d", ge)
+		}
+		if le != 0 {
+			line += fmt.Sprintf(" le %d", le)
+		}
+		fmt.Fprintln(w, line)
+	}
+	return nil
+}
+
+func exportHuaweiPrefixList(w io.Writer, prefixes []labeledPrefix, opts exportOptions) error {
+	for i, p := range prefixes {
+		ge, le, err := prefixLengthRange(p.Prefix, opts.GE, opts.LE)
+		if err != nil {
+			return err
+		}
+
+		family := "ip-prefix"
+		if p.Prefix.Addr().Is6() {
+			family = "ipv6-prefix"
+		}
+		line := fmt.Sprintf("ip %s %s index %d %s %s %d", family, opts.Name, (i+1)*opts.Seq, opts.action(), p.Prefix.Addr(), p.Prefix.Bits())
+		if ge != 0 {
+			line += fmt.Sprintf(" greater-equal %d", ge)
+		}
+		if le != 0 {
+			line += fmt.Sprintf(" less-equal %d", le)
+		}
+		fmt.Fprintln(w, line)
+	}
+	return nil
+}
+
+func exportJuniper(w io.Writer, prefixes []labeledPrefix, opts exportOptions) error {
+	for _, p := range prefixes {
+		fmt.Fprintf(w, "set policy-options prefix-list %s %s\n", opts.Name, p.Prefix)
+	}
+	return nil
+}
+
+func exportIptables(w io.Writer, prefixes []labeledPrefix, opts exportOptions) error {
+	target := "ACCEPT"
+	if !opts.Permit {
+		target = "DROP"
+	}
+
+	for _, p := range prefixes {
+		command := "iptables"
+		if p.Prefix.Addr().Is6() {
+			command = "ip6tables"
+		}
+		line := fmt.Sprintf("%s -A %s -s %s", command, opts.Chain, p.Prefix)
+		if p.Label != "" {
+			line += fmt.Sprintf(" -m comment --comment %q", p.Label)
+		}
+		fmt.Fprintf(w, "%s -j %s\n", line, target)
+	}
+	return nil
+}
+
+func exportIpset(w io.Writer, prefixes []labeledPrefix, opts exportOptions) error {
+	v4, v6 := splitFamilies(prefixes)
+	for _, group := range []struct {
+		prefixes []labeledPrefix
+		family   string
+		ipv6     bool
+	}{
+		{v4, "inet", false},
+		{v6, "inet6", true},
+	} {
+		if len(group.prefixes) == 0 {
+			continue
+		}
+		name := familySetName(opts.Name, v4, v6, group.ipv6)
+		fmt.Fprintf(w, "create %s hash:net family %s -exist\n", name, group.family)
+		for _, p := range group.prefixes {
+			fmt.Fprintf(w, "add %s %s -exist\n", name, p.Prefix)
+		}
+	}
+	return nil
+}
+
+func exportNftables(w io.Writer, prefixes []labeledPrefix, opts exportOptions) error {
+	v4, v6 := splitFamilies(prefixes)
+	for _, group := range []struct {
+		prefixes []labeledPrefix
+		addrType string
+		ipv6     bool
+	}{
+		{v4, "ipv4_addr", false},
+		{v6, "ipv6_addr", true},
+	} {
+		if len(group.prefixes) == 0 {
+			continue
+		}
+		name := familySetName(opts.Name, v4, v6, group.ipv6)
+		elements := make([]string, len(group.prefixes))
+		for i, p := range group.prefixes {
+			elements[i] = p.Prefix.String()
+		}
+		fmt.Fprintf(w, "add set %s %s { type %s; flags interval; auto-merge; }\n", opts.Table, name, group.addrType)
+		fmt.Fprintf(w, "add element %s %s { %s }\n", opts.Table, name, strings.Join(elements, ", "))
+	}
+	return nil
+}
+
+// awsIPRange 安全组规则中的 IPv4 地址范围
+type awsIPRange struct {
+	CidrIP      string `json:"CidrIp"`
+	Description string `json:"Description,omitempty"`
+}
+
+// awsIPv6Range 安全组规则中的 IPv6 地址范围
+type awsIPv6Range struct {
+	CidrIPv6    string `json:"CidrIpv6"`
+	Description string `json:"Description,omitempty"`
+}
+
+func exportAWS(w io.Writer, prefixes []labeledPrefix, _ exportOptions) error {
+	out := struct {
+		IPRanges   []awsIPRange   `json:"IpRanges"`
+		IPv6Ranges []awsIPv6Range `json:"Ipv6Ranges"`
+	}{
+		IPRanges:   []awsIPRange{},
+		IPv6Ranges: []awsIPv6Range{},
+	}
+
+	for _, p := range prefixes {
+		if p.Prefix.Addr().Is4() {
+			out.IPRanges = append(out.IPRanges, awsIPRange{CidrIP: p.Prefix.String(), Description: p.Label})
+		} else {
+			out.IPv6Ranges = append(out.IPv6Ranges, awsIPv6Range{CidrIPv6: p.Prefix.String(), Description: p.Label})
+		}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(out); err != nil {
+		return errors.Wrap(errors.ParseError, "failed to encode JSON", err)
+	}
+	return nil
+}
+
+func exportPrefixes(cmd *cobra.Command, args []string) {
+	format, _ := cmd.Flags().GetString("format")
+	file, _ := cmd.Flags().GetString("file")
+	action, _ := cmd.Flags().GetString("action")
+
+	opts := exportOptions{}
+	opts.Name, _ = cmd.Flags().GetString("name")
+	opts.GE, _ = cmd.Flags().GetInt("ge")
+	opts.LE, _ = cmd.Flags().GetInt("le")
+	opts.Seq, _ = cmd.Flags().GetInt("seq")
+	opts.Chain, _ = cmd.Flags().GetString("chain")
+	opts.Table, _ = cmd.Flags().GetString("table")
+
+	formatter, ok := exportFormats[format]
+	if !ok {
+		logger.PrintValidationError(fmt.Sprintf("unknown format %q, expected one of: %s", format, strings.Join(exportFormatNames(), ", ")))
+		return
+	}
+	switch action {
+	case "permit":
+		opts.Permit = true
+	case "deny":
+	default:
+		logger.PrintValidationError(fmt.Sprintf("action must be permit or deny, got %q", action))
+		return
+	}
+	if opts.Seq < 1 {
+		logger.PrintValidationError("--seq must be positive")
+		return
+	}
+
+	prefixes, err := collectPrefixes(args, file)
+	if err != nil {
+		logger.PrintErrorWithMessage("failed to read prefixes", err)
+		return
+	}
+	if len(prefixes) == 0 {
+		logger.PrintValidationError("no CIDRs given")
+		if err := cmd.Help(); err != nil {
+			logger.PrintErrorWithMessage("failed to show help", err)
+		}
+		return
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	if err := formatter(w, prefixes, opts); err != nil {
+		logger.PrintErrorWithMessage("failed to export prefixes", err)
+		return
+	}
+	if err := w.Flush(); err != nil {
+		logger.Debugf("Failed to flush output: %v", err)
+	}
+
+	logger.Infof("Successfully exported %d prefixes as %s", len(prefixes), format)
+}
@@ -0,0 +1,182 @@
+//go:build unit
+
+/*
+Copyright © 2024-2025 Auska <luodan0709@live.cn>
+
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+func exportInput(t *testing.T, entries ...string) []labeledPrefix {
+	t.Helper()
+	var prefixes []labeledPrefix
+	for _, e := range entries {
+		cidr, label, _ := strings.Cut(e, " ")
+		prefixes = append(prefixes, labeledPrefix{Prefix: netip.MustParsePrefix(cidr), Label: label})
+	}
+	return prefixes
+}
+
+func TestWildcardMask(t *testing.T) {
+	tests := []struct {
+		prefix   string
+		expected string
+	}{
+		{"10.0.0.0/8", "0.255.255.255"},
+		{"192.168.1.0/24", "0.0.0.255"},
+		{"192.168.1.16/28", "0.0.0.15"},
+		{"192.168.1.1/32", "0.0.0.0"},
+	}
+
+	for _, tt := range tests {
+		if result := wildcardMask(netip.MustParsePrefix(tt.prefix)); result != tt.expected {
+			t.Errorf("wildcardMask(%s) = %s, want %s", tt.prefix, result, tt.expected)
+		}
+	}
+}
+
+func TestPrefixLengthRange(t *testing.T) {
+	prefix := netip.MustParsePrefix("10.0.0.0/16")
+	tests := []struct {
+		ge, le         int
+		wantGE, wantLE int
+		wantErr        bool
+	}{
+		{0, 0, 0, 0, false},
+		{16, 24, 0, 24, false},
+		{20, 24, 20, 24, false},
+		{0, 32, 0, 32, false},
+		{8, 0, 0, 0, true},
+		{0, 8, 0, 0, true},
+		{24, 20, 0, 0, true},
+		{0, 33, 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		ge, le, err := prefixLengthRange(prefix, tt.ge, tt.le)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("prefixLengthRange(%d, %d) error = %v, wantErr %v", tt.ge, tt.le, err, tt.wantErr)
+		}
+		if !tt.wantErr && (ge != tt.wantGE || le != tt.wantLE) {
+			t.Errorf("prefixLengthRange(%d, %d) = %d, %d, want %d, %d", tt.ge, tt.le, ge, le, tt.wantGE, tt.wantLE)
+		}
+	}
+}
+
+func TestExportFormats(t *testing.T) {
+	prefixes := exportInput(t, "10.0.0.0/8 core", "192.168.1.1/32", "2001:db8::/32")
+	opts := exportOptions{Name: "MGMT", Permit: true, Seq: 5, Chain: "INPUT", Table: "inet filter"}
+
+	tests := []struct {
+		format   string
+		opts     exportOptions
+		expected []string
+	}{
+		{"cisco-acl", opts, []string{
+			"ip access-list standard MGMT",
+			" remark core",
+			" permit 10.0.0.0 0.255.255.255",
+			" permit host 192.168.1.1",
+			"ipv6 access-list MGMT",
+			" permit ipv6 2001:db8::/32 any",
+		}},
+		{"cisco-prefix-list", opts, []string{
+			"ip prefix-list MGMT seq 5 permit 10.0.0.0/8",
+			"ip prefix-list MGMT seq 10 permit 192.168.1.1/32",
+			"ipv6 prefix-list MGMT seq 15 permit 2001:db8::/32",
+		}},
+		{"huawei-prefix-list", opts, []string{
+			"ip ip-prefix MGMT index 5 permit 10.0.0.0 8",
+			"ip ipv6-prefix MGMT index 15 permit 2001:db8:: 32",
+		}},
+		{"juniper", opts, []string{
+			"set policy-options prefix-list MGMT 10.0.0.0/8",
+		}},
+		{"iptables", exportOptions{Name: "MGMT", Chain: "FORWARD"}, []string{
+			`iptables -A FORWARD -s 10.0.0.0/8 -m comment --comment "core" -j DROP`,
+			"ip6tables -A FORWARD -s 2001:db8::/32 -j DROP",
+		}},
+		{"ipset", opts, []string{
+			"create MGMT_v4 hash:net family inet -exist",
+			"add MGMT_v4 192.168.1.1/32 -exist",
+			"create MGMT_v6 hash:net family inet6 -exist",
+		}},
+		{"nftables", opts, []string{
+			"add set inet filter MGMT_v4 { type ipv4_addr; flags interval; auto-merge; }",
+			"add element inet filter MGMT_v4 { 10.0.0.0/8, 192.168.1.1/32 }",
+			"add element inet filter MGMT_v6 { 2001:db8::/32 }",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := exportFormats[tt.format](&buf, prefixes, tt.opts); err != nil {
+				t.Fatalf("export error = %v", err)
+			}
+			for _, want := range tt.expected {
+				if !strings.Contains(buf.String(), want+"\n") {
+					t.Errorf("output missing %q:\n%s", want, buf.String())
+				}
+			}
+		})
+	}
+}
+
+func TestExportPrefixListLengthRange(t *testing.T) {
+	prefixes := exportInput(t, "10.0.0.0/8")
+	opts := exportOptions{Name: "P", Permit: true, Seq: 10, GE: 16, LE: 24}
+
+	var cisco, huawei bytes.Buffer
+	if err := exportCiscoPrefixList(&cisco, prefixes, opts); err != nil {
+		t.Fatal(err)
+	}
+	if err := exportHuaweiPrefixList(&huawei, prefixes, opts); err != nil {
+		t.Fatal(err)
+	}
+	if got := cisco.String(); got != "ip prefix-list P seq 10 permit 10.0.0.0/8 ge 16 le 24\n" {
+		t.Errorf("cisco prefix-list = %q", got)
+	}
+	if got := huawei.String(); got != "ip ip-prefix P index 10 permit 10.0.0.0 8 greater-equal 16 less-equal 24\n" {
+		t.Errorf("huawei prefix-list = %q", got)
+	}
+
+	opts.LE = 4
+	if err := exportCiscoPrefixList(&cisco, prefixes, opts); err == nil {
+		t.Error("expected error for le shorter than the prefix")
+	}
+}
+
+func TestExportAWS(t *testing.T) {
+	var buf bytes.Buffer
+	if err := exportAWS(&buf, exportInput(t, "10.0.0.0/8 office", "2001:db8::/32"), exportOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	var out struct {
+		IPRanges []struct {
+			CidrIP      string `json:"CidrIp"`
+			Description string `json:"Description"`
+		} `json:"IpRanges"`
+		IPv6Ranges []struct {
+			CidrIPv6 string `json:"CidrIpv6"`
+		} `json:"Ipv6Ranges"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if len(out.IPRanges) != 1 || out.IPRanges[0].CidrIP != "10.0.0.0/8" || out.IPRanges[0].Description != "office" {
+		t.Errorf("IpRanges = %+v", out.IPRanges)
+	}
+	if len(out.IPv6Ranges) != 1 || out.IPv6Ranges[0].CidrIPv6 != "2001:db8::/32" {
+		t.Errorf("Ipv6Ranges = %+v", out.IPv6Ranges)
+	}
+}
@@ -0,0 +1,600 @@
+/*
+Copyright © 2024-2025 Auska <luodan0709@live.cn>
+
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"net/netip"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"macconv/pkg/errors"
+	"macconv/pkg/logger"
+)
+
+// 防火墙规则集文本格式
+const (
+	firewallFormatAuto     = "auto"
+	firewallFormatIptables = "iptables"
+	firewallFormatNft      = "nft"
+)
+
+// maxChainDepth 跳转链的最大嵌套深度，超过时视为循环跳转
+const maxChainDepth = 64
+
+// maxTraceRules 一次追踪最多评估的规则数；goto 不增加嵌套深度，goto 循环只能靠这个上限发现
+const maxTraceRules = 1 << 16
+
+var ipFirewallCmd = &cobra.Command{
+	Use:   "firewall",
+	Short: "Trace a packet through an iptables-save or nft ruleset",
+	Long: `
+Trace a packet offline through the output of iptables-save (or iptables -S)
+or nft list ruleset, following jumps, gotos and returns, and report the
+verdict and the rule that decided it.
+
+The hook defaults to input, forward when both --in and --out are given and
+output when only --out is given. For iptables the chains of --table (filter
+by default) are traced; for nftables every filter base chain on the hook is
+traced in priority order, and the packet is dropped if any of them drops it.
+
+Packet fields that are not given, and matches that are not modelled (limit,
+recent, ipset, marks, ICMP types, ...), are reported as possible matches
+instead of being guessed. When "macconv tcp" reports a port as closed, this
+tells whether the host firewall drops the connection. For example:
+
+	iptables-save | macconv ip firewall -f - --in eth0 --src 203.0.113.5 --dst 192.0.2.10 --proto tcp --dport 443
+	macconv ip firewall -f ruleset.nft --in eth0 --src 2001:db8::5 --dst 2001:db8::1 --proto tcp --dport 22
+	macconv ip firewall -f rules.v4 --in eth0 --out docker0 --src 203.0.113.5 --dst 172.17.0.2 --proto tcp --dport 80`,
+	Run: traceFirewall,
+}
+
+func init() {
+	ipCmd.AddCommand(ipFirewallCmd)
+	ipFirewallCmd.Flags().StringP("file", "f", "", "iptables-save or nft list ruleset output, - for stdin")
+	ipFirewallCmd.Flags().String("format", firewallFormatAuto, "Input format: auto, iptables or nft")
+	ipFirewallCmd.Flags().String("hook", "", "Hook to trace: prerouting, input, forward, output or postrouting")
+	ipFirewallCmd.Flags().String("table", "", "iptables table (default filter) or nftables table name to trace")
+	ipFirewallCmd.Flags().String("in", "", "Input interface of the packet")
+	ipFirewallCmd.Flags().String("out", "", "Output interface of the packet")
+	ipFirewallCmd.Flags().String("src", "", "Source address of the packet")
+	ipFirewallCmd.Flags().String("dst", "", "Destination address of the packet")
+	ipFirewallCmd.Flags().String("proto", "", "Protocol name or number (tcp, udp, icmp, 47, ...)")
+	ipFirewallCmd.Flags().String("sport", "", "Source port number or name")
+	ipFirewallCmd.Flags().String("dport", "", "Destination port number or name")
+	ipFirewallCmd.Flags().String("state", "new", "Conntrack state: new, established, related, invalid or untracked")
+}
+
+// fwPacket 待追踪的报文，未给出的接口和状态为空字符串
+type fwPacket struct {
+	aclPacket
+	InIface, OutIface string
+	State             string
+}
+
+// String 返回报文的单行描述
+func (p fwPacket) String() string {
+	s := p.aclPacket.String()
+	if p.InIface != "" {
+		s += " in " + p.InIface
+	}
+	if p.OutIface != "" {
+		s += " out " + p.OutIface
+	}
+	if p.State != "" {
+		s += " state " + p.State
+	}
+	return s
+}
+
+// family 返回报文的地址族，4 或 6，地址都未给出时为 0
+func (p fwPacket) family() int {
+	for _, addr := range []netip.Addr{p.Src, p.Dst} {
+		if addr.Is4() {
+			return 4
+		}
+		if addr.Is6() {
+			return 6
+		}
+	}
+	return 0
+}
+
+// fwCondition 规则中的一个匹配条件，Match 返回是否匹配以及所需字段是否已知
+type fwCondition struct {
+	Field string
+	Match func(p fwPacket) (ok, known bool)
+}
+
+// unmodelledCondition 未建模的匹配条件，结果总是不确定
+func unmodelledCondition(text string) fwCondition {
+	return fwCondition{Field: strconv.Quote(text), Match: func(fwPacket) (bool, bool) { return false, false }}
+}
+
+// addrCondition 地址属于任一范围，negate 时取反
+func addrCondition(field string, dst bool, ranges []addrRange, negate bool) fwCondition {
+	return fwCondition{Field: field, Match: func(p fwPacket) (bool, bool) {
+		addr := p.Src
+		if dst {
+			addr = p.Dst
+		}
+		if !addr.IsValid() {
+			return false, false
+		}
+		for _, r := range ranges {
+			if r.First.BitLen() == addr.BitLen() && !addr.Less(r.First) && !r.Last.Less(addr) {
+				return !negate, true
+			}
+		}
+		return negate, true
+	}}
+}
+
+// ifaceCondition 接口名匹配任一名称，名称以 + 或 * 结尾时按前缀匹配
+func ifaceCondition(field string, out bool, names []string, negate bool) fwCondition {
+	return fwCondition{Field: field, Match: func(p fwPacket) (bool, bool) {
+		iface := p.InIface
+		if out {
+			iface = p.OutIface
+		}
+		if iface == "" {
+			return false, false
+		}
+		for _, name := range names {
+			if prefix, ok := strings.CutSuffix(name, "+"); ok && strings.HasPrefix(iface, prefix) {
+				return !negate, true
+			}
+			if prefix, ok := strings.CutSuffix(name, "*"); ok && strings.HasPrefix(iface, prefix) {
+				return !negate, true
+			}
+			if iface == name {
+				return !negate, true
+			}
+		}
+		return negate, true
+	}}
+}
+
+// protocolCondition 协议属于任一编号
+func protocolCondition(protocols []int, negate bool) fwCondition {
+	return fwCondition{Field: "protocol", Match: func(p fwPacket) (bool, bool) {
+		if p.Protocol < 0 {
+			return false, false
+		}
+		for _, proto := range protocols {
+			if proto == p.Protocol {
+				return !negate, true
+			}
+		}
+		return negate, true
+	}}
+}
+
+// parseFirewallProtocol 解析协议名称或编号，额外支持 ip6tables 和 nft 使用的 ipv6-icmp
+func parseFirewallProtocol(s string) (int, error) {
+	if strings.EqualFold(s, "ipv6-icmp") {
+		return aclProtocols["icmpv6"], nil
+	}
+	return parseACLProtocol(s, false)
+}
+
+// portCondition 端口落在任一区间内
+func portCondition(field string, dst bool, ranges []portRange, negate bool) fwCondition {
+	return fwCondition{Field: field, Match: func(p fwPacket) (bool, bool) {
+		port := p.SrcPort
+		if dst {
+			port = p.DstPort
+		}
+		if port < 0 {
+			return false, false
+		}
+		return portsMatch(ranges, port) != negate, true
+	}}
+}
+
+// stateCondition 连接跟踪状态属于任一状态
+func stateCondition(states []string, negate bool) fwCondition {
+	return fwCondition{Field: "conntrack state", Match: func(p fwPacket) (bool, bool) {
+		if p.State == "" {
+			return false, false
+		}
+		for _, state := range states {
+			if strings.EqualFold(state, p.State) {
+				return !negate, true
+			}
+		}
+		return negate, true
+	}}
+}
+
+// familyCondition 报文属于指定地址族
+func familyCondition(family int) fwCondition {
+	return fwCondition{Field: "address family", Match: func(p fwPacket) (bool, bool) {
+		if p.family() == 0 {
+			return false, false
+		}
+		return p.family() == family, true
+	}}
+}
+
+// fwRule 链中的一条规则，Target 为空表示只计数不改变处理流程
+type fwRule struct {
+	Line       int
+	Text       string
+	Conditions []fwCondition
+	Target     string // ACCEPT、DROP、RETURN、链名或 LOG 等非终止目标
+	Goto       bool
+}
+
+// evaluate 判断规则是否匹配报文，结果不确定时同时返回所依赖的条件
+func (r *fwRule) evaluate(p fwPacket) (aclVerdict, []string) {
+	var unknown []string
+	for _, cond := range r.Conditions {
+		ok, known := cond.Match(p)
+		if !known {
+			unknown = append(unknown, cond.Field)
+			continue
+		}
+		if !ok {
+			return aclNoMatch, nil
+		}
+	}
+	if len(unknown) > 0 {
+		return aclMaybe, unknown
+	}
+	return aclMatch, nil
+}
+
+// fwChain 一条链，Hook 非空时为基础链
+type fwChain struct {
+	Name     string
+	Table    string
+	Family   string // nftables 表的地址族，iptables 为空
+	Hook     string
+	Type     string
+	Priority int
+	Policy   string
+	Rules    []*fwRule
+}
+
+// fwRuleset 解析后的规则集
+type fwRuleset struct {
+	Format string
+	Chains []*fwChain
+	byName map[string]*fwChain
+}
+
+func (rs *fwRuleset) chain(table, name string) *fwChain {
+	return rs.byName[table+" "+name]
+}
+
+// addChain 返回指定表中的链，不存在时创建
+func (rs *fwRuleset) addChain(table, family, name string) *fwChain {
+	if c := rs.chain(table, name); c != nil {
+		return c
+	}
+	if rs.byName == nil {
+		rs.byName = make(map[string]*fwChain)
+	}
+	c := &fwChain{Name: name, Table: table, Family: family}
+	rs.byName[table+" "+name] = c
+	rs.Chains = append(rs.Chains, c)
+	return c
+}
+
+// baseChains 返回挂在 hook 上、需要追踪的基础链，按优先级排序
+func (rs *fwRuleset) baseChains(hook, table string, family int) []*fwChain {
+	var chains []*fwChain
+	for _, c := range rs.Chains {
+		if c.Hook != hook {
+			continue
+		}
+		if rs.Format == firewallFormatIptables {
+			if c.Table == table {
+				chains = append(chains, c)
+			}
+			continue
+		}
+		if c.Type != "filter" || (table != "" && !strings.HasSuffix(c.Table, " "+table)) {
+			continue
+		}
+		switch {
+		case c.Family == "inet", family == 0 && (c.Family == "ip" || c.Family == "ip6"),
+			family == 4 && c.Family == "ip", family == 6 && c.Family == "ip6":
+			chains = append(chains, c)
+		}
+	}
+	sort.SliceStable(chains, func(i, j int) bool { return chains[i].Priority < chains[j].Priority })
+	return chains
+}
+
+// fwTerminalTargets 结束当前基础链的目标，NAT 目标在 nat 表中同样结束遍历
+var fwTerminalTargets = map[string]bool{
+	"ACCEPT": true, "DROP": true, "REJECT": true, "QUEUE": true, "NFQUEUE": true,
+	"DNAT": true, "SNAT": true, "MASQUERADE": true, "REDIRECT": true, "NETMAP": true,
+}
+
+// fwStep 追踪过程中的一步
+type fwStep struct {
+	Chain   *fwChain
+	Rule    *fwRule // 为 nil 时表示链的默认策略或返回
+	Verdict aclVerdict
+	Depends []string
+	Note    string
+}
+
+// traceChain 从基础链开始追踪报文，返回最终目标和经过的步骤
+// 不确定的规则只被记录，追踪按不匹配继续
+func (rs *fwRuleset) traceChain(base *fwChain, p fwPacket) (string, []fwStep, error) {
+	type frame struct {
+		chain *fwChain
+		next  int
+	}
+	stack := []frame{{chain: base}}
+	var steps []fwStep
+	evaluated := 0
+
+	for len(stack) > 0 {
+		top := &stack[len(stack)-1]
+		if top.next >= len(top.chain.Rules) {
+			if len(stack) == 1 {
+				policy := top.chain.Policy
+				if policy == "" {
+					policy = "ACCEPT"
+				}
+				steps = append(steps, fwStep{Chain: top.chain, Verdict: aclMatch, Note: "policy " + policy})
+				return policy, steps, nil
+			}
+			stack = stack[:len(stack)-1]
+			steps = append(steps, fwStep{Chain: top.chain, Verdict: aclMatch, Note: "end of chain, return"})
+			continue
+		}
+
+		if evaluated++; evaluated > maxTraceRules {
+			return "", steps, errors.New(errors.ValidationError,
+				fmt.Sprintf("trace of chain %s evaluated more than %d rules, possible goto loop", base.Name, maxTraceRules))
+		}
+		rule := top.chain.Rules[top.next]
+		top.next++
+		verdict, depends := rule.evaluate(p)
+		if verdict == aclNoMatch {
+			continue
+		}
+		step := fwStep{Chain: top.chain, Rule: rule, Verdict: verdict, Depends: depends}
+		if verdict == aclMaybe {
+			steps = append(steps, step)
+			continue
+		}
+
+		switch target := rule.Target; {
+		case fwTerminalTargets[target]:
+			steps = append(steps, step)
+			return target, steps, nil
+		case target == "RETURN":
+			step.Note = "return"
+			steps = append(steps, step)
+			if len(stack) == 1 {
+				stack[0].next = len(base.Rules)
+			} else {
+				stack = stack[:len(stack)-1]
+			}
+		case rs.chain(top.chain.Table, target) != nil:
+			if len(stack) >= maxChainDepth {
+				return "", steps, errors.New(errors.ValidationError, fmt.Sprintf("chain %s jumps too deep, possible loop", target))
+			}
+			next := rs.chain(top.chain.Table, target)
+			step.Note = "jump " + target
+			if rule.Goto {
+				// goto 的链结束后直接返回到上一层调用者，从基础链 goto 时应用默认策略
+				step.Note = "goto " + target
+				if len(stack) > 1 {
+					stack = stack[:len(stack)-1]
+				} else {
+					stack[0].next = len(base.Rules)
+				}
+			}
+			steps = append(steps, step)
+			stack = append(stack, frame{chain: next})
+		case target != "":
+			step.Note = target + ", continue"
+			steps = append(steps, step)
+		}
+	}
+	return "", steps, nil
+}
+
+// fwChainResult 一条基础链的追踪结果
+type fwChainResult struct {
+	Chain  *fwChain
+	Target string
+	Steps  []fwStep
+}
+
+// traceHook 依次追踪 hook 上的基础链，遇到 DROP、REJECT 或 QUEUE 时停止
+func (rs *fwRuleset) traceHook(chains []*fwChain, p fwPacket) ([]fwChainResult, error) {
+	var results []fwChainResult
+	for _, c := range chains {
+		target, steps, err := rs.traceChain(c, p)
+		if err != nil {
+			return results, err
+		}
+		results = append(results, fwChainResult{Chain: c, Target: target, Steps: steps})
+		if target == "DROP" || target == "REJECT" || target == "QUEUE" || target == "NFQUEUE" {
+			break
+		}
+	}
+	return results, nil
+}
+
+// detectFirewallFormat 根据特征文本判断规则集格式
+func detectFirewallFormat(text string) string {
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "table ") && strings.HasSuffix(line, "{"):
+			return firewallFormatNft
+		case strings.HasPrefix(line, "*"), strings.HasPrefix(line, "-A "), strings.HasPrefix(line, "-P "):
+			return firewallFormatIptables
+		}
+	}
+	return firewallFormatIptables
+}
+
+// parseFirewallRuleset 按格式解析规则集
+func parseFirewallRuleset(text, format string) (*fwRuleset, error) {
+	if format == firewallFormatAuto {
+		format = detectFirewallFormat(text)
+		logger.Debugf("Detected ruleset format: %s", format)
+	}
+	switch format {
+	case firewallFormatIptables:
+		return parseIptablesSave(text)
+	case firewallFormatNft:
+		return parseNftRuleset(text)
+	default:
+		return nil, errors.New(errors.ValidationError, fmt.Sprintf("unknown ruleset format %q", format))
+	}
+}
+
+// defaultHook 根据接口推断 hook：同时有入、出接口为 forward，只有出接口为 output，否则为 input
+func defaultHook(in, out string) string {
+	switch {
+	case in != "" && out != "":
+		return "forward"
+	case out != "":
+		return "output"
+	default:
+		return "input"
+	}
+}
+
+var firewallHooks = map[string]bool{"prerouting": true, "input": true, "forward": true, "output": true, "postrouting": true}
+
+var conntrackStates = map[string]bool{"new": true, "established": true, "related": true, "invalid": true, "untracked": true}
+
+func printFirewallStep(step fwStep) {
+	prefix := fmt.Sprintf("  [%s]", step.Chain.Name)
+	switch {
+	case step.Rule == nil:
+		fmt.Printf("%s %s\n", prefix, step.Note)
+	case step.Verdict == aclMaybe:
+		fmt.Printf("%s possible match, line %d (depends on %s): %s\n", prefix, step.Rule.Line, strings.Join(step.Depends, ", "), step.Rule.Text)
+	case step.Note != "":
+		fmt.Printf("%s line %d: %s (%s)\n", prefix, step.Rule.Line, step.Rule.Text, step.Note)
+	default:
+		fmt.Printf("%s line %d: %s\n", prefix, step.Rule.Line, step.Rule.Text)
+	}
+}
+
+func traceFirewall(cmd *cobra.Command, args []string) {
+	file, _ := cmd.Flags().GetString("file")
+	format, _ := cmd.Flags().GetString("format")
+	hook, _ := cmd.Flags().GetString("hook")
+	table, _ := cmd.Flags().GetString("table")
+	in, _ := cmd.Flags().GetString("in")
+	out, _ := cmd.Flags().GetString("out")
+	src, _ := cmd.Flags().GetString("src")
+	dst, _ := cmd.Flags().GetString("dst")
+	proto, _ := cmd.Flags().GetString("proto")
+	sport, _ := cmd.Flags().GetString("sport")
+	dport, _ := cmd.Flags().GetString("dport")
+	state, _ := cmd.Flags().GetString("state")
+
+	if file == "" {
+		logger.PrintValidationError("--file is required")
+		if err := cmd.Help(); err != nil {
+			logger.PrintErrorWithMessage("failed to show help", err)
+		}
+		return
+	}
+
+	if hook == "" {
+		hook = defaultHook(in, out)
+	}
+	hook = strings.ToLower(hook)
+	state = strings.ToLower(state)
+	if !firewallHooks[hook] {
+		logger.PrintValidationError(fmt.Sprintf("unknown hook %q", hook))
+		return
+	}
+	if state != "" && !conntrackStates[state] {
+		logger.PrintValidationError(fmt.Sprintf("unknown conntrack state %q", state))
+		return
+	}
+
+	tuple, err := parseACLPacket(src, dst, proto, sport, dport, false)
+	if err != nil {
+		logger.PrintErrorWithMessage("invalid packet", err)
+		return
+	}
+	packet := fwPacket{aclPacket: tuple, InIface: in, OutIface: out, State: strings.ToUpper(state)}
+
+	text, err := readTextInput(file)
+	if err != nil {
+		logger.PrintErrorWithMessage("failed to read ruleset", err)
+		return
+	}
+	rs, err := parseFirewallRuleset(text, format)
+	if err != nil {
+		logger.PrintErrorWithMessage("failed to parse ruleset", err)
+		return
+	}
+
+	if rs.Format == firewallFormatIptables && table == "" {
+		table = "filter"
+	}
+	chains := rs.baseChains(hook, table, packet.family())
+	if len(chains) == 0 {
+		fmt.Println("Packet:", packet)
+		fmt.Println("Hook:", hook)
+		fmt.Println("Verdict: ACCEPT (no chains on this hook)")
+		logger.Warnf("no base chains found for hook %s; check --table and --hook", hook)
+		return
+	}
+
+	results, err := rs.traceHook(chains, packet)
+	if err != nil {
+		logger.PrintErrorWithMessage("failed to trace packet", err)
+		return
+	}
+
+	fmt.Println("Packet:", packet)
+	fmt.Println("Hook:", hook)
+	possible := false
+	var decided fwChainResult
+	for _, result := range results {
+		fmt.Printf("\nChain: %s (table %s)\n", result.Chain.Name, result.Chain.Table)
+		for _, step := range result.Steps {
+			printFirewallStep(step)
+			if step.Verdict == aclMaybe {
+				possible = true
+			}
+		}
+		fmt.Println("  Result:", result.Target)
+		decided = result
+	}
+
+	fmt.Println()
+	verdict := "ACCEPT"
+	switch decided.Target {
+	case "DROP", "REJECT", "QUEUE", "NFQUEUE":
+		verdict = decided.Target
+	}
+	if possible {
+		fmt.Printf("Verdict: %s (unless a possible match above applies)\n", verdict)
+	} else {
+		fmt.Println("Verdict:", verdict)
+	}
+	if last := decided.Steps[len(decided.Steps)-1]; last.Rule != nil {
+		fmt.Printf("Decided By: %s line %d: %s\n", last.Chain.Name, last.Rule.Line, last.Rule.Text)
+	} else {
+		fmt.Printf("Decided By: %s %s\n", last.Chain.Name, last.Note)
+	}
+
+	logger.Infof("Successfully traced packet through %d chains", len(results))
+}
@@ -0,0 +1,288 @@
+/*
+Copyright © 2024-2025 Auska <luodan0709@live.cn>
+
+*/
+
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"macconv/pkg/errors"
+	"macconv/pkg/logger"
+)
+
+// iptablesBuiltinHooks 内置链与 hook 的对应关系
+var iptablesBuiltinHooks = map[string]string{
+	"PREROUTING": "prerouting", "INPUT": "input", "FORWARD": "forward", "OUTPUT": "output", "POSTROUTING": "postrouting",
+}
+
+// splitShellWords 按空白拆分，保留双引号内的空白，用于 iptables-save 中带引号的 --comment 等参数
+func splitShellWords(line string) []string {
+	var words []string
+	var sb strings.Builder
+	inQuote, inWord := false, false
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case c == '\\' && inQuote && i+1 < len(line):
+			i++
+			sb.WriteByte(line[i])
+		case c == '"':
+			inQuote, inWord = !inQuote, true
+		case (c == ' ' || c == '\t') && !inQuote:
+			if inWord {
+				words = append(words, sb.String())
+				sb.Reset()
+				inWord = false
+			}
+		default:
+			sb.WriteByte(c)
+			inWord = true
+		}
+	}
+	if inWord {
+		words = append(words, sb.String())
+	}
+	return words
+}
+
+// parseIptablesPorts 解析 22、1024:65535、:1023 或逗号分隔的多个端口
+func parseIptablesPorts(s string) ([]portRange, error) {
+	var ranges []portRange
+	for _, part := range strings.Split(s, ",") {
+		lo, hi, isRange := strings.Cut(part, ":")
+		r := portRange{0, 65535}
+		var err error
+		if lo != "" {
+			if r.Lo, err = parseACLPort(lo); err != nil {
+				return nil, err
+			}
+		}
+		switch {
+		case !isRange:
+			r.Hi = r.Lo
+		case hi != "":
+			if r.Hi, err = parseACLPort(hi); err != nil {
+				return nil, err
+			}
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges, nil
+}
+
+// parseAddrList 解析逗号分隔的地址、CIDR 或 起始-结束 范围
+func parseAddrList(s string) ([]addrRange, error) {
+	var ranges []addrRange
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if strings.Contains(part, "-") {
+			r, err := parseAddrRange(part)
+			if err != nil {
+				return nil, err
+			}
+			ranges = append(ranges, r)
+			continue
+		}
+		prefix, err := parsePrefix(part)
+		if err != nil {
+			return nil, err
+		}
+		ranges = append(ranges, prefixRange(prefix))
+	}
+	return ranges, nil
+}
+
+// parseIptablesRule 解析 -A 之后的规则参数
+// 未知模块及其参数、已知模块中的其他选项（如 --tcp-flags、--icmp-type）作为未建模条件，
+// -j 之后的目标参数被忽略
+func parseIptablesRule(rule *fwRule, args []string) error {
+	negate := false
+	var module []string
+	flushModule := func() {
+		if len(module) > 0 {
+			rule.Conditions = append(rule.Conditions, unmodelledCondition(strings.Join(module, " ")))
+			module = nil
+		}
+	}
+
+	for i := 0; i < len(args); i++ {
+		opt := args[i]
+		if opt == "!" {
+			negate = true
+			continue
+		}
+
+		known := true
+		var v string
+		switch opt {
+		case "-s", "--source", "-d", "--destination", "--src-range", "--dst-range", "-i", "--in-interface",
+			"-o", "--out-interface", "-p", "--protocol", "--sport", "--source-port", "--dport",
+			"--destination-port", "--sports", "--source-ports", "--dports", "--destination-ports", "--ports",
+			"--ctstate", "--state", "-m", "--match", "--comment", "-j", "--jump", "-g", "--goto":
+			if i+1 >= len(args) {
+				return errors.New(errors.ParseError, fmt.Sprintf("missing value for %s", opt))
+			}
+			i++
+			v = args[i]
+		default:
+			known = false
+		}
+
+		switch opt {
+		case "-s", "--source", "-d", "--destination", "--src-range", "--dst-range":
+			ranges, err := parseAddrList(v)
+			if err != nil {
+				return err
+			}
+			dst := opt == "-d" || opt == "--destination" || opt == "--dst-range"
+			field := "source address"
+			if dst {
+				field = "destination address"
+			}
+			rule.Conditions = append(rule.Conditions, addrCondition(field, dst, ranges, negate))
+		case "-i", "--in-interface":
+			rule.Conditions = append(rule.Conditions, ifaceCondition("input interface", false, []string{v}, negate))
+		case "-o", "--out-interface":
+			rule.Conditions = append(rule.Conditions, ifaceCondition("output interface", true, []string{v}, negate))
+		case "-p", "--protocol":
+			if v != "all" && v != "0" {
+				proto, err := parseFirewallProtocol(v)
+				if err != nil {
+					return err
+				}
+				rule.Conditions = append(rule.Conditions, protocolCondition([]int{proto}, negate))
+			}
+		case "--sport", "--source-port", "--dport", "--destination-port", "--sports", "--source-ports",
+			"--dports", "--destination-ports":
+			ranges, err := parseIptablesPorts(v)
+			if err != nil {
+				return err
+			}
+			dst := strings.HasPrefix(opt, "--d")
+			field := "source port"
+			if dst {
+				field = "destination port"
+			}
+			rule.Conditions = append(rule.Conditions, portCondition(field, dst, ranges, negate))
+		case "--ports":
+			ranges, err := parseIptablesPorts(v)
+			if err != nil {
+				return err
+			}
+			neg := negate
+			rule.Conditions = append(rule.Conditions, fwCondition{Field: "port", Match: func(p fwPacket) (bool, bool) {
+				if p.SrcPort < 0 || p.DstPort < 0 {
+					return false, false
+				}
+				return (portsMatch(ranges, p.SrcPort) || portsMatch(ranges, p.DstPort)) != neg, true
+			}})
+		case "--ctstate", "--state":
+			rule.Conditions = append(rule.Conditions, stateCondition(strings.Split(v, ","), negate))
+		case "-m", "--match":
+			flushModule()
+			switch v {
+			case "tcp", "udp", "sctp", "multiport", "conntrack", "state", "comment", "iprange":
+			default:
+				module = []string{"-m", v}
+			}
+		case "-j", "--jump", "-g", "--goto":
+			flushModule()
+			rule.Target = v
+			rule.Goto = opt == "-g" || opt == "--goto"
+			return nil
+		}
+
+		if !known {
+			if negate {
+				module = append(module, "!")
+			}
+			module = append(module, opt)
+			for i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") && args[i+1] != "!" {
+				i++
+				module = append(module, args[i])
+			}
+		}
+		negate = false
+	}
+	flushModule()
+	return nil
+}
+
+// parseIptablesSave 解析 iptables-save、ip6tables-save 或 iptables -S 的输出
+func parseIptablesSave(text string) (*fwRuleset, error) {
+	rs := &fwRuleset{Format: firewallFormatIptables}
+	table := "filter"
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	lineNo := 0
+
+	declare := func(name, policy string) {
+		c := rs.addChain(table, "", name)
+		c.Hook = iptablesBuiltinHooks[name]
+		c.Type = table
+		if policy != "-" {
+			c.Policy = policy
+		}
+	}
+
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || line == "COMMIT" {
+			continue
+		}
+		// iptables-save -c 在规则前输出 [包数:字节数]
+		if strings.HasPrefix(line, "[") {
+			if _, rest, ok := strings.Cut(line, "] "); ok {
+				line = rest
+			}
+		}
+
+		switch {
+		case strings.HasPrefix(line, "*"):
+			table = line[1:]
+		case strings.HasPrefix(line, ":"):
+			fields := strings.Fields(line[1:])
+			if len(fields) < 2 {
+				return nil, errors.New(errors.ParseError, fmt.Sprintf("line %d: invalid chain declaration", lineNo))
+			}
+			declare(fields[0], fields[1])
+		case strings.HasPrefix(line, "-P "), strings.HasPrefix(line, "-N "):
+			fields := strings.Fields(line)
+			if len(fields) < 2 {
+				return nil, errors.New(errors.ParseError, fmt.Sprintf("line %d: invalid chain declaration", lineNo))
+			}
+			policy := "-"
+			if fields[0] == "-P" && len(fields) > 2 {
+				policy = fields[2]
+			}
+			declare(fields[1], policy)
+		case strings.HasPrefix(line, "-A "):
+			args := splitShellWords(line)
+			if len(args) < 2 {
+				return nil, errors.New(errors.ParseError, fmt.Sprintf("line %d: missing chain name", lineNo))
+			}
+			c := rs.chain(table, args[1])
+			if c == nil {
+				declare(args[1], "-")
+				c = rs.chain(table, args[1])
+			}
+			rule := &fwRule{Line: lineNo, Text: line}
+			if err := parseIptablesRule(rule, args[2:]); err != nil {
+				return nil, errors.Wrap(errors.ParseError, "line "+strconv.Itoa(lineNo), err)
+			}
+			c.Rules = append(c.Rules, rule)
+		default:
+			logger.Debugf("Ignoring line %d: %s", lineNo, line)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(errors.FileSystemError, "failed to read ruleset", err)
+	}
+	return rs, nil
+}
@@ -0,0 +1,194 @@
+//go:build unit
+
+/*
+Copyright © 2024-2025 Auska <luodan0709@live.cn>
+
+*/
+
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+const sampleIptablesSave = `# Generated by iptables-save v1.8.7
+*nat
+:PREROUTING ACCEPT [0:0]
+:POSTROUTING ACCEPT [0:0]
+-A POSTROUTING -s 172.17.0.0/16 ! -o docker0 -j MASQUERADE
+COMMIT
+*filter
+:INPUT DROP [0:0]
+:FORWARD DROP [0:0]
+:OUTPUT ACCEPT [0:0]
+:DOCKER - [0:0]
+:SSH - [0:0]
+-A INPUT -i lo -j ACCEPT
+-A INPUT -m conntrack --ctstate RELATED,ESTABLISHED -j ACCEPT
+-A INPUT -p icmp -m icmp --icmp-type 8 -j ACCEPT
+-A INPUT -p tcp -m tcp --dport 22 -j SSH
+-A INPUT -p tcp -m multiport --dports 80,443 -m comment --comment "web traffic" -j ACCEPT
+[12:720] -A INPUT -p udp -m iprange --src-range 10.0.0.1-10.0.0.9 -m udp --dport 1000:2000 -j ACCEPT
+-A INPUT -j LOG --log-prefix "drop: "
+-A SSH -s 10.0.0.0/8 -j ACCEPT
+-A SSH -m recent --name ssh --rcheck --seconds 60 -j DROP
+-A SSH -j RETURN
+-A FORWARD -o docker0 -j DOCKER
+-A DOCKER -d 172.17.0.2/32 ! -i docker0 -o docker0 -p tcp -m tcp --dport 80 -j ACCEPT
+COMMIT
+`
+
+func TestSplitShellWords(t *testing.T) {
+	tests := []struct {
+		line     string
+		expected []string
+	}{
+		{`-A INPUT -j ACCEPT`, []string{"-A", "INPUT", "-j", "ACCEPT"}},
+		{`-m comment --comment "web  traffic" -j ACCEPT`, []string{"-m", "comment", "--comment", "web  traffic", "-j", "ACCEPT"}},
+		{`--log-prefix "say \"hi\" "`, []string{"--log-prefix", `say "hi" `}},
+		{`--comment ""`, []string{"--comment", ""}},
+	}
+
+	for _, tt := range tests {
+		if result := splitShellWords(tt.line); !reflect.DeepEqual(result, tt.expected) {
+			t.Errorf("splitShellWords(%q) = %q, want %q", tt.line, result, tt.expected)
+		}
+	}
+}
+
+func TestParseIptablesPorts(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []portRange
+		wantErr  bool
+	}{
+		{"22", []portRange{{22, 22}}, false},
+		{"1024:65535", []portRange{{1024, 65535}}, false},
+		{":1023", []portRange{{0, 1023}}, false},
+		{"8000:", []portRange{{8000, 65535}}, false},
+		{"80,443,ssh", []portRange{{80, 80}, {443, 443}, {22, 22}}, false},
+		{"70000", nil, true},
+	}
+
+	for _, tt := range tests {
+		result, err := parseIptablesPorts(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseIptablesPorts(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(result, tt.expected) {
+			t.Errorf("parseIptablesPorts(%q) = %v, want %v", tt.input, result, tt.expected)
+		}
+	}
+}
+
+func TestParseIptablesRule(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		fields  []string
+		target  string
+		isGoto  bool
+		wantErr bool
+	}{
+		{"Basic", []string{"-s", "10.0.0.0/8", "-p", "tcp", "--dport", "22", "-j", "ACCEPT"},
+			[]string{"source address", "protocol", "destination port"}, "ACCEPT", false, false},
+		{"Protocol all is no condition", []string{"-p", "all", "-g", "CUSTOM"}, nil, "CUSTOM", true, false},
+		{"Known modules are ignored", []string{"-m", "conntrack", "--ctstate", "NEW", "-m", "comment", "--comment", "x", "-j", "DROP"},
+			[]string{"conntrack state"}, "DROP", false, false},
+		{"Unknown module", []string{"-m", "limit", "--limit", "10/min", "--limit-burst", "5", "-j", "ACCEPT"},
+			[]string{`"-m limit --limit 10/min --limit-burst 5"`}, "ACCEPT", false, false},
+		{"Unknown option with several values", []string{"-p", "tcp", "--tcp-flags", "SYN,RST", "SYN", "-j", "DROP"},
+			[]string{"protocol", `"--tcp-flags SYN,RST SYN"`}, "DROP", false, false},
+		{"Negated unknown option", []string{"-p", "tcp", "!", "--syn", "-j", "DROP"},
+			[]string{"protocol", `"! --syn"`}, "DROP", false, false},
+		{"Target options are ignored", []string{"-j", "LOG", "--log-prefix", "x"}, nil, "LOG", false, false},
+		{"Missing value", []string{"-s"}, nil, "", false, true},
+		{"Invalid address", []string{"-s", "10.0.0.300", "-j", "DROP"}, nil, "", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := &fwRule{}
+			err := parseIptablesRule(rule, tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseIptablesRule() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			var fields []string
+			for _, cond := range rule.Conditions {
+				fields = append(fields, cond.Field)
+			}
+			if !reflect.DeepEqual(fields, tt.fields) || rule.Target != tt.target || rule.Goto != tt.isGoto {
+				t.Errorf("parseIptablesRule() = %q %s goto=%v, want %q %s goto=%v", fields, rule.Target, rule.Goto, tt.fields, tt.target, tt.isGoto)
+			}
+		})
+	}
+}
+
+func TestParseIptablesSave(t *testing.T) {
+	rs, err := parseIptablesSave(sampleIptablesSave)
+	if err != nil {
+		t.Fatalf("parseIptablesSave() error = %v", err)
+	}
+	if len(rs.Chains) != 7 {
+		t.Errorf("parsed %d chains, want 7", len(rs.Chains))
+	}
+	input := rs.chain("filter", "INPUT")
+	if input == nil || input.Hook != "input" || input.Policy != "DROP" || len(input.Rules) != 7 {
+		t.Fatalf("INPUT chain = %+v", input)
+	}
+	if input.Rules[5].Line != 18 {
+		t.Errorf("rule with counters at line %d, want 18", input.Rules[5].Line)
+	}
+	if docker := rs.chain("filter", "DOCKER"); docker == nil || docker.Hook != "" || docker.Policy != "" {
+		t.Errorf("DOCKER chain = %+v, want a user chain", docker)
+	}
+	if chains := rs.baseChains("postrouting", "nat", 4); len(chains) != 1 || chains[0].Table != "nat" {
+		t.Errorf("baseChains(postrouting, nat) = %v", chains)
+	}
+
+	if _, err := parseIptablesSave("*filter\n:INPUT\n"); err == nil {
+		t.Error("parseIptablesSave() expected an error for an invalid chain declaration")
+	}
+}
+
+func TestTraceIptables(t *testing.T) {
+	rs := mustParseFirewall(t, sampleIptablesSave)
+	tests := []struct {
+		name   string
+		packet fwPacket
+		hook   string
+		target string
+		steps  []string
+	}{
+		{"Web", mustFirewallPacket(t, "eth0", "", "203.0.113.5", "192.0.2.10", "tcp", "443", "new"), "input", "ACCEPT",
+			[]string{"INPUT:17"}},
+		{"SSH from allowed source", mustFirewallPacket(t, "eth0", "", "10.1.2.3", "192.0.2.10", "tcp", "22", "new"), "input", "ACCEPT",
+			[]string{"INPUT:16", "SSH:20"}},
+		{"SSH from elsewhere", mustFirewallPacket(t, "eth0", "", "203.0.113.5", "192.0.2.10", "tcp", "22", "new"), "input", "DROP",
+			[]string{"INPUT:16", "SSH:21?", "SSH:22", "INPUT:19", "INPUT:policy DROP"}},
+		{"Established", mustFirewallPacket(t, "eth0", "", "203.0.113.5", "192.0.2.10", "udp", "53", "established"), "input", "ACCEPT",
+			[]string{"INPUT:14"}},
+		{"Source range", mustFirewallPacket(t, "eth0", "", "10.0.0.7", "192.0.2.10", "udp", "1500", "new"), "input", "ACCEPT",
+			[]string{"INPUT:18"}},
+		{"ICMP type", mustFirewallPacket(t, "eth0", "", "203.0.113.5", "192.0.2.10", "icmp", "", "new"), "input", "DROP",
+			[]string{"INPUT:15?", "INPUT:19", "INPUT:policy DROP"}},
+		{"Docker port", mustFirewallPacket(t, "eth0", "docker0", "203.0.113.5", "172.17.0.2", "tcp", "80", "new"), "forward", "ACCEPT",
+			[]string{"FORWARD:23", "DOCKER:24"}},
+		{"Docker closed port", mustFirewallPacket(t, "eth0", "docker0", "203.0.113.5", "172.17.0.2", "tcp", "81", "new"), "forward", "DROP",
+			[]string{"FORWARD:23", "DOCKER:end of chain, return", "FORWARD:policy DROP"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target, steps := traceFilter(t, rs, tt.hook, "filter", tt.packet)
+			if target != tt.target || !reflect.DeepEqual(steps, tt.steps) {
+				t.Errorf("trace = %s %v, want %s %v", target, steps, tt.target, tt.steps)
+			}
+		})
+	}
+}
@@ -0,0 +1,600 @@
+/*
+Copyright © 2024-2025 Auska <luodan0709@live.cn>
+
+*/
+
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"macconv/pkg/errors"
+	"macconv/pkg/logger"
+)
+
+// nftPriorities nftables 的命名优先级
+var nftPriorities = map[string]int{
+	"raw": -300, "mangle": -150, "dstnat": -100, "filter": 0, "security": 50, "srcnat": 100,
+}
+
+// nftOperators 关系运算符
+var nftOperators = map[string]bool{
+	"==": true, "!=": true, "<": true, ">": true, "<=": true, ">=": true, "eq": true, "ne": true,
+	"lt": true, "gt": true, "le": true, "ge": true,
+}
+
+// nftLogOptions log 语句的带参数选项
+var nftLogOptions = map[string]bool{
+	"prefix": true, "level": true, "flags": true, "group": true, "snaplen": true, "queue-threshold": true,
+}
+
+// nftPendingRule 等待集合全部解析后再构建条件的规则
+type nftPendingRule struct {
+	chain  *fwChain
+	line   int
+	text   string
+	tokens []string
+}
+
+// splitNftTokens 按空白拆分规则，{...} 匿名集合和双引号字符串各作为一个标记，引号被去掉
+func splitNftTokens(line string) []string {
+	var tokens []string
+	for i := 0; i < len(line); {
+		switch c := line[i]; {
+		case c == ' ' || c == '\t':
+			i++
+		case c == '"':
+			end := strings.IndexByte(line[i+1:], '"')
+			if end < 0 {
+				tokens = append(tokens, line[i+1:])
+				return tokens
+			}
+			tokens = append(tokens, line[i+1:i+1+end])
+			i += end + 2
+		case c == '{':
+			end := strings.IndexByte(line[i:], '}')
+			if end < 0 {
+				tokens = append(tokens, line[i:])
+				return tokens
+			}
+			tokens = append(tokens, line[i:i+end+1])
+			i += end + 1
+		default:
+			end := strings.IndexAny(line[i:], " \t")
+			if end < 0 {
+				end = len(line) - i
+			}
+			tokens = append(tokens, line[i:i+end])
+			i += end
+		}
+	}
+	return tokens
+}
+
+// splitNftElements 拆分集合元素，忽略元素后的 timeout、expires 等属性
+func splitNftElements(s string) []string {
+	var elems []string
+	for _, e := range strings.Split(s, ",") {
+		if fields := strings.Fields(e); len(fields) > 0 {
+			elems = append(elems, strings.Trim(fields[0], `"`))
+		}
+	}
+	return elems
+}
+
+// nftValues 展开匹配值：匿名集合、@命名集合或逗号分隔的值
+func nftValues(tok string, sets map[string][]string) ([]string, error) {
+	switch {
+	case strings.HasPrefix(tok, "{"):
+		return splitNftElements(strings.TrimSuffix(strings.TrimPrefix(tok, "{"), "}")), nil
+	case strings.HasPrefix(tok, "@"):
+		elems, ok := sets[tok[1:]]
+		if !ok {
+			return nil, errors.New(errors.ParseError, fmt.Sprintf("unknown set %s", tok))
+		}
+		return elems, nil
+	default:
+		return splitNftElements(tok), nil
+	}
+}
+
+// parseNftPriority 解析 filter、filter - 10 或 -150 形式的优先级
+func parseNftPriority(s string) (int, error) {
+	s = strings.ReplaceAll(s, " ", "")
+	for name, base := range nftPriorities {
+		if rest, ok := strings.CutPrefix(s, name); ok {
+			if rest == "" {
+				return base, nil
+			}
+			offset, err := strconv.Atoi(rest)
+			if err != nil {
+				break
+			}
+			return base + offset, nil
+		}
+	}
+	priority, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, errors.New(errors.ParseError, fmt.Sprintf("invalid chain priority %q", s))
+	}
+	return priority, nil
+}
+
+// parseNftChainHeader 解析 type filter hook input priority filter; policy drop;
+func parseNftChainHeader(c *fwChain, line string) error {
+	for _, stmt := range strings.Split(line, ";") {
+		fields := strings.Fields(stmt)
+		for i := 0; i+1 < len(fields); i++ {
+			switch fields[i] {
+			case "type":
+				c.Type = fields[i+1]
+			case "hook":
+				c.Hook = fields[i+1]
+			case "policy":
+				c.Policy = strings.ToUpper(fields[i+1])
+			case "priority":
+				priority, err := parseNftPriority(strings.Join(fields[i+1:], " "))
+				if err != nil {
+					return err
+				}
+				c.Priority = priority
+				i = len(fields)
+			}
+		}
+	}
+	return nil
+}
+
+// nftSelector 识别 tokens[i] 处支持的匹配字段，返回规范化的字段名和消耗的标记数
+func nftSelector(tokens []string, i int) (string, int) {
+	next := ""
+	if i+1 < len(tokens) {
+		next = tokens[i+1]
+	}
+	switch tokens[i] {
+	case "iif", "iifname":
+		return "iifname", 1
+	case "oif", "oifname":
+		return "oifname", 1
+	case "meta":
+		switch next {
+		case "iif", "iifname":
+			return "iifname", 2
+		case "oif", "oifname":
+			return "oifname", 2
+		case "l4proto", "nfproto", "protocol":
+			return "meta " + next, 2
+		}
+	case "ip":
+		if next == "saddr" || next == "daddr" || next == "protocol" {
+			return "ip " + next, 2
+		}
+	case "ip6":
+		if next == "saddr" || next == "daddr" || next == "nexthdr" {
+			return "ip6 " + next, 2
+		}
+	case "tcp", "udp", "sctp", "dccp", "udplite", "th":
+		if next == "sport" || next == "dport" {
+			return tokens[i] + " " + next, 2
+		}
+	case "ct":
+		if next == "state" {
+			return "ct state", 2
+		}
+	case "icmp", "icmpv6":
+		if next == "type" {
+			return tokens[i] + " type", 2
+		}
+	}
+	return "", 0
+}
+
+// nftVerdict 识别 tokens[i] 处的裁决语句
+func nftVerdict(tokens []string, i int) (target string, isGoto, ok bool) {
+	switch t := tokens[i]; t {
+	case "accept", "drop", "reject", "queue", "return", "masquerade", "snat", "dnat", "redirect":
+		return strings.ToUpper(t), false, true
+	case "continue":
+		return "", false, true
+	case "jump", "goto":
+		if i+1 < len(tokens) {
+			return tokens[i+1], t == "goto", true
+		}
+	}
+	return "", false, false
+}
+
+// parseNftPorts 解析 22、1024-65535 或端口名称
+func parseNftPorts(elems []string) ([]portRange, error) {
+	var ranges []portRange
+	for _, e := range elems {
+		if port, ok := aclPorts[strings.ToLower(e)]; ok {
+			ranges = append(ranges, portRange{port, port})
+			continue
+		}
+		lo, hi, isRange := strings.Cut(e, "-")
+		first, err := parseACLPort(lo)
+		if err != nil {
+			return nil, err
+		}
+		r := portRange{first, first}
+		if isRange {
+			if r.Hi, err = parseACLPort(hi); err != nil {
+				return nil, err
+			}
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges, nil
+}
+
+// nftConditions 将“字段 运算符 值”转换为匹配条件，text 为原始表达式
+func nftConditions(key, op string, elems []string, text string) ([]fwCondition, error) {
+	negate := op == "!=" || op == "ne"
+	relational := !negate && op != "==" && op != "eq"
+	if len(elems) == 0 {
+		return nil, errors.New(errors.ParseError, "missing value")
+	}
+	if relational && !strings.HasSuffix(key, "port") {
+		return nil, errors.New(errors.ParseError, fmt.Sprintf("unsupported operator %s for %s", op, key))
+	}
+
+	family, selector, _ := strings.Cut(key, " ")
+	switch key {
+	case "iifname":
+		return []fwCondition{ifaceCondition("input interface", false, elems, negate)}, nil
+	case "oifname":
+		return []fwCondition{ifaceCondition("output interface", true, elems, negate)}, nil
+	case "ip saddr", "ip daddr", "ip6 saddr", "ip6 daddr":
+		ranges, err := parseAddrList(strings.Join(elems, ","))
+		if err != nil {
+			return nil, err
+		}
+		field := "source address"
+		if selector == "daddr" {
+			field = "destination address"
+		}
+		return []fwCondition{familyCondition(nftFamily(family)), addrCondition(field, selector == "daddr", ranges, negate)}, nil
+	case "ip protocol", "ip6 nexthdr", "meta l4proto":
+		var protocols []int
+		for _, e := range elems {
+			proto, err := parseFirewallProtocol(e)
+			if err != nil {
+				return nil, err
+			}
+			protocols = append(protocols, proto)
+		}
+		conds := []fwCondition{protocolCondition(protocols, negate)}
+		if family != "meta" {
+			conds = append([]fwCondition{familyCondition(nftFamily(family))}, conds...)
+		}
+		return conds, nil
+	case "meta nfproto", "meta protocol":
+		if len(elems) != 1 {
+			return nil, errors.New(errors.ParseError, "unsupported protocol set")
+		}
+		version := 0
+		switch elems[0] {
+		case "ipv4", "ip":
+			version = 4
+		case "ipv6", "ip6":
+			version = 6
+		default:
+			return nil, errors.New(errors.ParseError, fmt.Sprintf("unsupported protocol %q", elems[0]))
+		}
+		if negate {
+			version = 10 - version
+		}
+		return []fwCondition{familyCondition(version)}, nil
+	case "ct state":
+		return []fwCondition{stateCondition(elems, negate)}, nil
+	case "icmp type":
+		return []fwCondition{protocolCondition([]int{aclProtocols["icmp"]}, false), unmodelledCondition(text)}, nil
+	case "icmpv6 type":
+		return []fwCondition{protocolCondition([]int{aclProtocols["icmpv6"]}, false), unmodelledCondition(text)}, nil
+	}
+
+	// tcp/udp/sctp/dccp/udplite/th sport|dport
+	ranges, err := parseNftPorts(elems)
+	if err != nil {
+		return nil, err
+	}
+	if relational {
+		if len(ranges) != 1 || ranges[0].Lo != ranges[0].Hi {
+			return nil, errors.New(errors.ParseError, fmt.Sprintf("operator %s needs a single port", op))
+		}
+		port := ranges[0].Lo
+		switch op {
+		case "<", "lt":
+			ranges[0] = portRange{0, port - 1}
+		case ">", "gt":
+			ranges[0] = portRange{port + 1, 65535}
+		case "<=", "le":
+			ranges[0] = portRange{0, port}
+		case ">=", "ge":
+			ranges[0] = portRange{port, 65535}
+		}
+	}
+	field := "source port"
+	if selector == "dport" {
+		field = "destination port"
+	}
+	conds := []fwCondition{portCondition(field, selector == "dport", ranges, negate)}
+	if family != "th" {
+		proto, err := parseFirewallProtocol(family)
+		if err != nil {
+			return nil, err
+		}
+		conds = append([]fwCondition{protocolCondition([]int{proto}, false)}, conds...)
+	}
+	return conds, nil
+}
+
+// nftFamily 返回 ip 或 ip6 对应的地址族
+func nftFamily(family string) int {
+	if family == "ip6" {
+		return 6
+	}
+	return 4
+}
+
+// expandNftVmap 将 "字段 vmap { 值 : 裁决, ... }" 展开为逐项匹配的规则
+// vmap 的键互不重叠，按顺序逐项匹配与查表结果相同
+func expandNftVmap(base *fwRule, key, mapping string) ([]*fwRule, error) {
+	var rules []*fwRule
+	body := strings.TrimSuffix(strings.TrimPrefix(mapping, "{"), "}")
+	for _, entry := range strings.Split(body, ",") {
+		value, verdict, ok := strings.Cut(entry, " : ")
+		if !ok {
+			return nil, errors.New(errors.ParseError, fmt.Sprintf("invalid vmap entry %q", strings.TrimSpace(entry)))
+		}
+		value = strings.TrimSpace(value)
+		target, isGoto, ok := nftVerdict(strings.Fields(verdict), 0)
+		if !ok {
+			return nil, errors.New(errors.ParseError, fmt.Sprintf("invalid vmap verdict %q", strings.TrimSpace(verdict)))
+		}
+		conds, err := nftConditions(key, "==", splitNftElements(value), key+" "+value)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, &fwRule{
+			Line:       base.Line,
+			Text:       base.Text,
+			Conditions: append(append([]fwCondition{}, base.Conditions...), conds...),
+			Target:     target,
+			Goto:       isGoto,
+		})
+	}
+	return rules, nil
+}
+
+// parseNftRule 解析一条 nft 规则。计数、日志和注释语句被忽略，
+// 无法识别的匹配作为未建模条件，含 set 的语句（如 meta mark set 1）不影响匹配
+func parseNftRule(line int, text string, tokens []string, sets map[string][]string) []*fwRule {
+	rule := &fwRule{Line: line, Text: text}
+	var unknown []string
+	flush := func() {
+		if len(unknown) == 0 {
+			return
+		}
+		statement := unknown[0] == "add" || unknown[0] == "update" || unknown[0] == "notrack"
+		for _, t := range unknown {
+			statement = statement || t == "set"
+		}
+		if !statement {
+			rule.Conditions = append(rule.Conditions, unmodelledCondition(strings.Join(unknown, " ")))
+		}
+		unknown = nil
+	}
+
+	for i := 0; i < len(tokens); {
+		if target, isGoto, ok := nftVerdict(tokens, i); ok {
+			flush()
+			rule.Target, rule.Goto = target, isGoto
+			return []*fwRule{rule}
+		}
+
+		switch tokens[i] {
+		case "counter":
+			flush()
+			i++
+			for i+1 < len(tokens) && (tokens[i] == "packets" || tokens[i] == "bytes") {
+				i += 2
+			}
+			continue
+		case "log":
+			flush()
+			i++
+			for i+1 < len(tokens) && nftLogOptions[tokens[i]] {
+				i += 2
+			}
+			continue
+		case "comment":
+			flush()
+			i += 2
+			continue
+		}
+
+		key, n := nftSelector(tokens, i)
+		if n == 0 {
+			unknown = append(unknown, tokens[i])
+			i++
+			continue
+		}
+		flush()
+		j := i + n
+		op := "=="
+		if j < len(tokens) && nftOperators[tokens[j]] {
+			op = tokens[j]
+			j++
+		}
+		if j >= len(tokens) {
+			unknown = append(unknown, tokens[i:]...)
+			break
+		}
+
+		switch tokens[j] {
+		case "vmap":
+			if j+1 < len(tokens) {
+				rules, err := expandNftVmap(rule, key, tokens[j+1])
+				if err == nil {
+					return rules
+				}
+				logger.Debugf("line %d: %v", line, err)
+			}
+			rule.Conditions = append(rule.Conditions, unmodelledCondition(strings.Join(tokens[i:], " ")))
+			return []*fwRule{rule}
+		case ".":
+			// 拼接匹配，如 ip saddr . tcp dport @allowed，整体作为未建模条件
+			for j < len(tokens) && !strings.HasPrefix(tokens[j], "{") && !strings.HasPrefix(tokens[j], "@") {
+				j++
+			}
+			unknown = append(unknown, tokens[i:min(j+1, len(tokens))]...)
+			flush()
+			i = j + 1
+			continue
+		case "set":
+			unknown = append(unknown, tokens[i:j+1]...)
+			i = j + 1
+			continue
+		}
+
+		exprText := strings.Join(tokens[i:j+1], " ")
+		elems, err := nftValues(tokens[j], sets)
+		var conds []fwCondition
+		if err == nil {
+			conds, err = nftConditions(key, op, elems, exprText)
+		}
+		if err != nil {
+			logger.Debugf("line %d: %s: %v", line, exprText, err)
+			conds = []fwCondition{unmodelledCondition(exprText)}
+		}
+		rule.Conditions = append(rule.Conditions, conds...)
+		i = j + 1
+	}
+	flush()
+	return []*fwRule{rule}
+}
+
+// parseNftRuleset 解析 nft list ruleset 的输出。命名集合的元素可用于 @name 匹配，
+// map、flowtable 等其他对象被跳过
+func parseNftRuleset(text string) (*fwRuleset, error) {
+	rs := &fwRuleset{Format: firewallFormatNft}
+	sets := make(map[string]map[string][]string) // 表 -> 集合名 -> 元素
+	var pending []nftPendingRule
+
+	var blocks []string // 当前所在的块：table、chain、set 或 skip
+	var table, family, setName string
+	var chain *fwChain
+	var elements *strings.Builder // 正在读取跨行的 elements = { ... }
+
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := scanner.Text()
+		// nft -a 在行尾输出 # handle N
+		if idx := strings.Index(line, "# handle "); idx >= 0 {
+			line = line[:idx]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if elements != nil {
+			before, _, closed := strings.Cut(line, "}")
+			elements.WriteString(before + ",")
+			if closed {
+				if setName != "" {
+					sets[table][setName] = splitNftElements(elements.String())
+				}
+				elements = nil
+			}
+			continue
+		}
+
+		inside := ""
+		if len(blocks) > 0 {
+			inside = blocks[len(blocks)-1]
+		}
+		fields := strings.Fields(line)
+
+		switch {
+		case line == "}":
+			if len(blocks) == 0 {
+				return nil, errors.New(errors.ParseError, fmt.Sprintf("line %d: unexpected }", lineNo))
+			}
+			switch inside {
+			case "table":
+				table = ""
+			case "chain":
+				chain = nil
+			case "set":
+				setName = ""
+			}
+			blocks = blocks[:len(blocks)-1]
+		case inside == "skip", inside == "set":
+			if strings.HasPrefix(line, "elements = {") {
+				body := strings.TrimPrefix(line, "elements = {")
+				if before, _, closed := strings.Cut(body, "}"); closed {
+					if inside == "set" {
+						sets[table][setName] = splitNftElements(before)
+					}
+				} else {
+					// map 等跳过的块中 setName 为空，跨行元素读取后丢弃
+					elements = &strings.Builder{}
+					elements.WriteString(body + ",")
+				}
+			} else if strings.HasSuffix(line, "{") {
+				blocks = append(blocks, "skip")
+			}
+		case inside == "" && fields[0] == "table" && strings.HasSuffix(line, "{"):
+			switch len(fields) {
+			case 3:
+				family, table = "ip", "ip "+fields[1]
+			case 4:
+				family, table = fields[1], fields[1]+" "+fields[2]
+			default:
+				return nil, errors.New(errors.ParseError, fmt.Sprintf("line %d: invalid table declaration", lineNo))
+			}
+			if sets[table] == nil {
+				sets[table] = make(map[string][]string)
+			}
+			blocks = append(blocks, "table")
+		case inside == "table" && strings.HasSuffix(line, "{") && len(fields) == 3 && fields[0] == "chain":
+			chain = rs.addChain(table, family, fields[1])
+			blocks = append(blocks, "chain")
+		case inside == "table" && strings.HasSuffix(line, "{") && len(fields) == 3 && fields[0] == "set":
+			setName = fields[1]
+			blocks = append(blocks, "set")
+		case strings.HasSuffix(line, "{"):
+			blocks = append(blocks, "skip")
+		case inside == "chain" && (fields[0] == "type" || fields[0] == "policy"):
+			if err := parseNftChainHeader(chain, line); err != nil {
+				return nil, errors.Wrap(errors.ParseError, "line "+strconv.Itoa(lineNo), err)
+			}
+		case inside == "chain" && fields[0] != "comment":
+			pending = append(pending, nftPendingRule{chain: chain, line: lineNo, text: line, tokens: splitNftTokens(line)})
+		default:
+			logger.Debugf("Ignoring line %d: %s", lineNo, line)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(errors.FileSystemError, "failed to read ruleset", err)
+	}
+	if len(blocks) > 0 {
+		return nil, errors.New(errors.ParseError, "unexpected end of ruleset, missing }")
+	}
+
+	// 集合可能在引用它的链之后定义，所以在全部读取后再构建规则
+	for _, pr := range pending {
+		pr.chain.Rules = append(pr.chain.Rules, parseNftRule(pr.line, pr.text, pr.tokens, sets[pr.chain.Table])...)
+	}
+	return rs, nil
+}
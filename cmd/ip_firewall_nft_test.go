@@ -0,0 +1,249 @@
+//go:build unit
+
+/*
+Copyright © 2024-2025 Auska <luodan0709@live.cn>
+
+*/
+
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+const sampleNftRuleset = `table inet filter {
+	set allowed_v4 {
+		type ipv4_addr
+		flags interval
+		elements = { 10.0.0.0/8, 192.168.1.0/24,
+			     198.51.100.7 }
+	}
+
+	map portmap {
+		type inet_service : verdict
+		elements = { 80 : accept,
+			     443 : accept }
+	}
+
+	chain input { # handle 1
+		type filter hook input priority filter; policy drop;
+		ct state vmap { established : accept, related : accept, invalid : drop }
+		iifname "lo" accept
+		ip6 nexthdr ipv6-icmp accept
+		icmp type echo-request limit rate 5/second accept
+		ip saddr @allowed_v4 tcp dport 22 counter packets 10 bytes 600 accept comment "ssh"
+		tcp dport { 80, 443 } accept
+		meta l4proto udp th dport 1000-2000 jump udp_range
+		ip6 saddr 2001:db8::/32 tcp dport ssh accept # handle 9
+		meta mark set 0x1 tcp dport >= 8000 accept
+		log prefix "input drop: " counter drop
+	}
+
+	chain udp_range {
+		ip saddr != 10.0.0.0/8 reject with icmp type admin-prohibited
+	}
+
+	chain forward {
+		type filter hook forward priority filter - 10; policy accept;
+		oifname "docker*" ip daddr 172.17.0.0/16 drop
+	}
+}
+table ip extra {
+	chain input {
+		type filter hook input priority 10; policy accept;
+		tcp dport 443 drop
+	}
+}
+`
+
+func TestSplitNftTokens(t *testing.T) {
+	tests := []struct {
+		line     string
+		expected []string
+	}{
+		{`tcp dport 22 accept`, []string{"tcp", "dport", "22", "accept"}},
+		{`iifname "lo" accept`, []string{"iifname", "lo", "accept"}},
+		{`tcp dport { 80, 443 } log prefix "in: " drop`, []string{"tcp", "dport", "{ 80, 443 }", "log", "prefix", "in: ", "drop"}},
+		{"ip saddr\t@allowed  drop", []string{"ip", "saddr", "@allowed", "drop"}},
+	}
+
+	for _, tt := range tests {
+		if result := splitNftTokens(tt.line); !reflect.DeepEqual(result, tt.expected) {
+			t.Errorf("splitNftTokens(%q) = %q, want %q", tt.line, result, tt.expected)
+		}
+	}
+}
+
+func TestParseNftPriority(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int
+		wantErr  bool
+	}{
+		{"filter", 0, false},
+		{"filter - 10", -10, false},
+		{"mangle + 5", -145, false},
+		{"-300", -300, false},
+		{"10", 10, false},
+		{"unknown", 0, true},
+	}
+
+	for _, tt := range tests {
+		result, err := parseNftPriority(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseNftPriority(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if result != tt.expected {
+			t.Errorf("parseNftPriority(%q) = %d, want %d", tt.input, result, tt.expected)
+		}
+	}
+}
+
+func TestParseNftRule(t *testing.T) {
+	sets := map[string][]string{"ports": {"22", "80"}}
+	tests := []struct {
+		name    string
+		line    string
+		fields  [][]string // 每条展开规则的条件字段
+		targets []string
+	}{
+		{"Address and port", "ip saddr 10.0.0.0/8 tcp dport 22 accept",
+			[][]string{{"address family", "source address", "protocol", "destination port"}}, []string{"ACCEPT"}},
+		{"Named set", "th dport @ports drop", [][]string{{"destination port"}}, []string{"DROP"}},
+		{"Unknown set", "tcp dport @missing drop", [][]string{{`"tcp dport @missing"`}}, []string{"DROP"}},
+		{"Statements are skipped", `counter packets 1 bytes 2 log prefix "x" level info meta mark set 1 goto other`,
+			nil, []string{"other"}},
+		{"Unknown match", "fib daddr type local limit rate 5/second accept",
+			[][]string{{`"fib daddr type local limit rate 5/second"`}}, []string{"ACCEPT"}},
+		{"Concatenation", "ip saddr . tcp dport { 10.0.0.1 . 22 } accept",
+			[][]string{{`"ip saddr . tcp dport { 10.0.0.1 . 22 }"`}}, []string{"ACCEPT"}},
+		{"Verdict map", "iifname eth0 tcp dport vmap { 22 : jump ssh, 80 : accept }",
+			[][]string{{"input interface", "protocol", "destination port"}, {"input interface", "protocol", "destination port"}},
+			[]string{"ssh", "ACCEPT"}},
+		{"No verdict", "ct state new counter", [][]string{{"conntrack state"}}, []string{""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := parseNftRule(1, tt.line, splitNftTokens(tt.line), sets)
+			var fields [][]string
+			var targets []string
+			for _, rule := range rules {
+				var f []string
+				for _, cond := range rule.Conditions {
+					f = append(f, cond.Field)
+				}
+				if f != nil {
+					fields = append(fields, f)
+				}
+				targets = append(targets, rule.Target)
+			}
+			if !reflect.DeepEqual(fields, tt.fields) || !reflect.DeepEqual(targets, tt.targets) {
+				t.Errorf("parseNftRule() = %q %q, want %q %q", fields, targets, tt.fields, tt.targets)
+			}
+		})
+	}
+
+	rules := parseNftRule(1, "", splitNftTokens("ct state vmap { established : accept, invalid : drop }"), nil)
+	if len(rules) != 2 || rules[1].Target != "DROP" {
+		t.Fatalf("vmap expanded to %d rules", len(rules))
+	}
+	if ok, known := rules[1].Conditions[0].Match(fwPacket{State: "INVALID"}); !ok || !known {
+		t.Error("vmap entry invalid : drop does not match an invalid packet")
+	}
+}
+
+func TestParseNftRuleset(t *testing.T) {
+	rs, err := parseNftRuleset(sampleNftRuleset)
+	if err != nil {
+		t.Fatalf("parseNftRuleset() error = %v", err)
+	}
+	if len(rs.Chains) != 4 {
+		t.Errorf("parsed %d chains, want 4", len(rs.Chains))
+	}
+	input := rs.chain("inet filter", "input")
+	if input == nil || input.Hook != "input" || input.Type != "filter" || input.Policy != "DROP" || input.Family != "inet" {
+		t.Fatalf("input chain = %+v", input)
+	}
+	// ct state vmap 展开为 3 条规则
+	if len(input.Rules) != 12 {
+		t.Errorf("input has %d rules, want 12", len(input.Rules))
+	}
+	if forward := rs.chain("inet filter", "forward"); forward == nil || forward.Priority != -10 {
+		t.Errorf("forward chain = %+v, want priority -10", forward)
+	}
+	if udp := rs.chain("inet filter", "udp_range"); udp == nil || udp.Hook != "" {
+		t.Errorf("udp_range chain = %+v, want a regular chain", udp)
+	}
+
+	tests := []struct {
+		name   string
+		family int
+		table  string
+		chains []string
+	}{
+		{"IPv4 sees both tables", 4, "", []string{"inet filter", "ip extra"}},
+		{"IPv6 skips ip tables", 6, "", []string{"inet filter"}},
+		{"Table filter", 4, "extra", []string{"ip extra"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var tables []string
+			for _, c := range rs.baseChains("input", tt.table, tt.family) {
+				tables = append(tables, c.Table)
+			}
+			if !reflect.DeepEqual(tables, tt.chains) {
+				t.Errorf("baseChains() tables = %v, want %v", tables, tt.chains)
+			}
+		})
+	}
+
+	for _, text := range []string{
+		"table inet filter {\n\tchain input {\n",
+		"}\n",
+		"table inet filter {\n\tchain input {\n\t\ttype filter hook input priority bogus;\n\t}\n}\n",
+	} {
+		if _, err := parseNftRuleset(text); err == nil {
+			t.Errorf("parseNftRuleset(%q) expected an error", text)
+		}
+	}
+}
+
+func TestTraceNft(t *testing.T) {
+	rs := mustParseFirewall(t, sampleNftRuleset)
+	tests := []struct {
+		name   string
+		packet fwPacket
+		hook   string
+		target string
+		steps  []string
+	}{
+		{"IPv6 SSH", mustFirewallPacket(t, "eth0", "", "2001:db8::5", "2001:db8::1", "tcp", "22", "new"), "input", "ACCEPT",
+			[]string{"input:24"}},
+		{"SSH from named set", mustFirewallPacket(t, "eth0", "", "198.51.100.7", "192.0.2.1", "tcp", "22", "new"), "input", "ACCEPT",
+			[]string{"input:21", "input:policy ACCEPT"}},
+		{"Later table drops", mustFirewallPacket(t, "eth0", "", "203.0.113.5", "192.0.2.1", "tcp", "443", "new"), "input", "DROP",
+			[]string{"input:22", "input:41"}},
+		{"Jump and reject", mustFirewallPacket(t, "eth0", "", "203.0.113.5", "192.0.2.1", "udp", "1500", "new"), "input", "REJECT",
+			[]string{"input:23", "udp_range:30"}},
+		{"Established by vmap", mustFirewallPacket(t, "eth0", "", "203.0.113.5", "192.0.2.1", "tcp", "25", "established"), "input", "ACCEPT",
+			[]string{"input:17", "input:policy ACCEPT"}},
+		{"Relational port", mustFirewallPacket(t, "eth0", "", "203.0.113.5", "192.0.2.1", "tcp", "8080", "new"), "input", "ACCEPT",
+			[]string{"input:25", "input:policy ACCEPT"}},
+		{"ICMP depends on type and limit", mustFirewallPacket(t, "eth0", "", "203.0.113.5", "192.0.2.1", "icmp", "", "new"), "input", "DROP",
+			[]string{"input:20?", "input:26"}},
+		{"Docker wildcard", mustFirewallPacket(t, "eth0", "docker0", "203.0.113.5", "172.17.0.2", "tcp", "80", "new"), "forward", "DROP",
+			[]string{"forward:35"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target, steps := traceFilter(t, rs, tt.hook, "", tt.packet)
+			if target != tt.target || !reflect.DeepEqual(steps, tt.steps) {
+				t.Errorf("trace = %s %v, want %s %v", target, steps, tt.target, tt.steps)
+			}
+		})
+	}
+}
@@ -0,0 +1,199 @@
+//go:build unit
+
+/*
+Copyright © 2024-2025 Auska <luodan0709@live.cn>
+
+*/
+
+package cmd
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// mustFirewallPacket 构造测试报文，空字符串表示字段未知
+func mustFirewallPacket(t *testing.T, in, out, src, dst, proto, dport, state string) fwPacket {
+	t.Helper()
+	tuple, err := parseACLPacket(src, dst, proto, "", dport, false)
+	if err != nil {
+		t.Fatalf("parseACLPacket() error = %v", err)
+	}
+	return fwPacket{aclPacket: tuple, InIface: in, OutIface: out, State: strings.ToUpper(state)}
+}
+
+func mustParseFirewall(t *testing.T, text string) *fwRuleset {
+	t.Helper()
+	rs, err := parseFirewallRuleset(text, firewallFormatAuto)
+	if err != nil {
+		t.Fatalf("parseFirewallRuleset() error = %v", err)
+	}
+	return rs
+}
+
+// traceSummary 返回追踪步骤的摘要：规则为 "链:行号"，可能匹配为 "链:行号?"，其余为 "链:说明"
+func traceSummary(steps []fwStep) []string {
+	var summary []string
+	for _, step := range steps {
+		switch {
+		case step.Rule == nil:
+			summary = append(summary, step.Chain.Name+":"+step.Note)
+		case step.Verdict == aclMaybe:
+			summary = append(summary, step.Chain.Name+":"+strconv.Itoa(step.Rule.Line)+"?")
+		default:
+			summary = append(summary, step.Chain.Name+":"+strconv.Itoa(step.Rule.Line))
+		}
+	}
+	return summary
+}
+
+// traceFilter 追踪报文经过 hook 上全部基础链的最终目标和步骤
+func traceFilter(t *testing.T, rs *fwRuleset, hook, table string, p fwPacket) (string, []string) {
+	t.Helper()
+	results, err := rs.traceHook(rs.baseChains(hook, table, p.family()), p)
+	if err != nil {
+		t.Fatalf("traceHook() error = %v", err)
+	}
+	if len(results) == 0 {
+		t.Fatalf("no base chains on hook %s", hook)
+	}
+	var steps []string
+	for _, result := range results {
+		steps = append(steps, traceSummary(result.Steps)...)
+	}
+	return results[len(results)-1].Target, steps
+}
+
+func TestTraceChainFlow(t *testing.T) {
+	rs := mustParseFirewall(t, `*filter
+:INPUT DROP [0:0]
+:A - [0:0]
+:B - [0:0]
+:C - [0:0]
+-A INPUT -p tcp -j A
+-A INPUT -p udp -g C
+-A INPUT -p tcp --dport 22 -j ACCEPT
+-A A --dport 80 -j B
+-A A --dport 22 -j RETURN
+-A A -j LOG
+-A B -j RETURN
+-A C -j MARK --set-mark 1
+COMMIT
+`)
+	tests := []struct {
+		name   string
+		packet fwPacket
+		target string
+		steps  []string
+	}{
+		{"Jump, return and fall through", mustFirewallPacket(t, "", "", "", "", "tcp", "22", ""), "ACCEPT",
+			[]string{"INPUT:6", "A:10", "INPUT:8"}},
+		{"Nested jump and end of chain", mustFirewallPacket(t, "", "", "", "", "tcp", "80", ""), "DROP",
+			[]string{"INPUT:6", "A:9", "B:12", "A:11", "A:end of chain, return", "INPUT:policy DROP"}},
+		{"Goto from base chain applies policy", mustFirewallPacket(t, "", "", "", "", "udp", "53", ""), "DROP",
+			[]string{"INPUT:7", "C:13", "C:end of chain, return", "INPUT:policy DROP"}},
+		{"Unknown protocol is a possible match", mustFirewallPacket(t, "", "", "", "", "", "", ""), "DROP",
+			[]string{"INPUT:6?", "INPUT:7?", "INPUT:8?", "INPUT:policy DROP"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target, steps := traceFilter(t, rs, "input", "filter", tt.packet)
+			if target != tt.target || !reflect.DeepEqual(steps, tt.steps) {
+				t.Errorf("trace = %s %v, want %s %v", target, steps, tt.target, tt.steps)
+			}
+		})
+	}
+}
+
+func TestTraceChainLoop(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+	}{
+		{"Jump loop", "*filter\n:INPUT ACCEPT [0:0]\n-N LOOP\n-A INPUT -j LOOP\n-A LOOP -j LOOP\n"},
+		{"Goto loop", "*filter\n:INPUT ACCEPT [0:0]\n-N A\n-N B\n-A INPUT -j A\n-A A -g B\n-A B -g A\n"},
+		{"Goto loop from base chain", "*filter\n:INPUT ACCEPT [0:0]\n-N A\n-A INPUT -g A\n-A A -g A\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rs := mustParseFirewall(t, tt.text)
+			chains := rs.baseChains("input", "filter", 0)
+			if _, err := rs.traceHook(chains, mustFirewallPacket(t, "", "", "", "", "", "", "")); err == nil {
+				t.Error("traceHook() expected an error for a loop")
+			}
+		})
+	}
+}
+
+func TestFirewallConditions(t *testing.T) {
+	p := mustFirewallPacket(t, "eth0", "", "10.0.0.5", "192.0.2.1", "tcp", "443", "new")
+	ranges, err := parseAddrList("10.0.0.0/24")
+	if err != nil {
+		t.Fatalf("parseAddrList() error = %v", err)
+	}
+	tests := []struct {
+		name      string
+		cond      fwCondition
+		ok, known bool
+	}{
+		{"Source address", addrCondition("source address", false, ranges, false), true, true},
+		{"Negated destination", addrCondition("destination address", true, ranges, true), true, true},
+		{"Interface prefix", ifaceCondition("input interface", false, []string{"eth+"}, false), true, true},
+		{"Interface wildcard", ifaceCondition("input interface", false, []string{"wl*"}, false), false, true},
+		{"Unknown output interface", ifaceCondition("output interface", true, []string{"eth1"}, false), false, false},
+		{"Protocol", protocolCondition([]int{6, 17}, false), true, true},
+		{"Port", portCondition("destination port", true, []portRange{{400, 500}}, false), true, true},
+		{"Unknown source port", portCondition("source port", false, []portRange{{0, 1023}}, false), false, false},
+		{"State", stateCondition([]string{"established", "related"}, true), true, true},
+		{"Family", familyCondition(6), false, true},
+		{"Unmodelled", unmodelledCondition("-m limit"), false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, known := tt.cond.Match(p)
+			if ok != tt.ok || known != tt.known {
+				t.Errorf("Match() = %v, %v, want %v, %v", ok, known, tt.ok, tt.known)
+			}
+		})
+	}
+}
+
+func TestDetectFirewallFormat(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected string
+	}{
+		{"# Generated by iptables-save\n*filter\n:INPUT ACCEPT [0:0]\n", firewallFormatIptables},
+		{"-P INPUT DROP\n-A INPUT -j ACCEPT\n", firewallFormatIptables},
+		{"table inet filter {\n\tchain input {\n\t}\n}\n", firewallFormatNft},
+	}
+
+	for _, tt := range tests {
+		if result := detectFirewallFormat(tt.text); result != tt.expected {
+			t.Errorf("detectFirewallFormat(%q) = %s, want %s", tt.text, result, tt.expected)
+		}
+	}
+}
+
+func TestDefaultHook(t *testing.T) {
+	tests := []struct {
+		in, out  string
+		expected string
+	}{
+		{"eth0", "", "input"},
+		{"eth0", "docker0", "forward"},
+		{"", "eth0", "output"},
+		{"", "", "input"},
+	}
+
+	for _, tt := range tests {
+		if result := defaultHook(tt.in, tt.out); result != tt.expected {
+			t.Errorf("defaultHook(%q, %q) = %s, want %s", tt.in, tt.out, result, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,253 @@
+/*
+Copyright © 2024-2025 Auska <luodan0709@live.cn>
+
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"math/big"
+	"net"
+	"net/netip"
+	"strconv"
+
+	"github.com/spf13/cobra"
+	"macconv/pkg/errors"
+	"macconv/pkg/logger"
+)
+
+var ipFitCmd = &cobra.Command{
+	Use:   "fit HOSTS | fit CIDR",
+	Short: "Find the smallest subnet for a host count, or report utilization of a CIDR",
+	Long: `
+With a host count, print the smallest IPv4 prefix whose usable hosts hold the
+requested hosts plus --reserved addresses (gateway, HSRP/VRRP peers, ...).
+/31 and /32 follow the same rules as "macconv ip".
+
+With a CIDR, compare its usable hosts against the addresses in use, given
+with --used or --file (IPs, CIDRs, ranges or 10.0.0.* globs, one per line),
+and report used and free counts, utilization and the free ranges. For example:
+
+	macconv ip fit 300
+	macconv ip fit 50 --reserved 3
+	macconv ip fit 10.0.0.0/24 --used 10.0.0.1,10.0.0.10-20
+	macconv ip fit 10.0.0.0/22 -f arp-table.txt`,
+	Run: runFit,
+}
+
+func init() {
+	ipCmd.AddCommand(ipFitCmd)
+	ipFitCmd.Flags().Int("reserved", 0, "Addresses to reserve for gateways, HSRP/VRRP and similar")
+	ipFitCmd.Flags().StringSlice("used", nil, "Addresses or ranges in use (utilization mode)")
+	ipFitCmd.Flags().StringP("file", "f", "", "File with addresses in use, one per line (\"-\" for stdin)")
+	ipFitCmd.Flags().Bool("all", false, "Count network, broadcast and anycast addresses as usable")
+}
+
+// usableIPv4Hosts 返回 IPv4 前缀的可用主机数，与 calculateCIDRInfo 的规则一致
+func usableIPv4Hosts(bits int) int64 {
+	switch hostBits := 32 - bits; hostBits {
+	case 0:
+		return 1
+	case 1:
+		return 2
+	default:
+		return 1<<hostBits - 2
+	}
+}
+
+// fitPrefixLength 返回可用主机数不少于 hosts+reserved 的最长 IPv4 前缀长度
+func fitPrefixLength(hosts, reserved int64) (int, error) {
+	if hosts < 1 || reserved < 0 {
+		return 0, errors.New(errors.ValidationError, "host count must be positive and reserved must not be negative")
+	}
+	// 先排除超出 IPv4 地址空间的输入，避免 hosts+reserved 溢出
+	if hosts > 1<<32 || reserved > 1<<32 {
+		return 0, errors.New(errors.ValidationError, "host count does not fit in any IPv4 prefix")
+	}
+	need := hosts + reserved
+	for bits := 32; bits >= 0; bits-- {
+		if usableIPv4Hosts(bits) >= need {
+			return bits, nil
+		}
+	}
+	return 0, errors.New(errors.ValidationError, fmt.Sprintf("%d hosts do not fit in any IPv4 prefix", need))
+}
+
+// usageReport 网段利用率统计
+type usageReport struct {
+	Prefix   netip.Prefix
+	Hosts    addrRange
+	Reserved []addrRange // hosts 内部不可用的地址（/64 的保留任播）
+	Used     *big.Int
+	Free     []addrRange
+	Outside  []netip.Prefix // 不在网段内的地址
+	Special  []netip.Prefix // 在网段内但不是可用主机地址（网络、广播、保留任播）
+}
+
+// hostCount 返回可用主机数，即 hosts 减去内部保留的地址
+func (r usageReport) hostCount() *big.Int {
+	total := r.Hosts.size()
+	for _, h := range r.Reserved {
+		total.Sub(total, h.size())
+	}
+	return total
+}
+
+// computeUsage 统计可用主机范围 hosts 内被 used 占用的地址，重复的地址只计一次
+// holes 是 hosts 内部不可用的地址，不计入占用也不算空闲
+func computeUsage(prefix netip.Prefix, hosts addrRange, holes []addrRange, used []netip.Prefix) usageReport {
+	report := usageReport{Prefix: prefix, Hosts: hosts, Reserved: holes, Used: new(big.Int)}
+
+	var clipped []addrRange
+	for _, p := range used {
+		if !p.Overlaps(prefix) {
+			report.Outside = append(report.Outside, p)
+			continue
+		}
+		r := prefixRange(p)
+		if r.First.Less(hosts.First) || hosts.Last.Less(r.Last) || overlapsRanges(r, holes) {
+			report.Special = append(report.Special, p)
+		}
+		r.First = maxAddr(r.First, hosts.First)
+		r.Last = minAddr(r.Last, hosts.Last)
+		if !r.Last.Less(r.First) {
+			clipped = append(clipped, r)
+		}
+	}
+
+	merged := mergeRanges(clipped)
+	for _, r := range subtractRanges(merged, holes) {
+		report.Used.Add(report.Used, r.size())
+	}
+	report.Free = subtractRanges(rangeGaps(hosts.First, hosts.Last, merged), holes)
+	return report
+}
+
+// overlapsRanges 判断 r 是否与 ranges 中任一范围有公共地址
+func overlapsRanges(r addrRange, ranges []addrRange) bool {
+	for _, other := range ranges {
+		if rel := r.relate(other); rel != relationDisjoint && rel != relationAdjacent {
+			return true
+		}
+	}
+	return false
+}
+
+func maxAddr(a, b netip.Addr) netip.Addr {
+	if a.Less(b) {
+		return b
+	}
+	return a
+}
+
+func minAddr(a, b netip.Addr) netip.Addr {
+	if a.Less(b) {
+		return a
+	}
+	return b
+}
+
+func printFit(hosts, reserved int64) {
+	bits, err := fitPrefixLength(hosts, reserved)
+	if err != nil {
+		logger.PrintError(err)
+		return
+	}
+
+	usable := usableIPv4Hosts(bits)
+	need := hosts + reserved
+	if reserved > 0 {
+		fmt.Printf("Required Hosts: %d (%d + %d reserved)\n", need, hosts, reserved)
+	} else {
+		fmt.Println("Required Hosts:", need)
+	}
+	fmt.Printf("Prefix Length: /%d\n", bits)
+	mask := net.CIDRMask(bits, 32)
+	fmt.Println("Subnet Mask:", net.IP(mask))
+	fmt.Println("Inverse Mask:", calculateInverseMask(mask))
+	fmt.Println("Usable Hosts:", usable)
+	fmt.Println("Spare Hosts:", usable-need)
+	fmt.Println("Utilization:", formatPercent(big.NewInt(need), big.NewInt(usable)))
+	if bits < 32 {
+		fmt.Printf("Next Smaller: /%d (%d usable hosts)\n", bits+1, usableIPv4Hosts(bits+1))
+	}
+
+	logger.Infof("Successfully fitted %d hosts into /%d", need, bits)
+}
+
+func printUsage(report usageReport) {
+	total := report.hostCount()
+	free := new(big.Int).Sub(total, report.Used)
+
+	fmt.Println("Network:", report.Prefix)
+	fmt.Printf("Usable Hosts: %s (%s - %s)\n", formatCount(total), report.Hosts.First, report.Hosts.Last)
+	fmt.Println("Used:", formatCount(report.Used))
+	fmt.Println("Free:", formatCount(free))
+	fmt.Println("Utilization:", formatPercent(report.Used, total))
+	if len(report.Free) > 0 {
+		fmt.Println("Free Ranges:")
+		for _, r := range report.Free {
+			if r.First == r.Last {
+				fmt.Printf("  %s (1)\n", r.First)
+			} else {
+				fmt.Printf("  %s - %s (%s)\n", r.First, r.Last, formatCount(r.size()))
+			}
+		}
+	}
+}
+
+func runFit(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		logger.PrintValidationError("expected a host count or a CIDR")
+		if err := cmd.Help(); err != nil {
+			logger.PrintErrorWithMessage("failed to show help", err)
+		}
+		return
+	}
+
+	if hosts, err := strconv.ParseInt(args[0], 10, 64); err == nil {
+		reserved, _ := cmd.Flags().GetInt("reserved")
+		printFit(hosts, int64(reserved))
+		return
+	}
+
+	usedArgs, _ := cmd.Flags().GetStringSlice("used")
+	file, _ := cmd.Flags().GetString("file")
+	includeAll, _ := cmd.Flags().GetBool("all")
+
+	prefix, err := parsePrefix(args[0])
+	if err != nil {
+		logger.PrintErrorWithMessage("failed to parse CIDR address", err)
+		if err := cmd.Help(); err != nil {
+			logger.PrintErrorWithMessage("failed to show help", err)
+		}
+		return
+	}
+	first, last, err := hostRange(prefix.String(), includeAll)
+	if err != nil {
+		logger.PrintErrorWithMessage("failed to parse CIDR address", err)
+		return
+	}
+
+	used, err := collectPrefixes(usedArgs, file)
+	if err != nil {
+		logger.PrintErrorWithMessage("failed to read used addresses", err)
+		return
+	}
+	prefixes := make([]netip.Prefix, len(used))
+	for i, lp := range used {
+		prefixes[i] = lp.Prefix
+	}
+
+	report := computeUsage(prefix, addrRange{First: first, Last: last}, reservedHoles(prefix, includeAll), prefixes)
+	for _, p := range report.Outside {
+		logger.Warnf("%s is not inside %s, ignored", p, prefix)
+	}
+	for _, p := range report.Special {
+		logger.Warnf("%s includes addresses that are not usable hosts of %s", p, prefix)
+	}
+	printUsage(report)
+
+	logger.Infof("Successfully reported utilization of %s", prefix)
+}
@@ -0,0 +1,119 @@
+//go:build unit
+
+/*
+Copyright © 2024-2025 Auska <luodan0709@live.cn>
+
+*/
+
+package cmd
+
+import (
+	"math"
+	"net/netip"
+	"reflect"
+	"testing"
+)
+
+func TestFitPrefixLength(t *testing.T) {
+	tests := []struct {
+		name     string
+		hosts    int64
+		reserved int64
+		expected int
+		wantErr  bool
+	}{
+		{"Single host", 1, 0, 32, false},
+		{"Point-to-point", 2, 0, 31, false},
+		{"Three hosts", 3, 0, 29, false},
+		{"Full /24", 254, 0, 24, false},
+		{"One over /24", 255, 0, 23, false},
+		{"300 hosts", 300, 0, 23, false},
+		{"Reserved pushes to larger prefix", 60, 3, 25, false},
+		{"Reserved fits", 50, 3, 26, false},
+		{"Zero hosts", 0, 0, 0, true},
+		{"Negative reserved", 10, -1, 0, true},
+		{"Too many hosts", 1 << 33, 0, 0, true},
+		{"Overflowing sum", math.MaxInt64, 1, 0, true},
+		{"Overflowing reserved", 1, math.MaxInt64, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := fitPrefixLength(tt.hosts, tt.reserved)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("fitPrefixLength() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && result != tt.expected {
+				t.Errorf("fitPrefixLength() = /%d, want /%d", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestComputeUsage(t *testing.T) {
+	prefix := netip.MustParsePrefix("10.0.0.0/24")
+	hosts := parseRanges(t, "10.0.0.1-10.0.0.254")[0]
+	used := []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.1/32"),
+		netip.MustParsePrefix("10.0.0.8/29"),
+		netip.MustParsePrefix("10.0.0.12/32"), // 重复，只计一次
+		netip.MustParsePrefix("10.0.0.255/32"),
+		netip.MustParsePrefix("192.168.0.1/32"),
+	}
+
+	report := computeUsage(prefix, hosts, nil, used)
+	if report.Used.Int64() != 9 {
+		t.Errorf("Used = %s, want 9", report.Used)
+	}
+	wantFree := []string{"10.0.0.2-10.0.0.7", "10.0.0.16-10.0.0.254"}
+	if got := formatRanges(report.Free); !reflect.DeepEqual(got, wantFree) {
+		t.Errorf("Free = %v, want %v", got, wantFree)
+	}
+	if len(report.Outside) != 1 || report.Outside[0].String() != "192.168.0.1/32" {
+		t.Errorf("Outside = %v, want [192.168.0.1/32]", report.Outside)
+	}
+	if len(report.Special) != 1 || report.Special[0].String() != "10.0.0.255/32" {
+		t.Errorf("Special = %v, want [10.0.0.255/32]", report.Special)
+	}
+}
+
+func TestComputeUsageFull(t *testing.T) {
+	prefix := netip.MustParsePrefix("10.0.0.0/30")
+	hosts := parseRanges(t, "10.0.0.1-10.0.0.2")[0]
+
+	report := computeUsage(prefix, hosts, nil, []netip.Prefix{prefix})
+	if report.Used.Int64() != 2 || len(report.Free) != 0 {
+		t.Errorf("computeUsage() used = %s, free = %v, want 2 used and no free ranges", report.Used, formatRanges(report.Free))
+	}
+	if len(report.Special) != 1 {
+		t.Errorf("Special = %v, want the whole /30 reported", report.Special)
+	}
+}
+
+func TestComputeUsageReservedAnycast(t *testing.T) {
+	prefix := netip.MustParsePrefix("2001:db8::/64")
+	first, last, err := hostRange(prefix.String(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	used := []netip.Prefix{
+		netip.MustParsePrefix("2001:db8::1/128"),
+		netip.MustParsePrefix("2001:db8::fdff:ffff:ffff:ff81/128"),
+	}
+
+	report := computeUsage(prefix, addrRange{First: first, Last: last}, reservedHoles(prefix, false), used)
+	want, _ := calculateCIDRInfo(prefix.String())
+	if report.hostCount().Cmp(want.TotalHosts) != 0 {
+		t.Errorf("hostCount() = %s, want %s", report.hostCount(), want.TotalHosts)
+	}
+	if report.Used.Int64() != 1 {
+		t.Errorf("Used = %s, want 1", report.Used)
+	}
+	if len(report.Special) != 1 || report.Special[0] != used[1] {
+		t.Errorf("Special = %v, want [%v]", report.Special, used[1])
+	}
+	wantFree := []string{"2001:db8::2-2001:db8::fdff:ffff:ffff:ff7f", "2001:db8:0:0:fe00::-2001:db8::ffff:ffff:ffff:ffff"}
+	if got := formatRanges(report.Free); !reflect.DeepEqual(got, wantFree) {
+		t.Errorf("Free = %v, want %v", got, wantFree)
+	}
+}
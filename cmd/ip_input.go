@@ -0,0 +1,155 @@
+/*
+Copyright © 2024-2025 Auska <luodan0709@live.cn>
+
+*/
+
+package cmd
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/netip"
+	"strings"
+)
+
+// ipInput ip 命令的一条输入，能表示为单个网段时 CIDR 非空，否则为地址范围
+type ipInput struct {
+	Text  string
+	Label string
+	CIDR  string // 保留主机位，如 192.168.1.130/25
+	Range addrRange
+}
+
+// String 返回 "原始输入 (标签)" 形式的描述
+func (in ipInput) String() string {
+	if in.Label == "" {
+		return in.Text
+	}
+	return fmt.Sprintf("%s (%s)", in.Text, in.Label)
+}
+
+// isMaskArg 判断参数是否像掩码：前缀长度、十六进制掩码，或连续的点分子网掩码/反掩码
+// 用于区分 "192.168.1.1 255.255.255.0" 与两个独立的地址，如 "8.8.8.8 1.1.1.1"
+func isMaskArg(s string) bool {
+	if isDecimal(s) || strings.HasPrefix(strings.ToLower(s), "0x") {
+		return true
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil || !addr.Is4() {
+		return false
+	}
+	b := addr.As4()
+	value := binary.BigEndian.Uint32(b[:])
+	_, mask := contiguousMaskLength(value)
+	_, wildcard := contiguousMaskLength(^value)
+	return mask || wildcard
+}
+
+// isMaskPair 判断两个字段是否为 "地址 掩码" 的写法
+func isMaskPair(fields []string) bool {
+	return len(fields) == 2 && !strings.ContainsAny(fields[0], "/*-") && isMaskArg(fields[1])
+}
+
+// splitIPArgs 将命令行参数拆分为输入，只有两个参数且第二个像掩码时按 "地址 掩码" 处理
+func splitIPArgs(args []string) [][]string {
+	if isMaskPair(args) {
+		return [][]string{args}
+	}
+	groups := make([][]string, len(args))
+	for i, arg := range args {
+		groups[i] = []string{arg}
+	}
+	return groups
+}
+
+// parseIPInput 解析一条输入：CIDR（任意掩码写法）、裸 IP、10.0.0.* 通配、
+// 10.0.0.1-50 或 起始-结束 范围，以及 "地址 掩码" 两个字段的写法
+func parseIPInput(fields []string) (ipInput, error) {
+	in := ipInput{Text: strings.Join(fields, " ")}
+	s := strings.TrimSpace(fields[0])
+
+	switch {
+	case len(fields) > 1:
+		cidr, err := normalizeCIDRInput(fields)
+		if err != nil {
+			return ipInput{}, err
+		}
+		in.CIDR = cidr
+	case strings.Contains(s, "*"):
+		prefix, err := parseIPv4Glob(s)
+		if err != nil {
+			return ipInput{}, err
+		}
+		in.CIDR = prefix.String()
+	case strings.Contains(s, "-"):
+		r, err := parseAddrRange(s)
+		if err != nil {
+			return ipInput{}, err
+		}
+		if prefixes := rangeToPrefixes(r); len(prefixes) == 1 {
+			in.CIDR = prefixes[0].String()
+		} else {
+			in.Range = r
+		}
+	case strings.Contains(s, "/"):
+		cidr, err := normalizeCIDRInput([]string{s})
+		if err != nil {
+			return ipInput{}, err
+		}
+		in.CIDR = cidr
+	default:
+		addr, err := parseAddr(s)
+		if err != nil {
+			return ipInput{}, err
+		}
+		in.CIDR = fmt.Sprintf("%s/%d", addr, addr.BitLen())
+	}
+
+	return in, nil
+}
+
+// parseIPInputList 读取输入列表，每行一条输入，其余列作为标签
+func parseIPInputList(r io.Reader) ([]ipInput, error) {
+	var inputs []ipInput
+	err := scanList(r, "input list", func(fields []string) error {
+		n := 1
+		if len(fields) >= 2 && isMaskPair(fields[:2]) {
+			n = 2
+		}
+		in, err := parseIPInput(fields[:n])
+		if err != nil {
+			return err
+		}
+		in.Label = strings.Join(fields[n:], " ")
+		inputs = append(inputs, in)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return inputs, nil
+}
+
+// readIPInputFile 从文件读取输入列表，路径为 "-" 时读取标准输入
+func readIPInputFile(path string) ([]ipInput, error) {
+	var inputs []ipInput
+	err := readListFile(path, func(r io.Reader) error {
+		var err error
+		inputs, err = parseIPInputList(r)
+		return err
+	})
+	return inputs, err
+}
+
+// printRangeInfo 输出无法表示为单个网段的地址范围及覆盖它的最少 CIDR
+func printRangeInfo(r addrRange) {
+	prefixes := rangeToPrefixes(r)
+
+	fmt.Println("Address Range:", r.First, "-", r.Last)
+	fmt.Println("Total Addresses:", formatCount(r.size()))
+	fmt.Println("CIDR Blocks:", len(prefixes))
+	for _, prefix := range prefixes {
+		fmt.Println(" ", prefix)
+	}
+}
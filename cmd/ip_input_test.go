@@ -0,0 +1,115 @@
+//go:build unit
+
+/*
+Copyright © 2024-2025 Auska <luodan0709@live.cn>
+
+*/
+
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSplitIPArgs(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		expected int
+	}{
+		{"Address and dotted mask", []string{"192.168.1.1", "255.255.255.0"}, 1},
+		{"Address and wildcard mask", []string{"192.168.1.0", "0.0.0.255"}, 1},
+		{"Address and prefix length", []string{"10.0.0.0", "8"}, 1},
+		{"Address and hex mask", []string{"10.0.0.0", "0xff000000"}, 1},
+		{"Non-contiguous mask is an address", []string{"192.168.1.1", "255.0.255.0"}, 2},
+		{"Two addresses", []string{"10.0.0.1", "10.0.0.2"}, 2},
+		{"Addresses made of mask bytes", []string{"8.8.8.8", "1.1.1.1"}, 2},
+		{"Loopback is not a mask", []string{"9.9.9.9", "127.0.0.1"}, 2},
+		{"CIDR and address", []string{"10.0.0.0/8", "255.0.0.0"}, 2},
+		{"Three inputs", []string{"10.0.0.1", "255.255.255.0", "10.0.0.2"}, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := splitIPArgs(tt.args); len(result) != tt.expected {
+				t.Errorf("splitIPArgs() = %v, want %d inputs", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseIPInput(t *testing.T) {
+	tests := []struct {
+		name      string
+		fields    []string
+		cidr      string
+		rangeText string
+		wantErr   bool
+	}{
+		{name: "CIDR keeps host bits", fields: []string{"192.168.1.130/25"}, cidr: "192.168.1.130/25"},
+		{name: "Dotted mask", fields: []string{"192.168.1.1/255.255.255.0"}, cidr: "192.168.1.1/24"},
+		{name: "Mask pair", fields: []string{"192.168.1.1", "255.255.255.0"}, cidr: "192.168.1.1/24"},
+		{name: "Bare IPv4", fields: []string{"192.0.2.7"}, cidr: "192.0.2.7/32"},
+		{name: "Bare IPv6", fields: []string{"2001:db8::1"}, cidr: "2001:db8::1/128"},
+		{name: "Glob", fields: []string{"10.0.0.*"}, cidr: "10.0.0.0/24"},
+		{name: "Range matching one prefix", fields: []string{"10.0.0.0-255"}, cidr: "10.0.0.0/24"},
+		{name: "Short range", fields: []string{"10.0.0.1-50"}, rangeText: "10.0.0.1-10.0.0.50"},
+		{name: "Full range", fields: []string{"10.0.0.1-10.0.3.7"}, rangeText: "10.0.0.1-10.0.3.7"},
+		{name: "Invalid address", fields: []string{"10.0.0.256"}, wantErr: true},
+		{name: "Invalid glob", fields: []string{"10.*.0.0"}, wantErr: true},
+		{name: "Reversed range", fields: []string{"10.0.0.50-1"}, wantErr: true},
+		{name: "Non-contiguous mask", fields: []string{"192.168.1.1", "255.0.255.0"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := parseIPInput(tt.fields)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseIPInput() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if result.CIDR != tt.cidr {
+				t.Errorf("parseIPInput() CIDR = %q, want %q", result.CIDR, tt.cidr)
+			}
+			if tt.rangeText != "" {
+				if got := result.Range.First.String() + "-" + result.Range.Last.String(); got != tt.rangeText {
+					t.Errorf("parseIPInput() range = %s, want %s", got, tt.rangeText)
+				}
+			}
+		})
+	}
+}
+
+func TestParseIPInputList(t *testing.T) {
+	input := `address,site
+# comment
+10.0.0.5,office
+192.168.1.1 255.255.255.0 lab
+
+10.0.0.1-50
+`
+	inputs, err := parseIPInputList(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("parseIPInputList() error = %v", err)
+	}
+
+	expected := []string{"10.0.0.5 (office)", "192.168.1.1 255.255.255.0 (lab)", "10.0.0.1-50"}
+	if len(inputs) != len(expected) {
+		t.Fatalf("parseIPInputList() returned %d inputs, want %d", len(inputs), len(expected))
+	}
+	for i, in := range inputs {
+		if in.String() != expected[i] {
+			t.Errorf("input %d = %q, want %q", i, in.String(), expected[i])
+		}
+	}
+	if inputs[1].CIDR != "192.168.1.1/24" {
+		t.Errorf("mask pair CIDR = %s, want 192.168.1.1/24", inputs[1].CIDR)
+	}
+
+	if _, err := parseIPInputList(strings.NewReader("10.0.0.1\nbogus\n")); err == nil {
+		t.Error("parseIPInputList() expected error for invalid line")
+	}
+}
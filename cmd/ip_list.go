@@ -0,0 +1,251 @@
+/*
+Copyright © 2024-2025 Auska <luodan0709@live.cn>
+
+*/
+
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"net/netip"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/spf13/cobra"
+	"macconv/pkg/errors"
+	"macconv/pkg/logger"
+)
+
+const defaultListLimit = 1 << 20
+
+var ipListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Enumerate host addresses in a CIDR",
+	Long: `
+Print every usable host address of a CIDR, one per line. Network and
+broadcast addresses (IPv4) or the Subnet-Router and reserved anycast
+addresses (IPv6) are skipped unless --all is given; /31, /32, /127 and /128
+follow the same rules as "macconv ip". For example:
+
+	macconv ip list 10.0.0.0/22
+	macconv ip list 10.0.0.0/22 --stride 4
+	macconv ip list 10.0.0.0/8 --random 100 --seed 42
+	macconv ip list 2001:db8::/120 --all
+
+Output is streamed. Ranges with more addresses than --limit are refused
+unless --random is used or the limit is raised (0 disables the limit).`,
+	Run: listHosts,
+}
+
+func init() {
+	ipCmd.AddCommand(ipListCmd)
+	ipListCmd.Flags().Int64("stride", 1, "Print every Nth address")
+	ipListCmd.Flags().Bool("all", false, "Include network, broadcast and anycast addresses")
+	ipListCmd.Flags().Int("random", 0, "Print N randomly sampled addresses instead of the full list")
+	ipListCmd.Flags().Int64("seed", 0, "Random seed for --random (default: current time)")
+	ipListCmd.Flags().Int64("limit", defaultListLimit, "Maximum number of addresses to print, 0 for no limit")
+}
+
+// hostRange 返回网段中可枚举的地址范围，includeAll 时返回整个网段
+// 位于子网末尾的 RFC 2526 保留任播地址（/65 至 /120）不在范围内，/64 的保留块在范围中间，见 reservedHoles
+func hostRange(cidr string, includeAll bool) (netip.Addr, netip.Addr, error) {
+	if includeAll {
+		prefix, err := parsePrefix(cidr)
+		if err != nil {
+			return netip.Addr{}, netip.Addr{}, err
+		}
+		return prefix.Addr(), prefixLastAddr(prefix), nil
+	}
+
+	info, err := calculateCIDRInfo(cidr)
+	if err != nil {
+		return netip.Addr{}, netip.Addr{}, err
+	}
+	first, err := netip.ParseAddr(info.FirstIP)
+	if err != nil {
+		return netip.Addr{}, netip.Addr{}, errors.Wrap(errors.ParseError, "invalid first address", err)
+	}
+	last, err := netip.ParseAddr(info.LastIP)
+	if err != nil {
+		return netip.Addr{}, netip.Addr{}, errors.Wrap(errors.ParseError, "invalid last address", err)
+	}
+	if info.ReservedAnycastLast == info.LastIP {
+		if reservedFirst, err := netip.ParseAddr(info.ReservedAnycastFirst); err == nil {
+			last = reservedFirst.Prev()
+		}
+	}
+	return first.Unmap(), last.Unmap(), nil
+}
+
+// reservedHoles 返回 hostRange 范围内部不可用的地址，目前只有 /64 的 RFC 2526 保留任播地址
+func reservedHoles(prefix netip.Prefix, includeAll bool) []addrRange {
+	if includeAll || !prefix.Addr().Is6() || prefix.Bits() != 64 {
+		return nil
+	}
+	first, last := reservedAnycastRange(prefix.Masked())
+	return []addrRange{{First: first, Last: last}}
+}
+
+// inRanges 判断地址是否落在任一范围内
+func inRanges(addr netip.Addr, ranges []addrRange) bool {
+	for _, r := range ranges {
+		if r.First.Compare(addr) <= 0 && addr.Compare(r.Last) <= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// strideCount 返回 [first, last] 之间按 stride 步进的地址个数
+func strideCount(first, last netip.Addr, stride *big.Int) *big.Int {
+	span := new(big.Int).Sub(addrToInt(last), addrToInt(first))
+	if span.Sign() < 0 {
+		return new(big.Int)
+	}
+	span.Quo(span, stride)
+	return span.Add(span, big.NewInt(1))
+}
+
+// enumerateHosts 从 first 开始按 stride 步进依次回调，直到超过 last
+func enumerateHosts(first, last netip.Addr, stride *big.Int, fn func(netip.Addr)) {
+	addr := first
+	for addr.Compare(last) <= 0 {
+		fn(addr)
+		next, ok := addrAdd(addr, stride)
+		if !ok || next.Compare(addr) <= 0 {
+			return
+		}
+		addr = next
+	}
+}
+
+// sampleHosts 使用 Floyd 算法从 count 个位置中无重复地抽取 n 个，结果按地址升序排列
+// 只为抽中的位置分配内存，不会展开整个地址范围
+func sampleHosts(first netip.Addr, stride, count *big.Int, n int, rng *rand.Rand) []netip.Addr {
+	if count.Cmp(big.NewInt(int64(n))) <= 0 {
+		var all []netip.Addr
+		last, _ := addrAdd(first, new(big.Int).Mul(stride, new(big.Int).Sub(count, big.NewInt(1))))
+		if count.Sign() > 0 {
+			enumerateHosts(first, last, stride, func(addr netip.Addr) {
+				all = append(all, addr)
+			})
+		}
+		return all
+	}
+
+	addrs := make([]netip.Addr, 0, n)
+	for _, pos := range floydSample(count, n, rng) {
+		addr, _ := addrAdd(first, new(big.Int).Mul(pos, stride))
+		addrs = append(addrs, addr)
+	}
+	return addrs
+}
+
+// floydSample 使用 Floyd 算法从 [0, count) 中无重复地等概率抽取 n 个位置并升序返回，n 不能超过 count
+func floydSample(count *big.Int, n int, rng *rand.Rand) []*big.Int {
+	chosen := make(map[string]*big.Int, n)
+	j := new(big.Int).Sub(count, big.NewInt(int64(n)))
+	for i := 0; i < n; i++ {
+		t := new(big.Int).Rand(rng, new(big.Int).Add(j, big.NewInt(1)))
+		if _, ok := chosen[t.String()]; ok {
+			t = new(big.Int).Set(j)
+		}
+		chosen[t.String()] = t
+		j.Add(j, big.NewInt(1))
+	}
+
+	positions := make([]*big.Int, 0, len(chosen))
+	for _, pos := range chosen {
+		positions = append(positions, pos)
+	}
+	sort.Slice(positions, func(a, b int) bool {
+		return positions[a].Cmp(positions[b]) < 0
+	})
+	return positions
+}
+
+func listHosts(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		logger.PrintValidationError("missing CIDR address argument")
+		if err := cmd.Help(); err != nil {
+			logger.PrintErrorWithMessage("failed to show help", err)
+		}
+		return
+	}
+
+	strideValue, _ := cmd.Flags().GetInt64("stride")
+	includeAll, _ := cmd.Flags().GetBool("all")
+	randomCount, _ := cmd.Flags().GetInt("random")
+	seed, _ := cmd.Flags().GetInt64("seed")
+	limit, _ := cmd.Flags().GetInt64("limit")
+
+	if strideValue < 1 {
+		logger.PrintValidationError("stride must be at least 1")
+		return
+	}
+	if randomCount < 0 {
+		logger.PrintValidationError("random count must not be negative")
+		return
+	}
+
+	first, last, err := hostRange(args[0], includeAll)
+	if err != nil {
+		logger.PrintErrorWithMessage("failed to parse CIDR address", err)
+		if err := cmd.Help(); err != nil {
+			logger.PrintErrorWithMessage("failed to show help", err)
+		}
+		return
+	}
+	var holes []addrRange
+	if prefix, err := parsePrefix(args[0]); err == nil {
+		holes = reservedHoles(prefix, includeAll)
+	}
+
+	stride := big.NewInt(strideValue)
+	count := strideCount(first, last, stride)
+	logger.Debugf("Range %s - %s contains %s addresses at stride %d", first, last, count, strideValue)
+
+	out := bufio.NewWriter(os.Stdout)
+	defer func() {
+		if flushErr := out.Flush(); flushErr != nil {
+			logger.Debugf("Error flushing output: %v", flushErr)
+		}
+	}()
+
+	if randomCount > 0 {
+		if limit > 0 && int64(randomCount) > limit {
+			logger.PrintValidationError(fmt.Sprintf("random sample of %d exceeds --limit %d", randomCount, limit))
+			return
+		}
+		if !cmd.Flags().Changed("seed") {
+			seed = time.Now().UnixNano()
+		}
+		logger.Debugf("Sampling %d addresses with seed %d", randomCount, seed)
+		rng := rand.New(rand.NewSource(seed)) // #nosec G404 -- sampling, not security sensitive
+		for _, addr := range sampleHosts(first, stride, count, randomCount, rng) {
+			if inRanges(addr, holes) {
+				continue
+			}
+			fmt.Fprintln(out, addr)
+		}
+		return
+	}
+
+	if limit > 0 && count.Cmp(big.NewInt(limit)) > 0 {
+		logger.PrintValidationError(fmt.Sprintf(
+			"%s contains %s addresses, more than --limit %d; use --random, --stride or raise --limit", args[0], count, limit))
+		return
+	}
+
+	enumerateHosts(first, last, stride, func(addr netip.Addr) {
+		if !inRanges(addr, holes) {
+			fmt.Fprintln(out, addr)
+		}
+	})
+
+	logger.Infof("Listed %s addresses from %s", count, args[0])
+}
@@ -0,0 +1,166 @@
+//go:build unit
+
+/*
+Copyright © 2024-2025 Auska <luodan0709@live.cn>
+
+*/
+
+package cmd
+
+import (
+	"math/big"
+	"math/rand"
+	"net/netip"
+	"testing"
+)
+
+func TestHostRange(t *testing.T) {
+	tests := []struct {
+		name       string
+		cidr       string
+		includeAll bool
+		wantFirst  string
+		wantLast   string
+	}{
+		{"IPv4 /30 usable", "10.0.0.0/30", false, "10.0.0.1", "10.0.0.2"},
+		{"IPv4 /30 all", "10.0.0.0/30", true, "10.0.0.0", "10.0.0.3"},
+		{"IPv4 /31", "10.0.0.0/31", false, "10.0.0.0", "10.0.0.1"},
+		{"IPv4 /32", "10.0.0.7/32", false, "10.0.0.7", "10.0.0.7"},
+		{"IPv6 /120 usable", "2001:db8::/120", false, "2001:db8::1", "2001:db8::7f"},
+		{"IPv6 /120 all", "2001:db8::/120", true, "2001:db8::", "2001:db8::ff"},
+		{"IPv6 /64 usable", "2001:db8::/64", false, "2001:db8::1", "2001:db8::ffff:ffff:ffff:ffff"},
+		{"IPv6 /127", "2001:db8::/127", false, "2001:db8::", "2001:db8::1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first, last, err := hostRange(tt.cidr, tt.includeAll)
+			if err != nil {
+				t.Fatalf("hostRange() error = %v", err)
+			}
+			if first.String() != tt.wantFirst || last.String() != tt.wantLast {
+				t.Errorf("hostRange() = %v - %v, want %v - %v", first, last, tt.wantFirst, tt.wantLast)
+			}
+		})
+	}
+
+	if _, _, err := hostRange("10.0.0.0/33", false); err == nil {
+		t.Error("hostRange() expected error for invalid CIDR")
+	}
+}
+
+func TestEnumerateHosts(t *testing.T) {
+	tests := []struct {
+		name     string
+		first    string
+		last     string
+		stride   int64
+		expected []string
+	}{
+		{
+			name:     "Every address",
+			first:    "192.168.1.254",
+			last:     "192.168.2.1",
+			stride:   1,
+			expected: []string{"192.168.1.254", "192.168.1.255", "192.168.2.0", "192.168.2.1"},
+		},
+		{
+			name:     "Stride",
+			first:    "10.0.0.1",
+			last:     "10.0.0.10",
+			stride:   4,
+			expected: []string{"10.0.0.1", "10.0.0.5", "10.0.0.9"},
+		},
+		{
+			name:     "End of address space",
+			first:    "255.255.255.254",
+			last:     "255.255.255.255",
+			stride:   1,
+			expected: []string{"255.255.255.254", "255.255.255.255"},
+		},
+		{
+			name:     "IPv6",
+			first:    "2001:db8::fffe",
+			last:     "2001:db8::1:0",
+			stride:   1,
+			expected: []string{"2001:db8::fffe", "2001:db8::ffff", "2001:db8::1:0"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			enumerateHosts(netip.MustParseAddr(tt.first), netip.MustParseAddr(tt.last), big.NewInt(tt.stride), func(addr netip.Addr) {
+				got = append(got, addr.String())
+			})
+			if len(got) != len(tt.expected) {
+				t.Fatalf("enumerateHosts() = %v, want %v", got, tt.expected)
+			}
+			for i := range got {
+				if got[i] != tt.expected[i] {
+					t.Errorf("enumerateHosts()[%d] = %v, want %v", i, got[i], tt.expected[i])
+				}
+			}
+
+			count := strideCount(netip.MustParseAddr(tt.first), netip.MustParseAddr(tt.last), big.NewInt(tt.stride))
+			if count.Int64() != int64(len(tt.expected)) {
+				t.Errorf("strideCount() = %v, want %d", count, len(tt.expected))
+			}
+		})
+	}
+}
+
+func TestSampleHosts(t *testing.T) {
+	first := netip.MustParseAddr("10.0.0.0")
+	prefix := netip.MustParsePrefix("10.0.0.0/8")
+	count := prefixSize(prefix)
+
+	rng := rand.New(rand.NewSource(1))
+	addrs := sampleHosts(first, big.NewInt(1), count, 50, rng)
+	if len(addrs) != 50 {
+		t.Fatalf("sampleHosts() returned %d addresses, want 50", len(addrs))
+	}
+	for i, addr := range addrs {
+		if !prefix.Contains(addr) {
+			t.Errorf("sampleHosts() address %v outside %v", addr, prefix)
+		}
+		if i > 0 && addrs[i-1].Compare(addr) >= 0 {
+			t.Errorf("sampleHosts() not strictly ascending at %d: %v >= %v", i, addrs[i-1], addr)
+		}
+	}
+
+	again := sampleHosts(first, big.NewInt(1), count, 50, rand.New(rand.NewSource(1)))
+	for i := range addrs {
+		if addrs[i] != again[i] {
+			t.Fatalf("sampleHosts() not reproducible with the same seed")
+		}
+	}
+
+	small := sampleHosts(first, big.NewInt(2), big.NewInt(3), 10, rng)
+	if len(small) != 3 || small[2].String() != "10.0.0.4" {
+		t.Errorf("sampleHosts() with n >= count = %v, want 3 addresses ending at 10.0.0.4", small)
+	}
+
+	v6 := sampleHosts(netip.MustParseAddr("2001:db8::"), big.NewInt(1), powerOfTwo(96), 5, rng)
+	if len(v6) != 5 {
+		t.Errorf("sampleHosts() IPv6 returned %d addresses, want 5", len(v6))
+	}
+}
+
+func TestHostRangeMatchesHostCount(t *testing.T) {
+	for _, cidr := range []string{"2001:db8::/120", "2001:db8::/100", "2001:db8::/64", "10.0.0.0/24"} {
+		prefix := netip.MustParsePrefix(cidr)
+		first, last, err := hostRange(cidr, false)
+		if err != nil {
+			t.Fatalf("hostRange(%s) error = %v", cidr, err)
+		}
+		count := addrRange{First: first, Last: last}.size()
+		for _, h := range reservedHoles(prefix, false) {
+			count.Sub(count, h.size())
+		}
+		info, _ := calculateCIDRInfo(cidr)
+		if count.Cmp(info.TotalHosts) != 0 {
+			t.Errorf("%s: usable range holds %s addresses, TotalHosts = %s", cidr, count, info.TotalHosts)
+		}
+	}
+}
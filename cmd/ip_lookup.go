@@ -0,0 +1,141 @@
+/*
+Copyright © 2024-2025 Auska <luodan0709@live.cn>
+
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"net/netip"
+	"sort"
+
+	"github.com/spf13/cobra"
+	"macconv/pkg/logger"
+)
+
+var ipLookupCmd = &cobra.Command{
+	Use:     "lookup",
+	Aliases: []string{"contains"},
+	Short:   "Find which prefixes contain an IP address",
+	Long: `
+Report every prefix that contains each IP address and the longest match.
+Prefixes are given with --cidr or read from a file (one per line, optional
+label columns, CSV exports from IPAM tools are accepted). For example:
+
+	macconv ip lookup 10.1.2.3 --cidr 10.0.0.0/8 --cidr 10.1.0.0/16
+	macconv ip lookup 10.1.2.3 192.168.7.9 --file ipam.csv
+	macconv ip lookup 10.1.2.3 --file ipam.csv --longest`,
+	Run: lookupIPAddress,
+}
+
+func init() {
+	ipCmd.AddCommand(ipLookupCmd)
+	ipLookupCmd.Flags().StringSliceP("cidr", "c", nil, "Prefix to match against (repeatable)")
+	ipLookupCmd.Flags().StringP("file", "f", "", "Read prefixes from file (\"-\" for stdin)")
+	ipLookupCmd.Flags().Bool("longest", false, "Only print the longest match for each IP")
+}
+
+// lookupResult 单个 IP 的查询结果，Matches 按前缀长度从短到长排列
+type lookupResult struct {
+	Addr    netip.Addr
+	Matches []labeledPrefix
+}
+
+// Longest 返回最长匹配，没有匹配时 ok 为 false
+func (r lookupResult) Longest() (labeledPrefix, bool) {
+	if len(r.Matches) == 0 {
+		return labeledPrefix{}, false
+	}
+	return r.Matches[len(r.Matches)-1], true
+}
+
+// lookupPrefixes 查找包含 addr 的所有网段
+func lookupPrefixes(addr netip.Addr, prefixes []labeledPrefix) lookupResult {
+	result := lookupResult{Addr: addr}
+	for _, lp := range prefixes {
+		if lp.Prefix.Contains(addr) {
+			result.Matches = append(result.Matches, lp)
+		}
+	}
+
+	sort.SliceStable(result.Matches, func(i, j int) bool {
+		return result.Matches[i].Prefix.Bits() < result.Matches[j].Prefix.Bits()
+	})
+
+	return result
+}
+
+func lookupIPAddress(cmd *cobra.Command, args []string) {
+	if len(args) == 0 {
+		logger.PrintValidationError("missing IP address argument")
+		if err := cmd.Help(); err != nil {
+			logger.PrintErrorWithMessage("failed to show help", err)
+		}
+		return
+	}
+
+	cidrs, _ := cmd.Flags().GetStringSlice("cidr")
+	file, _ := cmd.Flags().GetString("file")
+	longestOnly, _ := cmd.Flags().GetBool("longest")
+
+	prefixes, err := collectPrefixes(cidrs, file)
+	if err != nil {
+		logger.PrintErrorWithMessage("failed to load prefixes", err)
+		return
+	}
+	if len(prefixes) == 0 {
+		logger.PrintValidationError("no prefixes given, use --cidr or --file")
+		if err := cmd.Help(); err != nil {
+			logger.PrintErrorWithMessage("failed to show help", err)
+		}
+		return
+	}
+	logger.Debugf("Loaded %d prefixes for lookup", len(prefixes))
+
+	for i, arg := range args {
+		addr, err := parseAddr(arg)
+		if err != nil {
+			logger.PrintErrorWithMessage("failed to parse IP address", err)
+			continue
+		}
+
+		result := lookupPrefixes(addr, prefixes)
+		if longestOnly {
+			printLongestMatch(result)
+			continue
+		}
+
+		if i > 0 {
+			fmt.Println()
+		}
+		printLookupResult(result)
+	}
+}
+
+func printLongestMatch(result lookupResult) {
+	best, ok := result.Longest()
+	if !ok {
+		fmt.Printf("%s\t-\n", result.Addr)
+		return
+	}
+	fmt.Printf("%s\t%s\t%s\n", result.Addr, best.Prefix, best.Label)
+}
+
+func printLookupResult(result lookupResult) {
+	fmt.Println("IP Address:", result.Addr)
+	fmt.Println("Matches:", len(result.Matches))
+	for i, lp := range result.Matches {
+		marker := " "
+		if i == len(result.Matches)-1 {
+			marker = "*"
+		}
+		fmt.Printf("  %s %-20s %s\n", marker, lp.Prefix, lp.Label)
+	}
+
+	if best, ok := result.Longest(); ok {
+		fmt.Println("Longest Match:", best)
+	} else {
+		fmt.Println("Longest Match: none")
+	}
+}
@@ -0,0 +1,104 @@
+//go:build unit
+
+/*
+Copyright © 2024-2025 Auska <luodan0709@live.cn>
+
+*/
+
+package cmd
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestLookupPrefixes(t *testing.T) {
+	prefixes := []labeledPrefix{
+		{Prefix: netip.MustParsePrefix("10.1.0.0/16"), Label: "site-a"},
+		{Prefix: netip.MustParsePrefix("10.0.0.0/8"), Label: "corp"},
+		{Prefix: netip.MustParsePrefix("10.1.2.0/24"), Label: "vlan-12"},
+		{Prefix: netip.MustParsePrefix("192.168.0.0/16"), Label: "lab"},
+		{Prefix: netip.MustParsePrefix("2001:db8::/32"), Label: "v6"},
+	}
+
+	tests := []struct {
+		name        string
+		addr        string
+		wantMatches int
+		wantLongest string
+	}{
+		{
+			name:        "Nested matches",
+			addr:        "10.1.2.3",
+			wantMatches: 3,
+			wantLongest: "10.1.2.0/24",
+		},
+		{
+			name:        "Single match",
+			addr:        "10.9.9.9",
+			wantMatches: 1,
+			wantLongest: "10.0.0.0/8",
+		},
+		{
+			name:        "IPv6 match",
+			addr:        "2001:db8::1",
+			wantMatches: 1,
+			wantLongest: "2001:db8::/32",
+		},
+		{
+			name:        "No match",
+			addr:        "172.16.0.1",
+			wantMatches: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := lookupPrefixes(netip.MustParseAddr(tt.addr), prefixes)
+			if len(result.Matches) != tt.wantMatches {
+				t.Errorf("lookupPrefixes() matches = %d, want %d", len(result.Matches), tt.wantMatches)
+			}
+			best, ok := result.Longest()
+			if ok != (tt.wantLongest != "") {
+				t.Fatalf("Longest() ok = %v, want %v", ok, tt.wantLongest != "")
+			}
+			if ok && best.Prefix.String() != tt.wantLongest {
+				t.Errorf("Longest() = %v, want %v", best.Prefix, tt.wantLongest)
+			}
+		})
+	}
+}
+
+func TestLookupIPAddress(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		cidr []string
+	}{
+		{
+			name: "Valid lookup",
+			args: []string{"10.1.2.3"},
+			cidr: []string{"10.0.0.0/8"},
+		},
+		{
+			name: "Missing argument",
+			args: []string{},
+		},
+		{
+			name: "No prefixes",
+			args: []string{"10.1.2.3"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := &cobra.Command{}
+			cmd.Flags().StringSlice("cidr", tt.cidr, "")
+			cmd.Flags().String("file", "", "")
+			cmd.Flags().Bool("longest", false, "")
+			lookupIPAddress(cmd, tt.args)
+		})
+	}
+}
@@ -0,0 +1,144 @@
+/*
+Copyright © 2024-2025 Auska <luodan0709@live.cn>
+
+*/
+
+package cmd
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+	"net"
+	"strconv"
+	"strings"
+
+	"macconv/pkg/errors"
+	"macconv/pkg/logger"
+)
+
+// normalizeCIDRInput 将各种掩码写法统一转换为 a.b.c.d/len 形式
+// 支持：192.168.1.1/24、192.168.1.1/255.255.255.0、192.168.1.1 255.255.255.0、
+// 反掩码 0.0.0.255 以及 ifconfig 输出的十六进制掩码 0xffffff00
+func normalizeCIDRInput(args []string) (string, error) {
+	var addrPart, maskPart string
+
+	switch len(args) {
+	case 1:
+		idx := strings.Index(args[0], "/")
+		if idx == -1 {
+			return args[0], nil
+		}
+		addrPart, maskPart = args[0][:idx], args[0][idx+1:]
+	case 2:
+		if strings.Contains(args[0], "/") {
+			return "", errors.New(errors.ValidationError,
+				fmt.Sprintf("address %s already has a prefix length, unexpected mask %s", args[0], args[1]))
+		}
+		addrPart, maskPart = args[0], args[1]
+	default:
+		return "", errors.New(errors.ValidationError, "expected an address with a prefix length or mask")
+	}
+
+	maskPart = strings.TrimPrefix(maskPart, "/")
+	if isDecimal(maskPart) {
+		return addrPart + "/" + maskPart, nil
+	}
+
+	ip := net.ParseIP(addrPart)
+	if ip != nil && ip.To4() == nil {
+		return "", errors.New(errors.ValidationError, fmt.Sprintf("IPv6 address %s requires a prefix length, not a mask", addrPart))
+	}
+
+	ones, wildcard, err := parseIPv4Mask(maskPart)
+	if err != nil {
+		return "", err
+	}
+	if wildcard {
+		logger.Infof("Interpreting %s as a wildcard (inverse) mask, prefix length /%d", maskPart, ones)
+	}
+
+	return fmt.Sprintf("%s/%d", addrPart, ones), nil
+}
+
+// parseIPv4Mask 解析点分十进制或十六进制 IPv4 掩码，返回前缀长度
+// 子网掩码不连续时尝试按反掩码解释，两者都不连续则返回说明原因的错误
+func parseIPv4Mask(s string) (ones int, wildcard bool, err error) {
+	value, err := parseIPv4MaskValue(s)
+	if err != nil {
+		return 0, false, err
+	}
+
+	if n, ok := contiguousMaskLength(value); ok {
+		return n, false, nil
+	}
+	if n, ok := contiguousMaskLength(^value); ok {
+		return n, true, nil
+	}
+
+	return 0, false, errors.New(errors.ValidationError, describeNonContiguousMask(s, value))
+}
+
+// parseIPv4MaskValue 把掩码字符串转换为 32 位整数
+func parseIPv4MaskValue(s string) (uint32, error) {
+	lower := strings.ToLower(s)
+	if strings.HasPrefix(lower, "0x") {
+		hex := lower[2:]
+		if len(hex) != 8 {
+			return 0, errors.New(errors.ValidationError, fmt.Sprintf("hex mask %s must have exactly 8 digits", s))
+		}
+		value, err := strconv.ParseUint(hex, 16, 32)
+		if err != nil {
+			return 0, errors.Wrap(errors.ParseError, fmt.Sprintf("invalid hex mask %s", s), err)
+		}
+		return uint32(value), nil
+	}
+
+	ip := net.ParseIP(s)
+	if ip == nil || ip.To4() == nil {
+		return 0, errors.New(errors.ValidationError, fmt.Sprintf("invalid mask %s, expected prefix length, dotted or hex mask", s))
+	}
+	return binary.BigEndian.Uint32(ip.To4()), nil
+}
+
+// contiguousMaskLength 判断掩码是否由连续的 1 后接连续的 0 组成
+func contiguousMaskLength(value uint32) (int, bool) {
+	ones := bits.LeadingZeros32(^value)
+	if value<<ones != 0 {
+		return 0, false
+	}
+	return ones, true
+}
+
+// describeNonContiguousMask 说明不连续掩码中第一个 0 之后出现 1 的位置（位编号从 1 开始）
+func describeNonContiguousMask(s string, value uint32) string {
+	firstZero := bits.LeadingZeros32(^value)
+	rest := value << firstZero
+	oneAfterZero := firstZero + bits.LeadingZeros32(rest)
+
+	return fmt.Sprintf(
+		"mask %s (%s) is not contiguous: bit %d is 0 but bit %d is 1; "+
+			"a subnet mask must be ones followed by zeros, a wildcard mask zeros followed by ones",
+		s, formatMaskBinary(value), firstZero+1, oneAfterZero+1)
+}
+
+// formatMaskBinary 以点分二进制显示掩码
+func formatMaskBinary(value uint32) string {
+	parts := make([]string, 4)
+	for i := 0; i < 4; i++ {
+		parts[i] = fmt.Sprintf("%08b", byte(value>>(24-8*i)))
+	}
+	return strings.Join(parts, ".")
+}
+
+func isDecimal(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
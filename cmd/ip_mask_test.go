@@ -0,0 +1,146 @@
+//go:build unit
+
+/*
+Copyright © 2024-2025 Auska <luodan0709@live.cn>
+
+*/
+
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalizeCIDRInput(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		expected string
+		wantErr  string
+	}{
+		{
+			name:     "Prefix length",
+			args:     []string{"192.168.1.1/24"},
+			expected: "192.168.1.1/24",
+		},
+		{
+			name:     "Dotted mask after slash",
+			args:     []string{"192.168.1.1/255.255.255.0"},
+			expected: "192.168.1.1/24",
+		},
+		{
+			name:     "Dotted mask as second argument",
+			args:     []string{"192.168.1.1", "255.255.255.0"},
+			expected: "192.168.1.1/24",
+		},
+		{
+			name:     "Prefix length as second argument",
+			args:     []string{"10.0.0.0", "8"},
+			expected: "10.0.0.0/8",
+		},
+		{
+			name:     "Wildcard mask",
+			args:     []string{"192.168.1.0", "0.0.0.255"},
+			expected: "192.168.1.0/24",
+		},
+		{
+			name:     "Wildcard mask /30",
+			args:     []string{"10.0.0.0/0.0.0.3"},
+			expected: "10.0.0.0/30",
+		},
+		{
+			name:     "Hex mask",
+			args:     []string{"192.168.1.1", "0xffffff00"},
+			expected: "192.168.1.1/24",
+		},
+		{
+			name:     "Hex mask uppercase",
+			args:     []string{"172.16.0.1", "0xFFFFF000"},
+			expected: "172.16.0.1/20",
+		},
+		{
+			name:     "Zero mask",
+			args:     []string{"0.0.0.0", "0.0.0.0"},
+			expected: "0.0.0.0/0",
+		},
+		{
+			name:     "Host mask",
+			args:     []string{"10.1.1.1", "255.255.255.255"},
+			expected: "10.1.1.1/32",
+		},
+		{
+			name:     "IPv6 prefix",
+			args:     []string{"2001:db8::/32"},
+			expected: "2001:db8::/32",
+		},
+		{
+			name:     "Bare address passes through",
+			args:     []string{"192.168.1.0"},
+			expected: "192.168.1.0",
+		},
+		{
+			name:    "Non-contiguous mask",
+			args:    []string{"192.168.1.1", "255.0.255.0"},
+			wantErr: "bit 9 is 0 but bit 17 is 1",
+		},
+		{
+			name:    "Short hex mask",
+			args:    []string{"192.168.1.1", "0xffff"},
+			wantErr: "exactly 8 digits",
+		},
+		{
+			name:    "IPv6 with dotted mask",
+			args:    []string{"2001:db8::1", "255.255.255.0"},
+			wantErr: "requires a prefix length",
+		},
+		{
+			name:    "Prefix and mask together",
+			args:    []string{"10.0.0.0/8", "255.0.0.0"},
+			wantErr: "already has a prefix length",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := normalizeCIDRInput(tt.args)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("normalizeCIDRInput() error = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("normalizeCIDRInput() unexpected error = %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("normalizeCIDRInput() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestContiguousMaskLength(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    uint32
+		expected int
+		ok       bool
+	}{
+		{"All zeros", 0x00000000, 0, true},
+		{"All ones", 0xffffffff, 32, true},
+		{"/24", 0xffffff00, 24, true},
+		{"/17", 0xffff8000, 17, true},
+		{"Non-contiguous", 0xff00ff00, 0, false},
+		{"Wildcard", 0x000000ff, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ones, ok := contiguousMaskLength(tt.value)
+			if ok != tt.ok || ones != tt.expected {
+				t.Errorf("contiguousMaskLength(%#08x) = (%d, %v), want (%d, %v)", tt.value, ones, ok, tt.expected, tt.ok)
+			}
+		})
+	}
+}
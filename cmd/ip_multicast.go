@@ -0,0 +1,443 @@
+/*
+Copyright © 2024-2025 Auska <luodan0709@live.cn>
+
+*/
+
+package cmd
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"macconv/pkg/errors"
+	"macconv/pkg/logger"
+)
+
+var (
+	ipv6MulticastPrefix  = netip.MustParsePrefix("ff00::/8")
+	solicitedNodePrefix  = netip.MustParsePrefix("ff02::1:ff00:0/104")
+	ssmPrefixBasedPrefix = netip.MustParsePrefix("ff30::/12")
+)
+
+// 组播地址第二个字节高 4 位中的标志位（RFC 4291、RFC 3306、RFC 3956）
+const (
+	multicastFlagT byte = 0x1
+	multicastFlagP byte = 0x2
+	multicastFlagR byte = 0x4
+)
+
+// wellKnownMulticast 常见的固定组播组（RFC 4291 及 IANA IPv6 组播地址注册表）
+var wellKnownMulticast = map[netip.Addr]string{
+	netip.MustParseAddr("ff01::1"):   "all nodes",
+	netip.MustParseAddr("ff02::1"):   "all nodes",
+	netip.MustParseAddr("ff01::2"):   "all routers",
+	netip.MustParseAddr("ff02::2"):   "all routers",
+	netip.MustParseAddr("ff05::2"):   "all routers",
+	netip.MustParseAddr("ff02::5"):   "OSPFv3 all SPF routers",
+	netip.MustParseAddr("ff02::6"):   "OSPFv3 all DR routers",
+	netip.MustParseAddr("ff02::9"):   "RIPng routers",
+	netip.MustParseAddr("ff02::a"):   "EIGRP routers",
+	netip.MustParseAddr("ff02::d"):   "all PIM routers",
+	netip.MustParseAddr("ff02::12"):  "VRRP",
+	netip.MustParseAddr("ff02::16"):  "all MLDv2-capable routers",
+	netip.MustParseAddr("ff02::fb"):  "mDNS",
+	netip.MustParseAddr("ff02::101"): "NTP",
+	netip.MustParseAddr("ff02::1:2"): "all DHCP relay agents and servers",
+	netip.MustParseAddr("ff02::1:3"): "LLMNR",
+	netip.MustParseAddr("ff05::1:3"): "all DHCP servers",
+}
+
+var ipMulticastCmd = &cobra.Command{
+	Use:   "multicast",
+	Short: "IPv6 multicast address helpers",
+	Long: `
+Compute solicited-node addresses, decode IPv6 multicast flags and scope, and
+build or parse RFC 3306 unicast-prefix-based and RFC 3956 embedded-RP
+multicast addresses. For example:
+
+	macconv ip multicast solicited-node 2001:db8::1:2345:6789 fe80::1
+	macconv ip multicast decode ff02::1:ff45:6789 ff7e:140:2001:db8:beef:feed::1234
+	macconv ip multicast prefix 2001:db8:cafe:1::/64 --group 0x1234 --scope site
+	macconv ip multicast embedded-rp 2001:db8:beef:feed::1/64 --group 0x1234`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := cmd.Help(); err != nil {
+			logger.PrintErrorWithMessage("failed to show help", err)
+		}
+	},
+}
+
+var solicitedNodeCmd = &cobra.Command{
+	Use:   "solicited-node IPV6...",
+	Short: "RFC 4291 solicited-node multicast address of a unicast address",
+	Run:   runSolicitedNode,
+}
+
+var multicastDecodeCmd = &cobra.Command{
+	Use:   "decode IPV6...",
+	Short: "Decode flags, scope, group and embedded prefix or RP of a multicast address",
+	Run:   runMulticastDecode,
+}
+
+var multicastPrefixCmd = &cobra.Command{
+	Use:   "prefix PREFIX",
+	Short: "Build an RFC 3306 unicast-prefix-based multicast address",
+	Run:   runMulticastPrefix,
+}
+
+var embeddedRPCmd = &cobra.Command{
+	Use:   "embedded-rp RP-ADDRESS[/PLEN]",
+	Short: "Build an RFC 3956 embedded-RP multicast address; PLEN defaults to 64",
+	Run:   runEmbeddedRP,
+}
+
+func init() {
+	ipCmd.AddCommand(ipMulticastCmd)
+	ipMulticastCmd.AddCommand(solicitedNodeCmd, multicastDecodeCmd, multicastPrefixCmd, embeddedRPCmd)
+
+	for _, c := range []*cobra.Command{multicastPrefixCmd, embeddedRPCmd} {
+		c.Flags().String("group", "1", "32-bit group ID, decimal or 0x-prefixed hex")
+		c.Flags().String("scope", "global", "Scope name (interface, link, realm, admin, site, org, global) or hex value")
+	}
+}
+
+// solicitedNodeAddr 返回 ff02::1:ff00:0/104 加上单播地址最低 24 位
+func solicitedNodeAddr(addr netip.Addr) netip.Addr {
+	b := solicitedNodePrefix.Addr().As16()
+	a := addr.As16()
+	copy(b[13:], a[13:])
+	return netip.AddrFrom16(b)
+}
+
+// multicastMAC 返回 IPv6 组播地址对应的以太网组播 MAC：33:33 加上最低 32 位（RFC 2464）
+func multicastMAC(addr netip.Addr) net.HardwareAddr {
+	b := addr.As16()
+	return net.HardwareAddr{0x33, 0x33, b[12], b[13], b[14], b[15]}
+}
+
+// parseMulticastScope 解析范围名称（interface、link、site、org 等）或十六进制值
+func parseMulticastScope(s string) (byte, error) {
+	name := strings.ToLower(strings.TrimSpace(s))
+	switch name {
+	case "org", "organization":
+		name = "organization"
+	}
+	for scope, scopeName := range ipv6MulticastScopes {
+		if name == scopeName || name+"-local" == scopeName {
+			return scope, nil
+		}
+	}
+
+	value, err := strconv.ParseUint(strings.TrimPrefix(name, "0x"), 16, 4)
+	if err != nil {
+		return 0, errors.New(errors.ValidationError,
+			fmt.Sprintf("invalid scope %q, expected interface, link, realm, admin, site, org, global or a hex digit", s))
+	}
+	return byte(value), nil
+}
+
+// parseGroupID 解析 32 位组 ID，支持十进制和 0x 开头的十六进制
+func parseGroupID(s string) (uint32, error) {
+	value, err := strconv.ParseUint(strings.TrimSpace(s), 0, 32)
+	if err != nil {
+		return 0, errors.Wrap(errors.ParseError, fmt.Sprintf("invalid group ID %q", s), err)
+	}
+	return uint32(value), nil
+}
+
+// multicastInfo IPv6 组播地址的解码结果
+type multicastInfo struct {
+	Addr      netip.Addr
+	Flags     byte
+	Scope     byte
+	GroupID   uint32       // 最低 32 位，RFC 3306 / RFC 3956 地址的组 ID
+	Prefix    netip.Prefix // P 标志置位时嵌入的单播前缀
+	RP        netip.Addr   // R 标志置位时嵌入的 RP 地址
+	RIID      byte
+	SSM       bool
+	Solicited bool
+	Name      string
+}
+
+// decodeMulticast 解码 IPv6 组播地址的标志、范围以及 RFC 3306 / RFC 3956 嵌入的信息
+func decodeMulticast(addr netip.Addr) (multicastInfo, error) {
+	if !addr.Is6() || !ipv6MulticastPrefix.Contains(addr) {
+		return multicastInfo{}, errors.New(errors.ValidationError, fmt.Sprintf("%s is not an IPv6 multicast address (ff00::/8)", addr))
+	}
+
+	b := addr.As16()
+	info := multicastInfo{
+		Addr:      addr,
+		Flags:     b[1] >> 4,
+		Scope:     b[1] & 0x0f,
+		GroupID:   binary.BigEndian.Uint32(b[12:]),
+		Solicited: solicitedNodePrefix.Contains(addr),
+	}
+
+	info.Name = wellKnownMulticast[addr]
+
+	if info.Flags&multicastFlagR != 0 && info.Flags&multicastFlagP == 0 {
+		return multicastInfo{}, errors.New(errors.ValidationError,
+			fmt.Sprintf("%s has the R flag set without the P flag (RFC 3956 section 3)", addr))
+	}
+	if info.Flags&multicastFlagP == 0 {
+		return info, nil
+	}
+
+	plen := int(b[3])
+	if plen > 64 {
+		return multicastInfo{}, errors.New(errors.ValidationError,
+			fmt.Sprintf("%s embeds a /%d prefix, at most /64 is allowed (RFC 3306)", addr, plen))
+	}
+	var network [16]byte
+	copy(network[:8], b[4:12])
+	info.Prefix = netip.PrefixFrom(netip.AddrFrom16(network), plen).Masked()
+	info.SSM = plen == 0 && ssmPrefixBasedPrefix.Contains(addr)
+
+	if info.Flags&multicastFlagR != 0 {
+		if plen == 0 {
+			return multicastInfo{}, errors.New(errors.ValidationError,
+				fmt.Sprintf("%s is an embedded-RP address with a zero prefix length (RFC 3956 section 3)", addr))
+		}
+		info.RIID = b[2] & 0x0f
+		rp := info.Prefix.Addr().As16()
+		rp[15] |= info.RIID
+		info.RP = netip.AddrFrom16(rp)
+	}
+	return info, nil
+}
+
+// describeMulticastFlags 以 "R, P, T" 形式列出置位的标志
+func describeMulticastFlags(flags byte) string {
+	var names []string
+	for _, f := range []struct {
+		bit  byte
+		name string
+	}{{multicastFlagR, "R"}, {multicastFlagP, "P"}, {multicastFlagT, "T"}} {
+		if flags&f.bit != 0 {
+			names = append(names, f.name)
+		}
+	}
+	if len(names) == 0 {
+		return fmt.Sprintf("%x (permanent)", flags)
+	}
+	return fmt.Sprintf("%x (%s)", flags, strings.Join(names, ", "))
+}
+
+// unicastPrefixMulticast 按 RFC 3306 构造 ff3S:00LL:前缀::组ID
+func unicastPrefixMulticast(prefix netip.Prefix, scope byte, group uint32) (netip.Addr, error) {
+	if !prefix.Addr().Is6() || prefix.Bits() > 64 {
+		return netip.Addr{}, errors.New(errors.ValidationError,
+			fmt.Sprintf("%s must be an IPv6 prefix of at most /64 (RFC 3306)", prefix))
+	}
+	return buildPrefixMulticast(multicastFlagP|multicastFlagT, scope, 0, prefix.Masked(), group), nil
+}
+
+// embeddedRPMulticast 按 RFC 3956 构造 ff7S:0RLL:前缀::组ID，RP 地址由前缀和最低 4 位的 RIID 组成
+func embeddedRPMulticast(rp netip.Prefix, scope byte, group uint32) (netip.Addr, error) {
+	addr := rp.Addr()
+	if !addr.Is6() || rp.Bits() < 1 || rp.Bits() > 64 {
+		return netip.Addr{}, errors.New(errors.ValidationError,
+			fmt.Sprintf("%s must be an IPv6 RP address with a /1 to /64 prefix length (RFC 3956)", rp))
+	}
+
+	b := addr.As16()
+	riid := b[15] & 0x0f
+	b[15] &^= 0x0f
+	if netip.AddrFrom16(b) != rp.Masked().Addr() {
+		return netip.Addr{}, errors.New(errors.ValidationError,
+			fmt.Sprintf("RP address %s must be its /%d prefix plus a 4-bit RIID in the last nibble (RFC 3956)", addr, rp.Bits()))
+	}
+	return buildPrefixMulticast(multicastFlagR|multicastFlagP|multicastFlagT, scope, riid, rp.Masked(), group), nil
+}
+
+func buildPrefixMulticast(flags, scope, riid byte, prefix netip.Prefix, group uint32) netip.Addr {
+	var b [16]byte
+	network := prefix.Addr().As16()
+	b[0] = 0xff
+	b[1] = flags<<4 | scope&0x0f
+	b[2] = riid
+	b[3] = byte(prefix.Bits())
+	copy(b[4:12], network[:8])
+	binary.BigEndian.PutUint32(b[12:], group)
+	return netip.AddrFrom16(b)
+}
+
+// multicastArgs 解析一个或多个 IPv6 地址参数
+func multicastArgs(cmd *cobra.Command, args []string) ([]netip.Addr, bool) {
+	if len(args) == 0 {
+		logger.PrintValidationError("missing IPv6 address argument")
+		if err := cmd.Help(); err != nil {
+			logger.PrintErrorWithMessage("failed to show help", err)
+		}
+		return nil, false
+	}
+
+	addrs := make([]netip.Addr, 0, len(args))
+	for _, arg := range args {
+		addr, err := netip.ParseAddr(arg)
+		if err != nil || !addr.Is6() || addr.Is4In6() {
+			logger.PrintValidationError(fmt.Sprintf("%s is not an IPv6 address", arg))
+			return nil, false
+		}
+		addrs = append(addrs, addr.WithZone(""))
+	}
+	return addrs, true
+}
+
+// multicastBuildFlags 读取 --group 和 --scope
+func multicastBuildFlags(cmd *cobra.Command) (uint32, byte, bool) {
+	groupFlag, _ := cmd.Flags().GetString("group")
+	scopeFlag, _ := cmd.Flags().GetString("scope")
+
+	group, err := parseGroupID(groupFlag)
+	if err != nil {
+		logger.PrintError(err)
+		return 0, 0, false
+	}
+	scope, err := parseMulticastScope(scopeFlag)
+	if err != nil {
+		logger.PrintError(err)
+		return 0, 0, false
+	}
+	return group, scope, true
+}
+
+func runSolicitedNode(cmd *cobra.Command, args []string) {
+	addrs, ok := multicastArgs(cmd, args)
+	if !ok {
+		return
+	}
+
+	for i, addr := range addrs {
+		if i > 0 {
+			fmt.Println()
+		}
+		if ipv6MulticastPrefix.Contains(addr) {
+			logger.Warnf("%s is a multicast address; solicited-node addresses are derived from unicast or anycast addresses", addr)
+		}
+		group := solicitedNodeAddr(addr)
+		fmt.Println("Address:", addr)
+		fmt.Println("Solicited-Node Multicast:", group)
+		fmt.Println("Ethernet Multicast MAC:", multicastMAC(group))
+	}
+}
+
+func runMulticastDecode(cmd *cobra.Command, args []string) {
+	addrs, ok := multicastArgs(cmd, args)
+	if !ok {
+		return
+	}
+
+	for i, addr := range addrs {
+		info, err := decodeMulticast(addr)
+		if err != nil {
+			logger.PrintErrorWithMessage("failed to decode multicast address", err)
+			continue
+		}
+		if i > 0 {
+			fmt.Println()
+		}
+		printMulticastInfo(info)
+	}
+}
+
+func printMulticastInfo(info multicastInfo) {
+	fmt.Println("Multicast Address:", info.Addr)
+	fmt.Println("Flags:", describeMulticastFlags(info.Flags))
+	fmt.Println("Scope:", describeIPv6MulticastScope(info.Scope))
+	if info.Name != "" {
+		fmt.Println("Group:", info.Name)
+	}
+
+	switch {
+	case info.Solicited:
+		b := info.Addr.As16()
+		fmt.Printf("Solicited-Node: for unicast addresses ending in %02x:%02x%02x\n", b[13], b[14], b[15])
+	case info.SSM:
+		fmt.Println("Source-Specific Multicast: yes (RFC 4607, ff3x::/96)")
+		fmt.Printf("Group ID: 0x%08x\n", info.GroupID)
+	case info.RP.IsValid():
+		fmt.Println("Embedded RP: RFC 3956")
+		fmt.Println("RP Prefix:", info.Prefix)
+		fmt.Printf("RIID: %x\n", info.RIID)
+		fmt.Println("RP Address:", info.RP)
+		fmt.Printf("Group ID: 0x%08x\n", info.GroupID)
+	case info.Prefix.IsValid():
+		fmt.Println("Unicast-Prefix-Based: RFC 3306")
+		fmt.Println("Unicast Prefix:", info.Prefix)
+		fmt.Printf("Group ID: 0x%08x\n", info.GroupID)
+	}
+	fmt.Println("Ethernet Multicast MAC:", multicastMAC(info.Addr))
+}
+
+func runMulticastPrefix(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		logger.PrintValidationError("expected exactly one IPv6 prefix")
+		if err := cmd.Help(); err != nil {
+			logger.PrintErrorWithMessage("failed to show help", err)
+		}
+		return
+	}
+	group, scope, ok := multicastBuildFlags(cmd)
+	if !ok {
+		return
+	}
+
+	prefix, err := netip.ParsePrefix(args[0])
+	if err != nil {
+		logger.PrintErrorWithMessage("failed to parse prefix", err)
+		return
+	}
+	addr, err := unicastPrefixMulticast(prefix, scope, group)
+	if err != nil {
+		logger.PrintError(err)
+		return
+	}
+
+	fmt.Println("Unicast Prefix:", prefix.Masked())
+	fmt.Println("Multicast Address:", addr)
+	fmt.Println("Multicast Range:", netip.PrefixFrom(addr, 96).Masked())
+	fmt.Println("Scope:", describeIPv6MulticastScope(scope))
+	fmt.Println("Ethernet Multicast MAC:", multicastMAC(addr))
+}
+
+func runEmbeddedRP(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		logger.PrintValidationError("expected exactly one RP address")
+		if err := cmd.Help(); err != nil {
+			logger.PrintErrorWithMessage("failed to show help", err)
+		}
+		return
+	}
+	group, scope, ok := multicastBuildFlags(cmd)
+	if !ok {
+		return
+	}
+
+	arg := args[0]
+	if !strings.Contains(arg, "/") {
+		arg += "/64"
+	}
+	rp, err := netip.ParsePrefix(arg)
+	if err != nil {
+		logger.PrintErrorWithMessage("failed to parse RP address", err)
+		return
+	}
+	addr, err := embeddedRPMulticast(rp, scope, group)
+	if err != nil {
+		logger.PrintError(err)
+		return
+	}
+
+	fmt.Println("RP Address:", rp.Addr())
+	fmt.Println("RP Prefix:", rp.Masked())
+	fmt.Println("Multicast Address:", addr)
+	fmt.Println("Multicast Range:", netip.PrefixFrom(addr, 96).Masked())
+	fmt.Println("Scope:", describeIPv6MulticastScope(scope))
+	fmt.Println("Ethernet Multicast MAC:", multicastMAC(addr))
+}
@@ -0,0 +1,166 @@
+//go:build unit
+
+/*
+Copyright © 2024-2025 Auska <luodan0709@live.cn>
+
+*/
+
+package cmd
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestSolicitedNodeAddr(t *testing.T) {
+	tests := []struct {
+		addr     string
+		expected string
+		mac      string
+	}{
+		{"2001:db8::1:2345:6789", "ff02::1:ff45:6789", "33:33:ff:45:67:89"},
+		{"fe80::1", "ff02::1:ff00:1", "33:33:ff:00:00:01"},
+		{"fe80::211:22ff:fe33:4455", "ff02::1:ff33:4455", "33:33:ff:33:44:55"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			result := solicitedNodeAddr(netip.MustParseAddr(tt.addr))
+			if result.String() != tt.expected {
+				t.Errorf("solicitedNodeAddr() = %s, want %s", result, tt.expected)
+			}
+			if mac := multicastMAC(result).String(); mac != tt.mac {
+				t.Errorf("multicastMAC() = %s, want %s", mac, tt.mac)
+			}
+		})
+	}
+}
+
+func TestParseMulticastScope(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected byte
+		wantErr  bool
+	}{
+		{"interface", 0x1, false},
+		{"link", 0x2, false},
+		{"Link-Local", 0x2, false},
+		{"site", 0x5, false},
+		{"org", 0x8, false},
+		{"organization", 0x8, false},
+		{"global", 0xe, false},
+		{"e", 0xe, false},
+		{"0x5", 0x5, false},
+		{"universe", 0, true},
+		{"10", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			result, err := parseMulticastScope(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseMulticastScope() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if result != tt.expected {
+				t.Errorf("parseMulticastScope() = %x, want %x", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDecodeMulticast(t *testing.T) {
+	tests := []struct {
+		addr      string
+		flags     byte
+		scope     byte
+		prefix    string
+		rp        string
+		group     uint32
+		ssm       bool
+		solicited bool
+		name      string
+		wantErr   bool
+	}{
+		{addr: "ff02::1", scope: 0x2, group: 1, name: "all nodes"},
+		{addr: "ff02::1:ff45:6789", scope: 0x2, group: 0xff456789, solicited: true},
+		{addr: "ff15::101", flags: 0x1, scope: 0x5, group: 0x101},
+		{addr: "ff3e:30:2001:db8:cafe::1234", flags: 0x3, scope: 0xe, prefix: "2001:db8:cafe::/48", group: 0x1234},
+		{addr: "ff3e::8000:1", flags: 0x3, scope: 0xe, prefix: "::/0", group: 0x80000001, ssm: true},
+		{addr: "ff7e:140:2001:db8:beef:feed:0:1234", flags: 0x7, scope: 0xe, prefix: "2001:db8:beef:feed::/64",
+			rp: "2001:db8:beef:feed::1", group: 0x1234},
+		{addr: "ff75:230:2001:db8:cafe::1", flags: 0x7, scope: 0x5, prefix: "2001:db8:cafe::/48", rp: "2001:db8:cafe::2", group: 1},
+		{addr: "ff42::1", wantErr: true},
+		{addr: "ff3e:41:2001:db8::1", wantErr: true},
+		{addr: "ff7e:100::1", wantErr: true},
+		{addr: "2001:db8::1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			info, err := decodeMulticast(netip.MustParseAddr(tt.addr))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decodeMulticast() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if info.Flags != tt.flags || info.Scope != tt.scope || info.GroupID != tt.group {
+				t.Errorf("decodeMulticast() flags=%x scope=%x group=%#x, want flags=%x scope=%x group=%#x",
+					info.Flags, info.Scope, info.GroupID, tt.flags, tt.scope, tt.group)
+			}
+			if (tt.prefix == "") == info.Prefix.IsValid() || (tt.prefix != "" && info.Prefix.String() != tt.prefix) {
+				t.Errorf("decodeMulticast() prefix = %v, want %q", info.Prefix, tt.prefix)
+			}
+			if (tt.rp == "") == info.RP.IsValid() || (tt.rp != "" && info.RP.String() != tt.rp) {
+				t.Errorf("decodeMulticast() RP = %v, want %q", info.RP, tt.rp)
+			}
+			if info.SSM != tt.ssm || info.Solicited != tt.solicited || info.Name != tt.name {
+				t.Errorf("decodeMulticast() ssm=%v solicited=%v name=%q, want %v %v %q",
+					info.SSM, info.Solicited, info.Name, tt.ssm, tt.solicited, tt.name)
+			}
+		})
+	}
+}
+
+func TestBuildPrefixMulticast(t *testing.T) {
+	addr, err := unicastPrefixMulticast(netip.MustParsePrefix("2001:db8:cafe:1::/64"), 0x5, 0x1234)
+	if err != nil || addr.String() != "ff35:40:2001:db8:cafe:1:0:1234" {
+		t.Errorf("unicastPrefixMulticast() = %v, %v, want ff35:40:2001:db8:cafe:1:0:1234", addr, err)
+	}
+	if _, err := unicastPrefixMulticast(netip.MustParsePrefix("2001:db8::/96"), 0xe, 1); err == nil {
+		t.Error("unicastPrefixMulticast() expected error for a prefix longer than /64")
+	}
+
+	tests := []struct {
+		rp       string
+		expected string
+		wantErr  bool
+	}{
+		{"2001:db8:beef:feed::1/64", "ff7e:140:2001:db8:beef:feed:0:1234", false},
+		{"2001:db8:cafe::2/48", "ff7e:230:2001:db8:cafe::1234", false},
+		{"2001:db8:beef::1:1/48", "", true},
+		{"2001:db8::10/64", "", true},
+		{"2001:db8::1/80", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rp, func(t *testing.T) {
+			rp := netip.MustParsePrefix(tt.rp)
+			result, err := embeddedRPMulticast(rp, 0xe, 0x1234)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("embeddedRPMulticast() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if result.String() != tt.expected {
+				t.Errorf("embeddedRPMulticast() = %s, want %s", result, tt.expected)
+			}
+
+			info, err := decodeMulticast(result)
+			if err != nil || info.RP != rp.Addr() {
+				t.Errorf("decodeMulticast() RP = %v, %v, want round trip to %s", info.RP, err, rp.Addr())
+			}
+		})
+	}
+}
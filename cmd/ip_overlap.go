@@ -0,0 +1,141 @@
+/*
+Copyright © 2024-2025 Auska <luodan0709@live.cn>
+
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/spf13/cobra"
+	"macconv/pkg/logger"
+)
+
+var ipOverlapCmd = &cobra.Command{
+	Use:   "overlap",
+	Short: "Detect overlapping and duplicate prefixes",
+	Long: `
+Check a set of prefixes for duplicates and containment. Prefixes are given as
+arguments or read from a file (one per line, optional label columns such as
+VPC or site name). For example:
+
+	macconv ip overlap 10.0.0.0/16 10.0.128.0/20 172.16.0.0/12
+	macconv ip overlap --file vpcs.csv
+	macconv ip overlap --file vpcs.csv --allow-nested
+
+The command exits with status 1 when any conflict is found, so it can be used
+as a CI check.`,
+	Run: checkOverlap,
+}
+
+func init() {
+	ipCmd.AddCommand(ipOverlapCmd)
+	ipOverlapCmd.Flags().StringP("file", "f", "", "Read prefixes from file (\"-\" for stdin)")
+	ipOverlapCmd.Flags().Bool("allow-nested", false, "Report containment but do not treat it as a conflict")
+}
+
+// overlapKind 两个网段之间的重叠类型
+type overlapKind int
+
+const (
+	overlapDuplicate overlapKind = iota
+	overlapContains
+)
+
+func (k overlapKind) String() string {
+	if k == overlapDuplicate {
+		return "duplicate"
+	}
+	return "contains"
+}
+
+// overlapPair 一对重叠网段，Outer 总是较大（或相同）的网段
+type overlapPair struct {
+	Kind  overlapKind
+	Outer labeledPrefix
+	Inner labeledPrefix
+}
+
+// findOverlaps 找出所有重复或包含关系的网段对
+// CIDR 之间只可能相同、包含或不相交，因此按起始地址排序后只需向后扫描被包含的网段
+func findOverlaps(prefixes []labeledPrefix) []overlapPair {
+	sorted := make([]labeledPrefix, len(prefixes))
+	copy(sorted, prefixes)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i].Prefix, sorted[j].Prefix
+		if c := a.Addr().Compare(b.Addr()); c != 0 {
+			return c < 0
+		}
+		return a.Bits() < b.Bits()
+	})
+
+	var pairs []overlapPair
+	for i := range sorted {
+		outer := sorted[i]
+		for j := i + 1; j < len(sorted); j++ {
+			inner := sorted[j]
+			if !outer.Prefix.Contains(inner.Prefix.Addr()) {
+				break
+			}
+
+			kind := overlapContains
+			if outer.Prefix == inner.Prefix {
+				kind = overlapDuplicate
+			}
+			pairs = append(pairs, overlapPair{Kind: kind, Outer: outer, Inner: inner})
+		}
+	}
+
+	return pairs
+}
+
+// countConflicts 统计冲突数量，allowNested 时包含关系不计为冲突
+func countConflicts(pairs []overlapPair, allowNested bool) int {
+	conflicts := 0
+	for _, pair := range pairs {
+		if pair.Kind == overlapDuplicate || !allowNested {
+			conflicts++
+		}
+	}
+	return conflicts
+}
+
+func checkOverlap(cmd *cobra.Command, args []string) {
+	file, _ := cmd.Flags().GetString("file")
+	allowNested, _ := cmd.Flags().GetBool("allow-nested")
+
+	prefixes, err := collectPrefixes(args, file)
+	if err != nil {
+		logger.PrintErrorWithMessage("failed to load prefixes", err)
+		os.Exit(1)
+	}
+	if len(prefixes) == 0 {
+		logger.PrintValidationError("no prefixes given, pass them as arguments or use --file")
+		if err := cmd.Help(); err != nil {
+			logger.PrintErrorWithMessage("failed to show help", err)
+		}
+		return
+	}
+	logger.Debugf("Checking %d prefixes for overlap", len(prefixes))
+
+	pairs := findOverlaps(prefixes)
+	for _, pair := range pairs {
+		if pair.Kind == overlapDuplicate {
+			fmt.Printf("DUPLICATE  %s == %s\n", pair.Outer, pair.Inner)
+		} else {
+			fmt.Printf("CONTAINS   %s ⊇ %s\n", pair.Outer, pair.Inner)
+		}
+	}
+
+	conflicts := countConflicts(pairs, allowNested)
+	fmt.Printf("Checked: %d prefixes, %d overlapping pairs, %d conflicts\n", len(prefixes), len(pairs), conflicts)
+
+	if conflicts > 0 {
+		logger.Infof("Overlap check failed with %d conflicts", conflicts)
+		os.Exit(1)
+	}
+	logger.Infof("Overlap check passed for %d prefixes", len(prefixes))
+}
@@ -0,0 +1,89 @@
+//go:build unit
+
+/*
+Copyright © 2024-2025 Auska <luodan0709@live.cn>
+
+*/
+
+package cmd
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestFindOverlaps(t *testing.T) {
+	tests := []struct {
+		name      string
+		prefixes  []string
+		wantPairs []string
+	}{
+		{
+			name:      "Disjoint prefixes",
+			prefixes:  []string{"10.0.0.0/16", "10.1.0.0/16", "192.168.0.0/24", "2001:db8::/32"},
+			wantPairs: nil,
+		},
+		{
+			name:      "Duplicate prefixes",
+			prefixes:  []string{"10.0.0.0/16", "172.16.0.0/12", "10.0.0.0/16"},
+			wantPairs: []string{"duplicate 10.0.0.0/16 10.0.0.0/16"},
+		},
+		{
+			name:     "Nested prefixes",
+			prefixes: []string{"10.0.128.0/20", "10.0.0.0/16", "10.0.130.0/24", "10.1.0.0/16"},
+			wantPairs: []string{
+				"contains 10.0.0.0/16 10.0.128.0/20",
+				"contains 10.0.0.0/16 10.0.130.0/24",
+				"contains 10.0.128.0/20 10.0.130.0/24",
+			},
+		},
+		{
+			name:      "Same start address",
+			prefixes:  []string{"10.0.0.0/24", "10.0.0.0/8"},
+			wantPairs: []string{"contains 10.0.0.0/8 10.0.0.0/24"},
+		},
+		{
+			name:      "IPv6 nested",
+			prefixes:  []string{"2001:db8::/32", "2001:db8:1::/48"},
+			wantPairs: []string{"contains 2001:db8::/32 2001:db8:1::/48"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var prefixes []labeledPrefix
+			for _, p := range tt.prefixes {
+				prefixes = append(prefixes, labeledPrefix{Prefix: netip.MustParsePrefix(p)})
+			}
+
+			pairs := findOverlaps(prefixes)
+			if len(pairs) != len(tt.wantPairs) {
+				t.Fatalf("findOverlaps() returned %d pairs, want %d: %v", len(pairs), len(tt.wantPairs), pairs)
+			}
+			for i, pair := range pairs {
+				got := pair.Kind.String() + " " + pair.Outer.Prefix.String() + " " + pair.Inner.Prefix.String()
+				if got != tt.wantPairs[i] {
+					t.Errorf("pair[%d] = %q, want %q", i, got, tt.wantPairs[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCountConflicts(t *testing.T) {
+	pairs := []overlapPair{
+		{Kind: overlapDuplicate},
+		{Kind: overlapContains},
+		{Kind: overlapContains},
+	}
+
+	if got := countConflicts(pairs, false); got != 3 {
+		t.Errorf("countConflicts(allowNested=false) = %d, want 3", got)
+	}
+	if got := countConflicts(pairs, true); got != 1 {
+		t.Errorf("countConflicts(allowNested=true) = %d, want 1", got)
+	}
+	if got := countConflicts(nil, false); got != 0 {
+		t.Errorf("countConflicts(nil) = %d, want 0", got)
+	}
+}
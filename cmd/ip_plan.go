@@ -0,0 +1,294 @@
+/*
+Copyright © 2024-2025 Auska <luodan0709@live.cn>
+
+*/
+
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"math/big"
+	"math/bits"
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"macconv/pkg/errors"
+	"macconv/pkg/logger"
+)
+
+// ipv6LANBits SLAAC 要求的局域网前缀长度
+const ipv6LANBits = 64
+
+// maxPlanNodes 输出树形时允许的最大叶子数
+const maxPlanNodes = 1 << 16
+
+var ipPlanCmd = &cobra.Command{
+	Use:   "plan PREFIX",
+	Short: "Plan a nibble-aligned IPv6 addressing hierarchy",
+	Long: `
+Split an IPv6 prefix into a hierarchy of nibble-aligned child prefixes and
+print the resulting tree. Each --level is NAME=COUNT or NAME=label1,label2,...
+and may end in /LEN to fix the prefix length of that level; otherwise the
+smallest nibble boundary that fits COUNT children is used. For example:
+
+	macconv ip plan 2001:db8::/32 --level region=4 --level site=12 --level vlan=20
+	macconv ip plan 2001:db8::/32 --level region=eu,us,ap --level site=40/48 --depth 2
+	macconv ip plan 2001:db8:100::/40 --level site=40 --level vlan=200/64 --summary`,
+	Run: planIPv6,
+}
+
+func init() {
+	ipCmd.AddCommand(ipPlanCmd)
+	ipPlanCmd.Flags().StringArray("level", nil, "Hierarchy level NAME=COUNT[/LEN] or NAME=a,b,c[/LEN], outermost first")
+	ipPlanCmd.Flags().Int("depth", 0, "Only print the tree down to this depth, 0 for all levels")
+	ipPlanCmd.Flags().Bool("summary", false, "Only print the per-level summary, not the tree")
+}
+
+// planLevel 层级定义，Bits 为 0 时自动按半字节对齐
+type planLevel struct {
+	Name   string
+	Count  int
+	Labels []string
+	Bits   int
+}
+
+// planLevelSummary 每一层的分配结果
+type planLevelSummary struct {
+	Name      string
+	PrefixLen int
+	Requested int
+	Available *big.Int // 每个上级前缀可容纳的数量
+	Total     int      // 该层前缀总数，超过 maxPlanNodes 时截断为 maxPlanNodes+1
+}
+
+// planNode 规划树中的一个前缀
+type planNode struct {
+	Name     string
+	Prefix   netip.Prefix
+	Children []*planNode
+}
+
+// parsePlanLevel 解析 NAME=COUNT[/LEN] 或 NAME=a,b,c[/LEN]
+func parsePlanLevel(s string) (planLevel, error) {
+	name, value, ok := strings.Cut(s, "=")
+	name = strings.TrimSpace(name)
+	if !ok || name == "" || value == "" {
+		return planLevel{}, errors.New(errors.ParseError, fmt.Sprintf("invalid level %q, expected NAME=COUNT[/LEN]", s))
+	}
+
+	level := planLevel{Name: name}
+	children, length, hasLength := strings.Cut(value, "/")
+	if hasLength {
+		n, err := strconv.Atoi(length)
+		if err != nil || n < 1 || n > 128 {
+			return planLevel{}, errors.New(errors.ValidationError, fmt.Sprintf("invalid prefix length in level %q", s))
+		}
+		level.Bits = n
+	}
+
+	if count, err := strconv.Atoi(children); err == nil {
+		if count < 1 {
+			return planLevel{}, errors.New(errors.ValidationError, fmt.Sprintf("level %q must have at least one child", name))
+		}
+		level.Count = count
+		return level, nil
+	}
+
+	for _, label := range strings.Split(children, ",") {
+		if label = strings.TrimSpace(label); label != "" {
+			level.Labels = append(level.Labels, label)
+		}
+	}
+	if len(level.Labels) == 0 {
+		return planLevel{}, errors.New(errors.ValidationError, fmt.Sprintf("level %q has no children", name))
+	}
+	level.Count = len(level.Labels)
+	return level, nil
+}
+
+// nibbleBits 返回容纳 count 个子网所需的位数，向上取整到 4 的倍数，至少 4 位
+func nibbleBits(count int) int {
+	needed := bits.Len(uint(count - 1))
+	return max(4, (needed+3)/4*4)
+}
+
+// planSummaries 计算每一层的前缀长度和容量
+func planSummaries(root netip.Prefix, levels []planLevel) ([]planLevelSummary, error) {
+	if !root.Addr().Is6() {
+		return nil, errors.New(errors.ValidationError, "the planner only supports IPv6 prefixes")
+	}
+	if len(levels) == 0 {
+		return nil, errors.New(errors.ValidationError, "at least one --level is required")
+	}
+
+	root = root.Masked()
+	summaries := make([]planLevelSummary, len(levels))
+	parentBits := root.Bits()
+	total := 1
+	for i, level := range levels {
+		childBits := level.Bits
+		if childBits == 0 {
+			childBits = parentBits + nibbleBits(level.Count)
+		}
+		if childBits <= parentBits || childBits > 128 {
+			return nil, errors.New(errors.ValidationError,
+				fmt.Sprintf("level %q: /%d does not fit inside /%d", level.Name, childBits, parentBits))
+		}
+
+		available := powerOfTwo(childBits - parentBits)
+		if big.NewInt(int64(level.Count)).Cmp(available) > 0 {
+			return nil, errors.New(errors.ValidationError,
+				fmt.Sprintf("level %q: %d children do not fit, a /%d holds %s /%d prefixes",
+					level.Name, level.Count, parentBits, available, childBits))
+		}
+
+		total = min(total*level.Count, maxPlanNodes+1)
+		summaries[i] = planLevelSummary{
+			Name:      level.Name,
+			PrefixLen: childBits,
+			Requested: level.Count,
+			Available: available,
+			Total:     total,
+		}
+		parentBits = childBits
+	}
+
+	return summaries, nil
+}
+
+// buildPlanTree 按 planSummaries 的结果逐级分配子前缀
+func buildPlanTree(root netip.Prefix, levels []planLevel, summaries []planLevelSummary) (*planNode, error) {
+	if leaves := summaries[len(summaries)-1].Total; leaves > maxPlanNodes {
+		return nil, errors.New(errors.ValidationError,
+			fmt.Sprintf("the plan has more than %d leaf prefixes; use --summary", maxPlanNodes))
+	}
+
+	tree := &planNode{Prefix: root.Masked()}
+	if err := planChildren(tree, levels, summaries); err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
+
+func planChildren(node *planNode, levels []planLevel, summaries []planLevelSummary) error {
+	if len(levels) == 0 {
+		return nil
+	}
+
+	level := levels[0]
+	first := netip.PrefixFrom(node.Prefix.Addr(), summaries[0].PrefixLen)
+	for i := 0; i < level.Count; i++ {
+		prefix, err := offsetPrefix(first, int64(i))
+		if err != nil {
+			return err
+		}
+
+		name := fmt.Sprintf("%s %d", level.Name, i+1)
+		if level.Labels != nil {
+			name = fmt.Sprintf("%s %s", level.Name, level.Labels[i])
+		}
+		child := &planNode{Name: name, Prefix: prefix}
+		if err := planChildren(child, levels[1:], summaries[1:]); err != nil {
+			return err
+		}
+		node.Children = append(node.Children, child)
+	}
+	return nil
+}
+
+// tree 转换为通用树节点
+func (n *planNode) tree() *treeNode {
+	label := n.Prefix.String()
+	if n.Name != "" {
+		label = fmt.Sprintf("%s  %s", n.Prefix, n.Name)
+	}
+	node := &treeNode{Label: label}
+	for _, child := range n.Children {
+		node.Children = append(node.Children, child.tree())
+	}
+	return node
+}
+
+func printPlanSummary(root netip.Prefix, summaries []planLevelSummary) {
+	fmt.Println("Prefix:", root)
+	parentBits := root.Bits()
+	for _, s := range summaries {
+		total := strconv.Itoa(s.Total)
+		if s.Total > maxPlanNodes {
+			total = fmt.Sprintf("more than %d", maxPlanNodes)
+		}
+		fmt.Printf("Level %s: /%d, %d per /%d (%s fit, %d bits), %s in total\n",
+			s.Name, s.PrefixLen, s.Requested, parentBits, formatCount(s.Available), s.PrefixLen-parentBits, total)
+		parentBits = s.PrefixLen
+	}
+
+	if parentBits <= ipv6LANBits {
+		fmt.Printf("/64 Networks Per %s: %s\n", summaries[len(summaries)-1].Name, formatCount(powerOfTwo(ipv6LANBits-parentBits)))
+	}
+}
+
+func planIPv6(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		logger.PrintValidationError("missing IPv6 prefix argument")
+		if err := cmd.Help(); err != nil {
+			logger.PrintErrorWithMessage("failed to show help", err)
+		}
+		return
+	}
+
+	rawLevels, _ := cmd.Flags().GetStringArray("level")
+	depth, _ := cmd.Flags().GetInt("depth")
+	summaryOnly, _ := cmd.Flags().GetBool("summary")
+
+	root, err := parsePrefix(args[0])
+	if err != nil {
+		logger.PrintErrorWithMessage("failed to parse IPv6 prefix", err)
+		return
+	}
+
+	levels := make([]planLevel, 0, len(rawLevels))
+	for _, raw := range rawLevels {
+		level, err := parsePlanLevel(raw)
+		if err != nil {
+			logger.PrintError(err)
+			return
+		}
+		levels = append(levels, level)
+	}
+
+	summaries, err := planSummaries(root, levels)
+	if err != nil {
+		logger.PrintErrorWithMessage("failed to plan hierarchy", err)
+		return
+	}
+
+	for _, s := range summaries {
+		if s.PrefixLen%4 != 0 {
+			logger.Warnf("level %s uses /%d, which is not on a nibble boundary", s.Name, s.PrefixLen)
+		}
+	}
+	if leaf := summaries[len(summaries)-1]; leaf.PrefixLen > ipv6LANBits {
+		logger.Warnf("level %s uses /%d; SLAAC requires /64 LAN prefixes", leaf.Name, leaf.PrefixLen)
+	}
+
+	printPlanSummary(root, summaries)
+	if !summaryOnly {
+		tree, err := buildPlanTree(root, levels, summaries)
+		if err != nil {
+			logger.PrintError(err)
+			return
+		}
+		fmt.Println()
+		w := bufio.NewWriter(os.Stdout)
+		writeTree(w, tree.tree(), depth)
+		if err := w.Flush(); err != nil {
+			logger.Debugf("Failed to flush output: %v", err)
+		}
+	}
+
+	logger.Infof("Successfully planned %s", root)
+}
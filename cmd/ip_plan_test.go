@@ -0,0 +1,136 @@
+//go:build unit
+
+/*
+Copyright © 2024-2025 Auska <luodan0709@live.cn>
+
+*/
+
+package cmd
+
+import (
+	"net/netip"
+	"reflect"
+	"testing"
+)
+
+func TestParsePlanLevel(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected planLevel
+		wantErr  bool
+	}{
+		{"region=4", planLevel{Name: "region", Count: 4}, false},
+		{"site=40/48", planLevel{Name: "site", Count: 40, Bits: 48}, false},
+		{"region=eu,us,ap", planLevel{Name: "region", Count: 3, Labels: []string{"eu", "us", "ap"}}, false},
+		{"dc=a, b/44", planLevel{Name: "dc", Count: 2, Labels: []string{"a", "b"}, Bits: 44}, false},
+		{"region", planLevel{}, true},
+		{"=4", planLevel{}, true},
+		{"site=0", planLevel{}, true},
+		{"site=4/129", planLevel{}, true},
+		{"site=,", planLevel{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			result, err := parsePlanLevel(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parsePlanLevel() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("parsePlanLevel() = %+v, want %+v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNibbleBits(t *testing.T) {
+	tests := []struct {
+		count    int
+		expected int
+	}{
+		{1, 4},
+		{2, 4},
+		{16, 4},
+		{17, 8},
+		{40, 8},
+		{256, 8},
+		{257, 12},
+	}
+
+	for _, tt := range tests {
+		if result := nibbleBits(tt.count); result != tt.expected {
+			t.Errorf("nibbleBits(%d) = %d, want %d", tt.count, result, tt.expected)
+		}
+	}
+}
+
+func TestPlanHierarchy(t *testing.T) {
+	root := netip.MustParsePrefix("2001:db8::/32")
+	levels := []planLevel{
+		{Name: "region", Count: 3, Labels: []string{"eu", "us", "ap"}},
+		{Name: "site", Count: 40},
+		{Name: "vlan", Count: 2, Bits: 64},
+	}
+
+	summaries, err := planSummaries(root, levels)
+	if err != nil {
+		t.Fatalf("planSummaries() error = %v", err)
+	}
+	lengths := []int{summaries[0].PrefixLen, summaries[1].PrefixLen, summaries[2].PrefixLen}
+	if !reflect.DeepEqual(lengths, []int{36, 44, 64}) {
+		t.Errorf("prefix lengths = %v, want [36 44 64]", lengths)
+	}
+	if summaries[2].Total != 240 {
+		t.Errorf("leaf total = %d, want 240", summaries[2].Total)
+	}
+
+	tree, err := buildPlanTree(root, levels, summaries)
+	if err != nil {
+		t.Fatalf("buildPlanTree() error = %v", err)
+	}
+	us := tree.Children[1]
+	if us.Name != "region us" || us.Prefix.String() != "2001:db8:1000::/36" {
+		t.Errorf("second region = %s %s", us.Name, us.Prefix)
+	}
+	site := us.Children[39]
+	if site.Prefix.String() != "2001:db8:1270::/44" {
+		t.Errorf("last site = %s, want 2001:db8:1270::/44", site.Prefix)
+	}
+	if vlan := site.Children[1]; vlan.Prefix.String() != "2001:db8:1270:1::/64" {
+		t.Errorf("second vlan = %s, want 2001:db8:1270:1::/64", vlan.Prefix)
+	}
+}
+
+func TestPlanSummariesErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		root   string
+		levels []planLevel
+	}{
+		{"IPv4", "10.0.0.0/8", []planLevel{{Name: "a", Count: 2}}},
+		{"No levels", "2001:db8::/32", nil},
+		{"Too many children", "2001:db8::/32", []planLevel{{Name: "a", Count: 17, Bits: 36}}},
+		{"Length shorter than parent", "2001:db8::/32", []planLevel{{Name: "a", Count: 2, Bits: 32}}},
+		{"Past /128", "2001:db8::/120", []planLevel{{Name: "a", Count: 2}, {Name: "b", Count: 2}, {Name: "c", Count: 2}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := planSummaries(netip.MustParsePrefix(tt.root), tt.levels); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestBuildPlanTreeLimit(t *testing.T) {
+	root := netip.MustParsePrefix("2001:db8::/32")
+	levels := []planLevel{{Name: "a", Count: 65536}, {Name: "b", Count: 2}}
+	summaries, err := planSummaries(root, levels)
+	if err != nil {
+		t.Fatalf("planSummaries() error = %v", err)
+	}
+	if _, err := buildPlanTree(root, levels, summaries); err == nil {
+		t.Error("expected leaf limit error")
+	}
+}
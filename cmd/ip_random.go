@@ -0,0 +1,210 @@
+/*
+Copyright © 2024-2025 Auska <luodan0709@live.cn>
+
+*/
+
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"net/netip"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/spf13/cobra"
+	"macconv/pkg/errors"
+	"macconv/pkg/logger"
+)
+
+var ipRandomCmd = &cobra.Command{
+	Use:   "random CIDR",
+	Short: "Pick random host addresses or non-overlapping subnets in a CIDR",
+	Long: `
+Pick N random host addresses, or with --size N random non-overlapping
+subnets of that prefix length, inside a CIDR. Prefixes given with --avoid or
+--avoid-file (CIDRs, ranges or 10.0.0.* globs, one per line) are never
+picked from. The same --seed always gives the same result. For example:
+
+	macconv ip random 10.0.0.0/24 --count 5
+	macconv ip random 10.0.0.0/8 --size 16 --count 3 --avoid-file used.txt
+	macconv ip random fd00::/8 --size 48 --seed 42
+	macconv ip random 172.16.0.0/12 --size 20 --avoid 172.16.0.0/16,172.20.0.0/14`,
+	Run: pickRandom,
+}
+
+func init() {
+	ipCmd.AddCommand(ipRandomCmd)
+	ipRandomCmd.Flags().IntP("count", "n", 1, "Number of addresses or subnets to pick")
+	ipRandomCmd.Flags().Int("size", 0, "Prefix length of the subnets to pick, 0 for host addresses")
+	ipRandomCmd.Flags().StringSlice("avoid", nil, "Prefixes or ranges that must not be picked from")
+	ipRandomCmd.Flags().String("avoid-file", "", "File with prefixes or ranges to avoid, one per line (\"-\" for stdin)")
+	ipRandomCmd.Flags().Bool("all", false, "Allow network, broadcast and anycast addresses when picking hosts")
+	ipRandomCmd.Flags().Int64("seed", 0, "Random seed (default: current time)")
+}
+
+// alignedSlots 返回 r 中按 size 对齐、完整落在 r 内的块的起始序号和数量
+func alignedSlots(r addrRange, size *big.Int) (*big.Int, *big.Int) {
+	start := addrToInt(r.First)
+	start.Add(start, size).Sub(start, big.NewInt(1)).Quo(start, size)
+	end := addrToInt(r.Last)
+	end.Add(end, big.NewInt(1)).Quo(end, size)
+
+	count := new(big.Int).Sub(end, start)
+	if count.Sign() < 0 {
+		count.SetInt64(0)
+	}
+	return start, count
+}
+
+// freeSlots [lo, hi] 中未与 used 重叠的 /bits 块，按地址顺序从 0 开始编号
+type freeSlots struct {
+	bits   int
+	bitLen int
+	size   *big.Int   // 每块的地址数
+	starts []*big.Int // 每个空闲段中第一个块的块序号
+	ends   []*big.Int // 到每个空闲段为止的累计块数
+}
+
+func newFreeSlots(lo, hi netip.Addr, bits int, used []addrRange) *freeSlots {
+	s := &freeSlots{bits: bits, bitLen: lo.BitLen(), size: powerOfTwo(lo.BitLen() - bits)}
+	total := new(big.Int)
+	for _, gap := range rangeGaps(lo, hi, mergeRanges(used)) {
+		start, count := alignedSlots(gap, s.size)
+		if count.Sign() == 0 {
+			continue
+		}
+		total.Add(total, count)
+		s.starts = append(s.starts, start)
+		s.ends = append(s.ends, new(big.Int).Set(total))
+	}
+	return s
+}
+
+// total 返回空闲块的数量
+func (s *freeSlots) total() *big.Int {
+	if len(s.ends) == 0 {
+		return new(big.Int)
+	}
+	return s.ends[len(s.ends)-1]
+}
+
+// prefix 返回编号为 k 的空闲块
+func (s *freeSlots) prefix(k *big.Int) netip.Prefix {
+	i := sort.Search(len(s.ends), func(i int) bool { return s.ends[i].Cmp(k) > 0 })
+	// 块序号 = 段中第一个块的序号 + (k - 前面各段的块数)
+	n := new(big.Int).Add(s.starts[i], k)
+	if i > 0 {
+		n.Sub(n, s.ends[i-1])
+	}
+	addr, _ := intToAddr(n.Mul(n, s.size), s.bitLen)
+	return netip.PrefixFrom(addr, s.bits)
+}
+
+// freeSlotCount 返回 [lo, hi] 中未与 used 重叠的 /bits 块数量
+func freeSlotCount(lo, hi netip.Addr, bits int, used []addrRange) *big.Int {
+	return newFreeSlots(lo, hi, bits, used).total()
+}
+
+// randomPrefixes 在 [lo, hi] 中选取 n 个互不重叠且不与 avoid 重叠的 /bits 块，结果按地址排序
+// 对齐的块之间不会部分重叠，因此只需用 Floyd 算法从空闲块编号中无重复地抽取 n 个
+func randomPrefixes(lo, hi netip.Addr, bits, n int, avoid []addrRange, rng *rand.Rand) ([]netip.Prefix, error) {
+	slots := newFreeSlots(lo, hi, bits, avoid)
+	if free := slots.total(); free.Cmp(big.NewInt(int64(n))) < 0 {
+		return nil, errors.New(errors.ValidationError,
+			fmt.Sprintf("only %s free /%d blocks are available, %d requested", free, bits, n))
+	}
+
+	picked := make([]netip.Prefix, 0, n)
+	for _, k := range floydSample(slots.total(), n, rng) {
+		picked = append(picked, slots.prefix(k))
+	}
+	return picked, nil
+}
+
+func pickRandom(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		logger.PrintValidationError("missing CIDR address argument")
+		if err := cmd.Help(); err != nil {
+			logger.PrintErrorWithMessage("failed to show help", err)
+		}
+		return
+	}
+
+	count, _ := cmd.Flags().GetInt("count")
+	size, _ := cmd.Flags().GetInt("size")
+	avoidArgs, _ := cmd.Flags().GetStringSlice("avoid")
+	avoidFile, _ := cmd.Flags().GetString("avoid-file")
+	includeAll, _ := cmd.Flags().GetBool("all")
+	seed, _ := cmd.Flags().GetInt64("seed")
+
+	if count < 1 {
+		logger.PrintValidationError("count must be at least 1")
+		return
+	}
+
+	root, err := parsePrefix(args[0])
+	if err != nil {
+		logger.PrintErrorWithMessage("failed to parse CIDR address", err)
+		if err := cmd.Help(); err != nil {
+			logger.PrintErrorWithMessage("failed to show help", err)
+		}
+		return
+	}
+
+	bitLen := root.Addr().BitLen()
+	lo, hi := root.Addr(), prefixLastAddr(root)
+	if size == 0 {
+		size = bitLen
+		if lo, hi, err = hostRange(root.String(), includeAll); err != nil {
+			logger.PrintErrorWithMessage("failed to parse CIDR address", err)
+			return
+		}
+	}
+	if size < root.Bits() || size > bitLen {
+		logger.PrintValidationError(fmt.Sprintf("size /%d does not fit inside %s", size, root))
+		return
+	}
+
+	avoidPrefixes, err := collectPrefixes(avoidArgs, avoidFile)
+	if err != nil {
+		logger.PrintErrorWithMessage("failed to read prefixes to avoid", err)
+		return
+	}
+	avoid := make([]addrRange, 0, len(avoidPrefixes))
+	for _, lp := range avoidPrefixes {
+		if lp.Prefix.Overlaps(root) {
+			avoid = append(avoid, prefixRange(lp.Prefix))
+		}
+	}
+	logger.Debugf("Avoiding %d of %d prefixes that overlap %s", len(avoid), len(avoidPrefixes), root)
+
+	if !cmd.Flags().Changed("seed") {
+		seed = time.Now().UnixNano()
+	}
+	logger.Debugf("Picking %d /%d blocks from %s with seed %d", count, size, root, seed)
+	rng := rand.New(rand.NewSource(seed)) // #nosec G404 -- address selection, not security sensitive
+
+	picked, err := randomPrefixes(lo, hi, size, count, avoid, rng)
+	if err != nil {
+		logger.PrintErrorWithMessage(fmt.Sprintf("cannot pick from %s", root), err)
+		return
+	}
+
+	out := bufio.NewWriter(os.Stdout)
+	for _, prefix := range picked {
+		if size == bitLen {
+			fmt.Fprintln(out, prefix.Addr())
+		} else {
+			fmt.Fprintln(out, prefix)
+		}
+	}
+	if err := out.Flush(); err != nil {
+		logger.Debugf("Error flushing output: %v", err)
+	}
+
+	logger.Infof("Successfully picked %d from %s with seed %d", len(picked), root, seed)
+}
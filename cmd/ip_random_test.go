@@ -0,0 +1,122 @@
+//go:build unit
+
+/*
+Copyright © 2024-2025 Auska <luodan0709@live.cn>
+
+*/
+
+package cmd
+
+import (
+	"math/big"
+	"math/rand"
+	"net/netip"
+	"reflect"
+	"testing"
+)
+
+func TestFreeSlotCount(t *testing.T) {
+	lo, hi := netip.MustParseAddr("10.0.0.0"), netip.MustParseAddr("10.0.0.255")
+	tests := []struct {
+		name     string
+		bits     int
+		used     []string
+		expected int64
+	}{
+		{"Empty /26", 26, nil, 4},
+		{"One /26 used", 26, []string{"10.0.0.0-10.0.0.63"}, 3},
+		{"Unaligned use blocks two /26", 26, []string{"10.0.0.5-10.0.0.70"}, 2},
+		{"Hosts", 32, []string{"10.0.0.0-10.0.0.9"}, 246},
+		{"All used", 24, []string{"10.0.0.128-10.0.0.128"}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := freeSlotCount(lo, hi, tt.bits, parseRanges(t, tt.used...))
+			if result.Int64() != tt.expected {
+				t.Errorf("freeSlotCount() = %s, want %d", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRandomPrefixes(t *testing.T) {
+	root := netip.MustParsePrefix("10.0.0.0/16")
+	lo, hi := root.Addr(), prefixLastAddr(root)
+	avoid := parseRanges(t, "10.0.0.0-10.0.127.255", "10.0.200.17-10.0.200.17")
+
+	picked, err := randomPrefixes(lo, hi, 24, 20, avoid, rand.New(rand.NewSource(1)))
+	if err != nil {
+		t.Fatalf("randomPrefixes() error = %v", err)
+	}
+	if len(picked) != 20 {
+		t.Fatalf("randomPrefixes() returned %d prefixes, want 20", len(picked))
+	}
+
+	for i, prefix := range picked {
+		if prefix.Bits() != 24 || !root.Contains(prefix.Addr()) {
+			t.Errorf("picked %s is not a /24 inside %s", prefix, root)
+		}
+		for _, r := range avoid {
+			if prefixRange(prefix).relate(r) != relationDisjoint && prefixRange(prefix).relate(r) != relationAdjacent {
+				t.Errorf("picked %s overlaps avoided range %s-%s", prefix, r.First, r.Last)
+			}
+		}
+		if i > 0 && !picked[i-1].Addr().Less(prefix.Addr()) {
+			t.Errorf("picked prefixes not sorted and unique: %s then %s", picked[i-1], prefix)
+		}
+	}
+
+	again, _ := randomPrefixes(lo, hi, 24, 20, avoid, rand.New(rand.NewSource(1)))
+	for i := range picked {
+		if picked[i] != again[i] {
+			t.Fatalf("same seed gave different results: %v and %v", picked, again)
+		}
+	}
+}
+
+func TestFreeSlotsPrefix(t *testing.T) {
+	lo, hi := netip.MustParseAddr("10.0.0.0"), netip.MustParseAddr("10.0.0.255")
+	slots := newFreeSlots(lo, hi, 28, parseRanges(t, "10.0.0.20-10.0.0.40", "10.0.0.100-10.0.0.200"))
+
+	var got []string
+	for k := int64(0); k < slots.total().Int64(); k++ {
+		got = append(got, slots.prefix(big.NewInt(k)).String())
+	}
+	want := []string{"10.0.0.0/28", "10.0.0.48/28", "10.0.0.64/28", "10.0.0.80/28",
+		"10.0.0.208/28", "10.0.0.224/28", "10.0.0.240/28"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("free slots = %v, want %v", got, want)
+	}
+}
+
+func TestRandomPrefixesMany(t *testing.T) {
+	root := netip.MustParsePrefix("10.0.0.0/8")
+	picked, err := randomPrefixes(root.Addr(), prefixLastAddr(root), 32, 50000, nil, rand.New(rand.NewSource(3)))
+	if err != nil {
+		t.Fatalf("randomPrefixes() error = %v", err)
+	}
+	for i := 1; i < len(picked); i++ {
+		if !picked[i-1].Addr().Less(picked[i].Addr()) {
+			t.Fatalf("picked addresses not sorted and unique at %d: %s then %s", i, picked[i-1], picked[i])
+		}
+	}
+}
+
+func TestRandomPrefixesExhaustive(t *testing.T) {
+	lo, hi := netip.MustParseAddr("2001:db8::"), netip.MustParseAddr("2001:db8::ff")
+	picked, err := randomPrefixes(lo, hi, 124, 16, nil, rand.New(rand.NewSource(7)))
+	if err != nil {
+		t.Fatalf("randomPrefixes() error = %v", err)
+	}
+	for i, prefix := range picked {
+		expected, _ := offsetPrefix(netip.MustParsePrefix("2001:db8::/124"), int64(i))
+		if prefix != expected {
+			t.Errorf("picked[%d] = %s, want %s", i, prefix, expected)
+		}
+	}
+
+	if _, err := randomPrefixes(lo, hi, 124, 17, nil, rand.New(rand.NewSource(7))); err == nil {
+		t.Error("randomPrefixes() expected error when more blocks are requested than available")
+	}
+}
@@ -0,0 +1,730 @@
+/*
+Copyright © 2024-2025 Auska <luodan0709@live.cn>
+
+*/
+
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"macconv/pkg/errors"
+	"macconv/pkg/logger"
+	"macconv/pkg/validator"
+)
+
+// 路由表文本格式
+const (
+	routeFormatAuto   = "auto"
+	routeFormatLinux  = "linux"
+	routeFormatCisco  = "cisco"
+	routeFormatHuawei = "huawei"
+)
+
+var ipRouteCmd = &cobra.Command{
+	Use:   "route [ip...]",
+	Short: "Look up destinations in a pasted routing table",
+	Long: `
+Parse routing table output and show which route a destination takes by
+longest prefix match, including all equal-cost next hops. Supported input:
+
+	linux   ip route / ip -6 route (including multipath nexthop lines)
+	cisco   show ip route / show ipv6 route
+	huawei  display ip routing-table / display ipv6 routing-table
+
+The format is detected automatically unless --format is given. Without
+destination arguments the parsed table is printed. For example:
+
+	macconv ip route -f rib.txt 10.1.2.3 2001:db8::1
+	ip route | macconv ip route -f - 8.8.8.8
+	macconv ip route -f core1.txt --format cisco --all 10.1.2.3`,
+	Run: lookupRoutes,
+}
+
+func init() {
+	ipCmd.AddCommand(ipRouteCmd)
+	ipRouteCmd.Flags().StringP("file", "f", "", "Routing table file, - for stdin")
+	ipRouteCmd.Flags().String("format", routeFormatAuto, "Input format: auto, linux, cisco or huawei")
+	ipRouteCmd.Flags().Bool("all", false, "Also show less specific and backup routes that match")
+}
+
+// nextHop 路由的一个下一跳，Via 无效时表示直连
+type nextHop struct {
+	Via       netip.Addr
+	Interface string
+}
+
+// String 返回 "via 地址 dev 接口" 形式的描述
+func (nh nextHop) String() string {
+	var parts []string
+	if nh.Via.IsValid() {
+		parts = append(parts, "via "+nh.Via.String())
+	} else {
+		parts = append(parts, "directly connected")
+	}
+	if nh.Interface != "" {
+		parts = append(parts, "dev "+nh.Interface)
+	}
+	return strings.Join(parts, " ")
+}
+
+// route 路由表中的一条路由
+type route struct {
+	Prefix   netip.Prefix
+	Type     string // Linux 路由类型，如 blackhole、local，普通单播路由为空
+	Protocol string
+	Metric   string // 原始度量：Linux 为 metric，Cisco 为 [距离/度量]，华为为 优先级/开销
+	NextHops []nextHop
+}
+
+// String 返回路由的单行描述
+func (r route) String() string {
+	var sb strings.Builder
+	sb.WriteString(r.Prefix.String())
+	if r.Type != "" {
+		sb.WriteString(" " + r.Type)
+	}
+
+	var attrs []string
+	if r.Protocol != "" {
+		attrs = append(attrs, r.Protocol)
+	}
+	if r.Metric != "" {
+		attrs = append(attrs, r.Metric)
+	}
+	if len(attrs) > 0 {
+		fmt.Fprintf(&sb, " [%s]", strings.Join(attrs, " "))
+	}
+	return sb.String()
+}
+
+var (
+	huaweiBlockPattern         = regexp.MustCompile(`(?m)^\s*Destination\s+:`)
+	ciscoMetricAnywherePattern = regexp.MustCompile(`\[\d+/\d+\]`)
+)
+
+// detectRouteFormat 根据特征文本判断路由表格式
+func detectRouteFormat(text string) string {
+	switch {
+	case strings.Contains(text, "Destination/Mask"), strings.Contains(text, "Routing Tables:"),
+		huaweiBlockPattern.MatchString(text):
+		return routeFormatHuawei
+	case strings.Contains(text, "Codes:"), strings.Contains(text, "Gateway of last resort"),
+		strings.Contains(text, "is directly connected"), ciscoMetricAnywherePattern.MatchString(text):
+		return routeFormatCisco
+	default:
+		return routeFormatLinux
+	}
+}
+
+// parseRouteTable 按格式解析路由表文本
+func parseRouteTable(text, format string) ([]route, error) {
+	if format == routeFormatAuto {
+		format = detectRouteFormat(text)
+		logger.Debugf("Detected routing table format: %s", format)
+	}
+
+	switch format {
+	case routeFormatLinux:
+		return parseLinuxRoutes(strings.NewReader(text))
+	case routeFormatCisco:
+		return parseCiscoRoutes(strings.NewReader(text))
+	case routeFormatHuawei:
+		return parseHuaweiRoutes(strings.NewReader(text))
+	default:
+		return nil, errors.New(errors.ValidationError, fmt.Sprintf("unknown routing table format %q", format))
+	}
+}
+
+// linuxRouteTypes ip route 输出中出现在目的地址之前的路由类型
+var linuxRouteTypes = map[string]bool{
+	"unicast": true, "local": true, "broadcast": true, "multicast": true, "anycast": true,
+	"blackhole": true, "unreachable": true, "prohibit": true, "throw": true, "nat": true,
+}
+
+// parseLinuxNextHop 解析 via/dev 参数，返回消耗的标记数
+func parseLinuxNextHop(fields []string, i int, nh *nextHop) (int, error) {
+	switch fields[i] {
+	case "via":
+		j := i + 1
+		if j < len(fields) && (fields[j] == "inet" || fields[j] == "inet6") {
+			j++
+		}
+		if j >= len(fields) {
+			return 0, errors.New(errors.ParseError, "via without address")
+		}
+		addr, err := parseAddr(fields[j])
+		if err != nil {
+			return 0, err
+		}
+		nh.Via = addr
+		return j - i + 1, nil
+	case "dev":
+		if i+1 >= len(fields) {
+			return 0, errors.New(errors.ParseError, "dev without interface")
+		}
+		nh.Interface = fields[i+1]
+		return 2, nil
+	}
+	return 0, nil
+}
+
+// parseLinuxRoutes 解析 ip route / ip -6 route 输出
+func parseLinuxRoutes(r io.Reader) ([]route, error) {
+	var routes []route
+	var pendingDefaults []int // 地址族待定的默认路由
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+
+	for scanner.Scan() {
+		lineNo++
+		line := scanner.Text()
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		// 多路径路由的 nexthop 续行
+		if fields[0] == "nexthop" {
+			if len(routes) == 0 {
+				return nil, errors.New(errors.ParseError, fmt.Sprintf("line %d: nexthop without a route", lineNo))
+			}
+			var nh nextHop
+			for i := 1; i < len(fields); i++ {
+				n, err := parseLinuxNextHop(fields, i, &nh)
+				if err != nil {
+					return nil, errors.Wrap(errors.ParseError, fmt.Sprintf("line %d", lineNo), err)
+				}
+				if n > 0 {
+					i += n - 1
+				}
+			}
+			last := &routes[len(routes)-1]
+			last.NextHops = append(last.NextHops, nh)
+			continue
+		}
+
+		rt := route{}
+		if linuxRouteTypes[fields[0]] {
+			if fields[0] != "unicast" {
+				rt.Type = fields[0]
+			}
+			fields = fields[1:]
+			if len(fields) == 0 {
+				return nil, errors.New(errors.ParseError, fmt.Sprintf("line %d: missing destination", lineNo))
+			}
+		}
+
+		isDefault := fields[0] == "default"
+		if !isDefault {
+			prefix, err := parsePrefix(fields[0])
+			if err != nil {
+				return nil, errors.Wrap(errors.ParseError, fmt.Sprintf("line %d", lineNo), err)
+			}
+			rt.Prefix = prefix
+		}
+
+		var nh nextHop
+		for i := 1; i < len(fields); i++ {
+			n, err := parseLinuxNextHop(fields, i, &nh)
+			if err != nil {
+				return nil, errors.Wrap(errors.ParseError, fmt.Sprintf("line %d", lineNo), err)
+			}
+			if n > 0 {
+				i += n - 1
+				continue
+			}
+			if i+1 >= len(fields) {
+				continue
+			}
+			switch fields[i] {
+			case "proto":
+				rt.Protocol = fields[i+1]
+				i++
+			case "metric":
+				rt.Metric = fields[i+1]
+				i++
+			case "nexthop":
+				// 同一行中的 nexthop（ip -o route）
+				if nh.Via.IsValid() || nh.Interface != "" {
+					rt.NextHops = append(rt.NextHops, nh)
+				}
+				nh = nextHop{}
+			}
+		}
+		if nh.Via.IsValid() || nh.Interface != "" {
+			rt.NextHops = append(rt.NextHops, nh)
+		}
+
+		if isDefault {
+			switch linuxRouteFamily(fields, rt.NextHops) {
+			case 4:
+				rt.Prefix = netip.MustParsePrefix("0.0.0.0/0")
+			case 6:
+				rt.Prefix = netip.MustParsePrefix("::/0")
+			default:
+				pendingDefaults = append(pendingDefaults, len(routes))
+			}
+		}
+		routes = append(routes, rt)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(errors.FileSystemError, "failed to read routing table", err)
+	}
+
+	// 行内没有地址的默认路由（如 ip -6 route 中的 default dev ppp0）按其他路由的地址族判断
+	defaultPrefix := netip.MustParsePrefix("0.0.0.0/0")
+	v4, v6 := false, false
+	for _, rt := range routes {
+		if rt.Prefix.IsValid() {
+			v4 = v4 || rt.Prefix.Addr().Is4()
+			v6 = v6 || rt.Prefix.Addr().Is6()
+		}
+	}
+	if v6 && !v4 {
+		defaultPrefix = netip.MustParsePrefix("::/0")
+	}
+	for _, i := range pendingDefaults {
+		routes[i].Prefix = defaultPrefix
+	}
+	return routes, nil
+}
+
+// linuxRouteFamily 根据下一跳、src 等地址或只出现在 ip -6 route 中的 pref 字段判断地址族
+// 无法判断时返回 0
+func linuxRouteFamily(fields []string, nextHops []nextHop) int {
+	family := func(addr netip.Addr) int {
+		if addr.Is4() {
+			return 4
+		}
+		return 6
+	}
+	for _, nh := range nextHops {
+		if nh.Via.IsValid() {
+			return family(nh.Via)
+		}
+	}
+	for _, field := range fields {
+		if addr, err := netip.ParseAddr(field); err == nil {
+			return family(addr)
+		}
+		if field == "pref" {
+			return 6
+		}
+	}
+	return 0
+}
+
+var (
+	ciscoMetricPattern    = regexp.MustCompile(`^\[(\d+/\d+)\]\s*`)
+	ciscoSubnettedPattern = regexp.MustCompile(`^(\S+)\s+is\s+subnetted`)
+	ciscoCodePattern      = regexp.MustCompile(`^[A-Za-z0-9*+%]{1,4}$`)
+)
+
+// classfulPrefix 没有掩码的 IPv4 目的地址按有类网络长度补全
+func classfulPrefix(addr netip.Addr) netip.Prefix {
+	bits, _ := classfulBits(addr)
+	if bits == 0 {
+		bits = 32
+	}
+	return netip.PrefixFrom(addr, bits).Masked()
+}
+
+// parseCiscoNextHop 解析 "via 地址, 时间, 接口" 或 "is directly connected, 接口"
+func parseCiscoNextHop(rest string) (nextHop, bool) {
+	var nh nextHop
+	var parts []string
+	switch {
+	case strings.HasPrefix(rest, "via "):
+		parts = strings.Split(strings.TrimPrefix(rest, "via "), ",")
+		first := strings.TrimSpace(parts[0])
+		if addr, err := parseAddr(first); err == nil {
+			nh.Via = addr
+		} else {
+			nh.Interface = first
+		}
+		parts = parts[1:]
+	case strings.HasPrefix(rest, "is directly connected"), strings.HasPrefix(rest, "is a summary"):
+		parts = strings.Split(rest, ",")[1:]
+	default:
+		return nh, false
+	}
+
+	// 接口名以字母开头，时间（00:01:02、1d02h）以数字开头
+	for _, part := range parts {
+		part = strings.TrimSpace(part)
+		if nh.Interface == "" && part != "" && isLetter(part[0]) && !strings.Contains(part, " ") {
+			nh.Interface = part
+		}
+	}
+	return nh, true
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// parseCiscoRoutes 解析 show ip route / show ipv6 route 输出
+func parseCiscoRoutes(r io.Reader) ([]route, error) {
+	var routes []route
+	// 最近一个 "网络/掩码 is subnetted" 标题：有类网络及其子网掩码长度
+	var subnetted netip.Prefix
+	subnetBits := 0
+	scanner := bufio.NewScanner(r)
+
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t")
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			continue
+		}
+
+		if line[0] == ' ' || line[0] == '\t' {
+			// "172.16.0.0/24 is subnetted" 给出后续无掩码条目的掩码
+			if m := ciscoSubnettedPattern.FindStringSubmatch(trimmed); m != nil {
+				if prefix, err := netip.ParsePrefix(m[1]); err == nil && !strings.Contains(trimmed, "variably") {
+					subnetted = classfulPrefix(prefix.Addr())
+					subnetBits = prefix.Bits()
+				}
+				continue
+			}
+
+			// 等价路径或 IPv6 格式中单独一行的下一跳
+			rest := trimmed
+			metric := ""
+			if m := ciscoMetricPattern.FindStringSubmatch(rest); m != nil {
+				metric = m[1]
+				rest = rest[len(m[0]):]
+			}
+			if nh, ok := parseCiscoNextHop(rest); ok && len(routes) > 0 {
+				last := &routes[len(routes)-1]
+				if last.Metric == "" {
+					last.Metric = metric
+				}
+				last.NextHops = append(last.NextHops, nh)
+			}
+			continue
+		}
+
+		fields := strings.Fields(line)
+		destIndex := -1
+		for i, field := range fields {
+			if _, err := netip.ParseAddr(strings.Split(field, "/")[0]); err == nil {
+				destIndex = i
+				break
+			}
+			if !ciscoCodePattern.MatchString(field) {
+				break
+			}
+		}
+		if destIndex < 1 {
+			continue
+		}
+
+		dest := fields[destIndex]
+		var prefix netip.Prefix
+		if strings.Contains(dest, "/") {
+			p, err := parsePrefix(dest)
+			if err != nil {
+				return nil, err
+			}
+			prefix = p
+		} else {
+			addr, err := parseAddr(dest)
+			if err != nil {
+				return nil, err
+			}
+			if subnetted.IsValid() && subnetted.Contains(addr) {
+				prefix = netip.PrefixFrom(addr, subnetBits)
+			} else {
+				prefix = classfulPrefix(addr)
+			}
+		}
+
+		protocol := strings.ReplaceAll(strings.Join(fields[:destIndex], " "), "*", "")
+		rt := route{Prefix: prefix, Protocol: strings.TrimSpace(protocol)}
+
+		rest := strings.TrimSpace(strings.SplitN(trimmed, dest, 2)[1])
+		if m := ciscoMetricPattern.FindStringSubmatch(rest); m != nil {
+			rt.Metric = m[1]
+			rest = rest[len(m[0]):]
+		}
+		if nh, ok := parseCiscoNextHop(rest); ok {
+			rt.NextHops = append(rt.NextHops, nh)
+		}
+		routes = append(routes, rt)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(errors.FileSystemError, "failed to read routing table", err)
+	}
+	return routes, nil
+}
+
+var huaweiFieldPattern = regexp.MustCompile(`(\w+)\s*:\s*(\S+)`)
+
+// parseHuaweiRoutes 解析 display ip routing-table（表格）与 display ipv6 routing-table（键值块）输出
+func parseHuaweiRoutes(r io.Reader) ([]route, error) {
+	var routes []route
+	var block map[string]string
+	scanner := bufio.NewScanner(r)
+
+	flushBlock := func() error {
+		if block == nil {
+			return nil
+		}
+		defer func() { block = nil }()
+
+		prefix, err := parsePrefix(block["Destination"] + "/" + block["PrefixLength"])
+		if err != nil {
+			return err
+		}
+		rt := route{Prefix: prefix, Protocol: block["Protocol"], Metric: block["Preference"] + "/" + block["Cost"]}
+		nh := nextHop{Interface: block["Interface"]}
+		if addr, err := parseAddr(block["NextHop"]); err == nil && !addr.IsUnspecified() && rt.Protocol != "Direct" {
+			nh.Via = addr
+		}
+		rt.NextHops = append(rt.NextHops, nh)
+		routes = append(routes, rt)
+		return nil
+	}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if matches := huaweiFieldPattern.FindAllStringSubmatch(line, -1); strings.Contains(line, " : ") && matches != nil {
+			for _, m := range matches {
+				if m[1] == "Destination" {
+					if err := flushBlock(); err != nil {
+						return nil, err
+					}
+					block = map[string]string{}
+				}
+				if block != nil {
+					block[m[1]] = m[2]
+				}
+			}
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 5 {
+			continue
+		}
+
+		if strings.Contains(fields[0], "/") {
+			prefix, err := parsePrefix(fields[0])
+			if err != nil || len(fields) < 6 {
+				continue
+			}
+			rt := route{Prefix: prefix}
+			if nh, ok := parseHuaweiColumns(fields[1:], &rt); ok {
+				rt.NextHops = append(rt.NextHops, nh)
+				routes = append(routes, rt)
+			}
+			continue
+		}
+
+		// 没有目的地址的续行是上一条路由的等价路径
+		if len(routes) > 0 {
+			var rt route
+			if nh, ok := parseHuaweiColumns(fields, &rt); ok && rt.Protocol == routes[len(routes)-1].Protocol {
+				last := &routes[len(routes)-1]
+				last.NextHops = append(last.NextHops, nh)
+			}
+		}
+	}
+	if err := flushBlock(); err != nil {
+		return nil, err
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(errors.FileSystemError, "failed to read routing table", err)
+	}
+	return routes, nil
+}
+
+// parseHuaweiColumns 解析 Proto Pre Cost [Flags] NextHop Interface 各列
+func parseHuaweiColumns(fields []string, rt *route) (nextHop, bool) {
+	if len(fields) != 5 && len(fields) != 6 {
+		return nextHop{}, false
+	}
+	if _, err := strconv.Atoi(fields[1]); err != nil {
+		return nextHop{}, false
+	}
+	via, err := parseAddr(fields[len(fields)-2])
+	if err != nil {
+		return nextHop{}, false
+	}
+
+	rt.Protocol = fields[0]
+	rt.Metric = fields[1] + "/" + fields[2]
+	nh := nextHop{Via: via, Interface: fields[len(fields)-1]}
+	if rt.Protocol == "Direct" {
+		nh.Via = netip.Addr{}
+	}
+	return nh, true
+}
+
+// metricKey 将度量转换为可比较的整数序列，无法解析的部分视为 0
+func metricKey(metric string) []int {
+	var key []int
+	for _, part := range strings.Split(metric, "/") {
+		n, _ := strconv.Atoi(part)
+		key = append(key, n)
+	}
+	return key
+}
+
+// compareMetric 比较两个度量，较小者优先
+func compareMetric(a, b string) int {
+	ka, kb := metricKey(a), metricKey(b)
+	for i := 0; i < len(ka) && i < len(kb); i++ {
+		if ka[i] != kb[i] {
+			if ka[i] < kb[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return len(ka) - len(kb)
+}
+
+// matchRoutes 返回包含 addr 的所有路由，按前缀长度从长到短、度量从小到大排序
+func matchRoutes(routes []route, addr netip.Addr) []route {
+	var matches []route
+	for _, rt := range routes {
+		if rt.Prefix.Contains(addr) {
+			matches = append(matches, rt)
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Prefix.Bits() != matches[j].Prefix.Bits() {
+			return matches[i].Prefix.Bits() > matches[j].Prefix.Bits()
+		}
+		return compareMetric(matches[i].Metric, matches[j].Metric) < 0
+	})
+	return matches
+}
+
+// selectRoute 按最长前缀匹配选出转发路由，相同前缀、相同度量的多条路由合并为等价路径
+func selectRoute(matches []route) (route, bool) {
+	if len(matches) == 0 {
+		return route{}, false
+	}
+
+	best := matches[0]
+	best.NextHops = append([]nextHop(nil), best.NextHops...)
+	for _, rt := range matches[1:] {
+		if rt.Prefix != best.Prefix || compareMetric(rt.Metric, best.Metric) != 0 {
+			break
+		}
+		best.NextHops = append(best.NextHops, rt.NextHops...)
+	}
+	return best, true
+}
+
+// readTextInput 读取整个输入文件（路由表、ACL 配置等），路径为 "-" 时读取标准输入
+func readTextInput(path string) (string, error) {
+	if path == "-" {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return "", errors.Wrap(errors.FileSystemError, "failed to read stdin", err)
+		}
+		return string(data), nil
+	}
+
+	if err := validator.ValidateFilePath(path); err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", errors.Wrap(errors.FileSystemError, fmt.Sprintf("failed to read %s", path), err)
+	}
+	return string(data), nil
+}
+
+func printRoute(rt route, indent string) {
+	fmt.Printf("%sRoute: %s\n", indent, rt)
+	for _, nh := range rt.NextHops {
+		fmt.Printf("%s  %s\n", indent, nh)
+	}
+}
+
+func lookupRoutes(cmd *cobra.Command, args []string) {
+	file, _ := cmd.Flags().GetString("file")
+	format, _ := cmd.Flags().GetString("format")
+	showAll, _ := cmd.Flags().GetBool("all")
+
+	if file == "" {
+		logger.PrintValidationError("--file is required")
+		if err := cmd.Help(); err != nil {
+			logger.PrintErrorWithMessage("failed to show help", err)
+		}
+		return
+	}
+
+	text, err := readTextInput(file)
+	if err != nil {
+		logger.PrintErrorWithMessage("failed to read routing table", err)
+		return
+	}
+	routes, err := parseRouteTable(text, format)
+	if err != nil {
+		logger.PrintErrorWithMessage("failed to parse routing table", err)
+		return
+	}
+	logger.Debugf("Parsed %d routes", len(routes))
+
+	if len(args) == 0 {
+		for _, rt := range routes {
+			printRoute(rt, "")
+		}
+		return
+	}
+
+	for i, arg := range args {
+		addr, err := parseAddr(arg)
+		if err != nil {
+			logger.PrintErrorWithMessage("failed to parse address", err)
+			continue
+		}
+		if i > 0 {
+			fmt.Println()
+		}
+
+		fmt.Println("Destination:", addr)
+		matches := matchRoutes(routes, addr)
+		best, ok := selectRoute(matches)
+		if !ok {
+			fmt.Println("Route: none")
+			continue
+		}
+		printRoute(best, "")
+		if len(best.NextHops) > 1 {
+			fmt.Println("ECMP Paths:", len(best.NextHops))
+		}
+
+		if showAll && len(matches) > 1 {
+			fmt.Println("Other Matching Routes:")
+			for _, rt := range matches[1:] {
+				if rt.Prefix == best.Prefix && compareMetric(rt.Metric, best.Metric) == 0 {
+					continue
+				}
+				printRoute(rt, "  ")
+			}
+		}
+	}
+
+	logger.Infof("Successfully looked up %d destinations in %d routes", len(args), len(routes))
+}
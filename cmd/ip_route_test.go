@@ -0,0 +1,289 @@
+//go:build unit
+
+/*
+Copyright © 2024-2025 Auska <luodan0709@live.cn>
+
+*/
+
+package cmd
+
+import (
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+const linuxRouteSample = `default via 192.168.1.1 dev eth0 proto dhcp src 192.168.1.10 metric 100
+default via 192.168.1.254 dev eth1 proto static metric 200
+10.0.0.0/8 via 10.1.1.1 dev eth1 proto static
+10.2.0.0/16 proto bird metric 32
+	nexthop via 10.1.1.1 dev eth1 weight 1
+	nexthop via 10.1.1.2 dev eth2 weight 1
+192.168.1.0/24 dev eth0 proto kernel scope link src 192.168.1.10 metric 100
+blackhole 10.99.0.0/16
+default via fe80::1 dev eth0 proto ra metric 1024 expires 1789sec pref medium
+`
+
+const ciscoRouteSample = `Codes: L - local, C - connected, S - static, R - RIP, M - mobile, B - BGP
+       D - EIGRP, EX - EIGRP external, O - OSPF, IA - OSPF inter area
+
+Gateway of last resort is 10.0.0.1 to network 0.0.0.0
+
+S*    0.0.0.0/0 [1/0] via 10.0.0.1
+      10.0.0.0/8 is variably subnetted, 4 subnets, 3 masks
+C        10.0.0.0/24 is directly connected, GigabitEthernet0/0
+L        10.0.0.2/32 is directly connected, GigabitEthernet0/0
+O        10.1.0.0/16 [110/20] via 10.0.0.3, 00:01:02, GigabitEthernet0/0
+                     [110/20] via 10.0.0.4, 00:01:02, GigabitEthernet0/1
+O IA     10.1.2.0/24 [110/30] via 10.0.0.3, 1d02h, GigabitEthernet0/0
+      172.16.0.0/24 is subnetted, 2 subnets
+S        172.16.1.0 [1/0] via 10.0.0.9
+D EX     172.16.2.0 [170/2816] via 10.0.0.5, 2w0d, GigabitEthernet0/2
+B     203.0.113.0 [20/0] via 198.51.100.1, 2w0d
+`
+
+const ciscoIPv6RouteSample = `IPv6 Routing Table - default - 4 entries
+Codes: C - Connected, L - Local, S - Static, U - Per-user Static route
+C   2001:DB8:1::/64 [0/0]
+     via GigabitEthernet0/0, directly connected
+L   2001:DB8:1::1/128 [0/0]
+     via GigabitEthernet0/0, receive
+S   ::/0 [1/0]
+     via 2001:DB8:1::FE
+O   2001:DB8:2::/64 [110/2]
+     via FE80::2, GigabitEthernet0/1
+     via FE80::3, GigabitEthernet0/2
+`
+
+const huaweiRouteSample = `Route Flags: R - relay, D - download to fib
+------------------------------------------------------------------------------
+Routing Tables: Public
+         Destinations : 4        Routes : 5
+
+Destination/Mask    Proto   Pre  Cost      Flags NextHop         Interface
+
+        0.0.0.0/0   Static  60   0          RD   10.1.1.1        GigabitEthernet0/0/1
+       10.1.1.0/24  Direct  0    0           D   10.1.1.2        GigabitEthernet0/0/1
+       10.2.0.0/16  OSPF    10   2           D   10.1.1.3        GigabitEthernet0/0/1
+                    OSPF    10   2           D   10.1.1.4        GigabitEthernet0/0/2
+      127.0.0.0/8   Direct  0    0           D   127.0.0.1       InLoopBack0
+`
+
+const huaweiIPv6RouteSample = `Routing Table : Public
+         Destinations : 2        Routes : 2
+
+ Destination  : ::1                             PrefixLength : 128
+ NextHop      : ::1                             Preference   : 0
+ Cost         : 0                               Protocol     : Direct
+ RelayNextHop : ::                              TunnelID     : 0x0
+ Interface    : InLoopBack0                     Flags        : D
+
+ Destination  : 2001:DB8::                      PrefixLength : 32
+ NextHop      : FE80::1                         Preference   : 60
+ Cost         : 0                               Protocol     : Static
+ RelayNextHop : ::                              TunnelID     : 0x0
+ Interface    : GigabitEthernet0/0/1            Flags        : RD
+`
+
+func TestDetectRouteFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		expected string
+	}{
+		{"Linux", linuxRouteSample, routeFormatLinux},
+		{"Cisco", ciscoRouteSample, routeFormatCisco},
+		{"Cisco IPv6", ciscoIPv6RouteSample, routeFormatCisco},
+		{"Huawei", huaweiRouteSample, routeFormatHuawei},
+		{"Huawei IPv6", huaweiIPv6RouteSample, routeFormatHuawei},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := detectRouteFormat(tt.text); result != tt.expected {
+				t.Errorf("detectRouteFormat() = %s, want %s", result, tt.expected)
+			}
+		})
+	}
+}
+
+// routeSummary 将路由表压缩为 "前缀 协议 度量 下一跳;下一跳" 便于比较
+func routeSummary(routes []route) []string {
+	var out []string
+	for _, rt := range routes {
+		var hops []string
+		for _, nh := range rt.NextHops {
+			hops = append(hops, nh.String())
+		}
+		fields := []string{rt.Prefix.String(), rt.Type, rt.Protocol, rt.Metric, strings.Join(hops, ";")}
+		out = append(out, strings.TrimSpace(strings.Join(fields, "|")))
+	}
+	return out
+}
+
+func TestParseRouteTables(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		expected []string
+	}{
+		{"Linux", linuxRouteSample, []string{
+			"0.0.0.0/0||dhcp|100|via 192.168.1.1 dev eth0",
+			"0.0.0.0/0||static|200|via 192.168.1.254 dev eth1",
+			"10.0.0.0/8||static||via 10.1.1.1 dev eth1",
+			"10.2.0.0/16||bird|32|via 10.1.1.1 dev eth1;via 10.1.1.2 dev eth2",
+			"192.168.1.0/24||kernel|100|directly connected dev eth0",
+			"10.99.0.0/16|blackhole|||",
+			"::/0||ra|1024|via fe80::1 dev eth0",
+		}},
+		{"Cisco", ciscoRouteSample, []string{
+			"0.0.0.0/0||S|1/0|via 10.0.0.1",
+			"10.0.0.0/24||C||directly connected dev GigabitEthernet0/0",
+			"10.0.0.2/32||L||directly connected dev GigabitEthernet0/0",
+			"10.1.0.0/16||O|110/20|via 10.0.0.3 dev GigabitEthernet0/0;via 10.0.0.4 dev GigabitEthernet0/1",
+			"10.1.2.0/24||O IA|110/30|via 10.0.0.3 dev GigabitEthernet0/0",
+			"172.16.1.0/24||S|1/0|via 10.0.0.9",
+			"172.16.2.0/24||D EX|170/2816|via 10.0.0.5 dev GigabitEthernet0/2",
+			"203.0.113.0/24||B|20/0|via 198.51.100.1",
+		}},
+		{"Cisco IPv6", ciscoIPv6RouteSample, []string{
+			"2001:db8:1::/64||C|0/0|directly connected dev GigabitEthernet0/0",
+			"2001:db8:1::1/128||L|0/0|directly connected dev GigabitEthernet0/0",
+			"::/0||S|1/0|via 2001:db8:1::fe",
+			"2001:db8:2::/64||O|110/2|via fe80::2 dev GigabitEthernet0/1;via fe80::3 dev GigabitEthernet0/2",
+		}},
+		{"Huawei", huaweiRouteSample, []string{
+			"0.0.0.0/0||Static|60/0|via 10.1.1.1 dev GigabitEthernet0/0/1",
+			"10.1.1.0/24||Direct|0/0|directly connected dev GigabitEthernet0/0/1",
+			"10.2.0.0/16||OSPF|10/2|via 10.1.1.3 dev GigabitEthernet0/0/1;via 10.1.1.4 dev GigabitEthernet0/0/2",
+			"127.0.0.0/8||Direct|0/0|directly connected dev InLoopBack0",
+		}},
+		{"Huawei IPv6", huaweiIPv6RouteSample, []string{
+			"::1/128||Direct|0/0|directly connected dev InLoopBack0",
+			"2001:db8::/32||Static|60/0|via fe80::1 dev GigabitEthernet0/0/1",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			routes, err := parseRouteTable(tt.text, routeFormatAuto)
+			if err != nil {
+				t.Fatalf("parseRouteTable() error = %v", err)
+			}
+			result := routeSummary(routes)
+			if len(result) != len(tt.expected) {
+				t.Fatalf("parseRouteTable() =\n%s\nwant\n%s", strings.Join(result, "\n"), strings.Join(tt.expected, "\n"))
+			}
+			for i := range result {
+				if result[i] != tt.expected[i] {
+					t.Errorf("route %d = %s, want %s", i, result[i], tt.expected[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseLinuxDefaultRouteFamily(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		expected []string
+	}{
+		{"IPv6 default with pref", "default dev ppp0 proto static metric 1024 pref medium\n",
+			[]string{"::/0||static|1024|directly connected dev ppp0"}},
+		{"IPv4 default with src", "default dev wg0 scope link src 10.8.0.2\n",
+			[]string{"0.0.0.0/0||||directly connected dev wg0"}},
+		{"IPv6-only table", "default dev tun0 metric 1\n2001:db8::/64 dev eth0 proto kernel metric 256\n",
+			[]string{"::/0|||1|directly connected dev tun0", "2001:db8::/64||kernel|256|directly connected dev eth0"}},
+		{"Unknown family defaults to IPv4", "default dev tun0\n",
+			[]string{"0.0.0.0/0||||directly connected dev tun0"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			routes, err := parseLinuxRoutes(strings.NewReader(tt.text))
+			if err != nil {
+				t.Fatalf("parseLinuxRoutes() error = %v", err)
+			}
+			result := routeSummary(routes)
+			if strings.Join(result, "\n") != strings.Join(tt.expected, "\n") {
+				t.Errorf("parseLinuxRoutes() =\n%s\nwant\n%s", strings.Join(result, "\n"), strings.Join(tt.expected, "\n"))
+			}
+		})
+	}
+}
+
+func TestParseLinuxRoutesErrors(t *testing.T) {
+	for _, text := range []string{
+		"nexthop via 10.0.0.1",
+		"10.0.0.0/33 dev eth0",
+		"10.0.0.0/8 via",
+		"blackhole",
+	} {
+		if _, err := parseLinuxRoutes(strings.NewReader(text)); err == nil {
+			t.Errorf("parseLinuxRoutes(%q) expected error", text)
+		}
+	}
+}
+
+func TestCompareMetric(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"100", "200", -1},
+		{"", "100", -1},
+		{"110/20", "110/20", 0},
+		{"1/0", "110/20", -1},
+		{"110/30", "110/20", 1},
+	}
+
+	for _, tt := range tests {
+		if result := compareMetric(tt.a, tt.b); result != tt.expected {
+			t.Errorf("compareMetric(%q, %q) = %d, want %d", tt.a, tt.b, result, tt.expected)
+		}
+	}
+}
+
+func TestSelectRoute(t *testing.T) {
+	routes, err := parseRouteTable(linuxRouteSample+"10.2.0.0/16 via 10.1.1.9 dev eth3 proto bird metric 32\n", routeFormatLinux)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		addr     string
+		prefix   string
+		nextHops int
+		matches  int
+	}{
+		{"10.2.3.4", "10.2.0.0/16", 3, 5},
+		{"10.3.0.1", "10.0.0.0/8", 1, 3},
+		{"8.8.8.8", "0.0.0.0/0", 1, 2},
+		{"192.168.1.50", "192.168.1.0/24", 1, 3},
+		{"2001:db8::1", "::/0", 1, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			matches := matchRoutes(routes, netip.MustParseAddr(tt.addr))
+			if len(matches) != tt.matches {
+				t.Errorf("matchRoutes() returned %d routes, want %d", len(matches), tt.matches)
+			}
+			best, ok := selectRoute(matches)
+			if !ok {
+				t.Fatal("selectRoute() found no route")
+			}
+			if best.Prefix.String() != tt.prefix || len(best.NextHops) != tt.nextHops {
+				t.Errorf("selectRoute() = %s with %d next hops, want %s with %d", best.Prefix, len(best.NextHops), tt.prefix, tt.nextHops)
+			}
+		})
+	}
+
+	if best, _ := selectRoute(matchRoutes(routes, netip.MustParseAddr("8.8.8.8"))); best.Metric != "100" {
+		t.Errorf("default route metric = %s, want the lower metric 100", best.Metric)
+	}
+	if _, ok := selectRoute(nil); ok {
+		t.Error("selectRoute(nil) should find nothing")
+	}
+}
@@ -0,0 +1,470 @@
+/*
+Copyright © 2024-2025 Auska <luodan0709@live.cn>
+
+*/
+
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math/bits"
+	"math/rand"
+	"net"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"macconv/pkg/errors"
+	"macconv/pkg/logger"
+)
+
+// RFC 7217 第 6 节与 RFC 8981 第 3.8 节的默认参数
+const (
+	idgenRetries          = 3
+	tempPreferredLifetime = 24 * time.Hour
+	tempValidLifetime     = 48 * time.Hour
+	tempRegenAdvance      = 5 * time.Second // 2 + TEMP_IDGEN_RETRIES * DupAddrDetectTransmits * RetransTimer
+	maxDesyncFactor       = 0.4             // MAX_DESYNC_FACTOR 占 TEMP_PREFERRED_LIFETIME 的比例
+)
+
+// slaacModes 支持的接口标识生成方式
+var slaacModes = []string{"eui64", "stable", "linux", "temporary"}
+
+var ipSLAACCmd = &cobra.Command{
+	Use:   "slaac PREFIX",
+	Short: "Compute SLAAC addresses with EUI-64, stable (RFC 7217, Linux) or RFC 8981 temporary IIDs",
+	Long: `
+Compute the addresses a host will form from a /64 prefix:
+
+  eui64      modified EUI-64 from --mac (RFC 4291)
+  stable     the example function F of RFC 7217: the low 64 bits of
+             SHA-256(prefix || interface || 0 || network ID || 0 || DAD counter || secret)
+  linux      what the Linux kernel forms with addr_gen_mode 2 or 3: one SHA-1
+             block over (secret || prefix || hardware address || DAD counter),
+             keeping the first two digest words as stored on little-endian hosts
+  temporary  RFC 8981 randomized IIDs with their preferred/valid lifetimes
+
+The stable mode is a generic RFC 7217 implementation; it does not predict the
+Linux kernel or NetworkManager. For the linux mode pass the value of
+net.ipv6.conf.<iface>.stable_secret as --secret and the permanent MAC
+address as --mac; the interface name and network ID are not used. Reserved
+IIDs (RFC 5453) are skipped by incrementing the DAD counter or drawing again.
+By default every mode whose inputs are given is shown. For example:
+
+	macconv ip slaac 2001:db8:1:2::/64 --mac 00:11:22:33:44:55
+	macconv ip slaac 2001:db8:1:2::/64 --mode stable --iface eth0 --secret 0123456789abcdef0123456789abcdef
+	macconv ip slaac 2001:db8:1:2::/64 --mode stable --iface wlan0 --network-id office-wifi --dad 1 --secret ::1
+	macconv ip slaac 2001:db8:1:2::/64 --mode linux --mac 00:11:22:33:44:55 --secret 2001:db8::1234
+	macconv ip slaac 2001:db8:1:2::/64 --mode temporary --count 3 --seed 42`,
+	Run: runSLAAC,
+}
+
+func init() {
+	ipCmd.AddCommand(ipSLAACCmd)
+	ipSLAACCmd.Flags().StringSlice("mode", nil, "Modes to show: "+strings.Join(slaacModes, ", ")+" (default: all with inputs)")
+	ipSLAACCmd.Flags().String("mac", "", "MAC address for the EUI-64 and linux interface IDs")
+	ipSLAACCmd.Flags().String("iface", "eth0", "Interface name (RFC 7217 Net_Iface)")
+	ipSLAACCmd.Flags().String("network-id", "", "Network identifier such as an SSID (RFC 7217 Network_ID)")
+	ipSLAACCmd.Flags().Int("dad", 0, "DAD counter (RFC 7217 DAD_Counter)")
+	ipSLAACCmd.Flags().String("secret", "", "Stable secret key, hex or IPv6 address form")
+	ipSLAACCmd.Flags().Int("count", 1, "Number of consecutive temporary addresses")
+	ipSLAACCmd.Flags().Duration("preferred-lifetime", tempPreferredLifetime, "TEMP_PREFERRED_LIFETIME")
+	ipSLAACCmd.Flags().Duration("valid-lifetime", tempValidLifetime, "TEMP_VALID_LIFETIME")
+	ipSLAACCmd.Flags().Int64("seed", 0, "Random seed for temporary addresses (default: current time)")
+}
+
+// isReservedIID 判断接口标识是否在 RFC 5453 保留范围内：Subnet-Router 任播（全零）、
+// 0200:5eff:fe00:0000-0200:5eff:feff:ffff（其中 0200:5eff:fe00:5213 分配给 Proxy Mobile IPv6）
+// 及子网保留任播 fdff:ffff:ffff:ff80-ffff
+func isReservedIID(iid [8]byte) bool {
+	switch {
+	case iid == [8]byte{}:
+		return true
+	case iid[0] == 0x02 && iid[1] == 0x00 && iid[2] == 0x5e && iid[3] == 0xff && iid[4] == 0xfe:
+		return true
+	case iid[0] == 0xfd && iid[1] == 0xff && iid[2] == 0xff && iid[3] == 0xff &&
+		iid[4] == 0xff && iid[5] == 0xff && iid[6] == 0xff && iid[7] >= 0x80:
+		return true
+	}
+	return false
+}
+
+// withIID 用接口标识替换地址的低 64 位
+func withIID(prefix netip.Prefix, iid [8]byte) netip.Addr {
+	b := prefix.Masked().Addr().As16()
+	copy(b[8:], iid[:])
+	return netip.AddrFrom16(b)
+}
+
+// formatIID 以 xxxx:xxxx:xxxx:xxxx 形式显示接口标识
+func formatIID(iid [8]byte) string {
+	return groupString(hex.EncodeToString(iid[:]), 4, ":")
+}
+
+// parseStableSecret 解析十六进制或 IPv6 地址形式的密钥
+func parseStableSecret(s string) ([]byte, error) {
+	if addr, err := netip.ParseAddr(s); err == nil && addr.Is6() {
+		b := addr.As16()
+		return b[:], nil
+	}
+	secret, err := hex.DecodeString(strings.TrimPrefix(strings.ToLower(s), "0x"))
+	if err != nil || len(secret) == 0 {
+		return nil, errors.New(errors.ValidationError,
+			fmt.Sprintf("invalid secret %q, expected hex digits or an IPv6 address", s))
+	}
+	return secret, nil
+}
+
+// linuxStableIID 按 Linux 内核 ipv6_generate_stable_address 计算接口标识：
+// 对 secret(16) || 前缀(8) || 硬件地址(32，不足补零) || DAD 计数(1) 补零到 64 字节后只做一次 SHA-1 压缩，
+// 不做消息填充，前两个摘要字按小端主机的内存顺序作为接口标识；结果为保留值时递增 DAD 计数，最多到 idgen_retries
+func linuxStableIID(prefix netip.Prefix, hwaddr net.HardwareAddr, dad int, secret [16]byte) ([8]byte, int, error) {
+	var block [64]byte
+	copy(block[:16], secret[:])
+	p := prefix.Masked().Addr().As16()
+	copy(block[16:24], p[:8])
+	copy(block[24:56], hwaddr)
+
+	for ; ; dad++ {
+		block[56] = byte(dad)
+		h := sha1Init
+		sha1Block(&h, &block)
+
+		var iid [8]byte
+		binary.LittleEndian.PutUint32(iid[:4], h[0])
+		binary.LittleEndian.PutUint32(iid[4:], h[1])
+		if !isReservedIID(iid) {
+			return iid, dad, nil
+		}
+		logger.Debugf("Linux stable IID %s with DAD counter %d is reserved, retrying", formatIID(iid), dad)
+		if dad >= idgenRetries {
+			return [8]byte{}, 0, errors.New(errors.ValidationError,
+				fmt.Sprintf("no usable stable IID after %d retries", idgenRetries))
+		}
+	}
+}
+
+// sha1Init SHA-1 的初始摘要（FIPS 180-4 第 5.3.1 节）
+var sha1Init = [5]uint32{0x67452301, 0xefcdab89, 0x98badcfe, 0x10325476, 0xc3d2e1f0}
+
+// sha1Block 对一个 64 字节分组执行 SHA-1 压缩函数，crypto/sha1 没有导出这一步
+func sha1Block(h *[5]uint32, block *[64]byte) {
+	var w [80]uint32
+	for i := 0; i < 16; i++ {
+		w[i] = binary.BigEndian.Uint32(block[i*4:])
+	}
+	for i := 16; i < 80; i++ {
+		w[i] = bits.RotateLeft32(w[i-3]^w[i-8]^w[i-14]^w[i-16], 1)
+	}
+
+	a, b, c, d, e := h[0], h[1], h[2], h[3], h[4]
+	for i := 0; i < 80; i++ {
+		var f, k uint32
+		switch {
+		case i < 20:
+			f, k = b&c|^b&d, 0x5a827999
+		case i < 40:
+			f, k = b^c^d, 0x6ed9eba1
+		case i < 60:
+			f, k = b&c|b&d|c&d, 0x8f1bbcdc
+		default:
+			f, k = b^c^d, 0xca62c1d6
+		}
+		t := bits.RotateLeft32(a, 5) + f + e + k + w[i]
+		a, b, c, d, e = t, a, bits.RotateLeft32(b, 30), c, d
+	}
+	h[0] += a
+	h[1] += b
+	h[2] += c
+	h[3] += d
+	h[4] += e
+}
+
+// stableIIDParams RFC 7217 中函数 F 的输入
+type stableIIDParams struct {
+	Prefix    netip.Prefix
+	Iface     string
+	NetworkID string
+	DAD       int
+	Secret    []byte
+}
+
+// stableIID 按 RFC 7217 计算接口标识，结果为保留值时递增 DAD 计数重试，返回最终使用的计数
+func stableIID(p stableIIDParams) ([8]byte, int, error) {
+	prefix := p.Prefix.Masked().Addr().As16()
+	for dad := p.DAD; dad <= p.DAD+idgenRetries; dad++ {
+		h := sha256.New()
+		h.Write(prefix[:8])
+		h.Write([]byte(p.Iface))
+		h.Write([]byte{0})
+		h.Write([]byte(p.NetworkID))
+		h.Write([]byte{0, byte(dad)})
+		h.Write(p.Secret)
+		sum := h.Sum(nil)
+
+		var iid [8]byte
+		copy(iid[:], sum[len(sum)-8:])
+		if !isReservedIID(iid) {
+			return iid, dad, nil
+		}
+		logger.Debugf("Stable IID %s with DAD counter %d is reserved, retrying", formatIID(iid), dad)
+	}
+	return [8]byte{}, 0, errors.New(errors.ValidationError,
+		fmt.Sprintf("no usable stable IID after %d retries", idgenRetries))
+}
+
+// temporaryAddress 一个临时地址及其相对于第一个地址生成时刻的时间线
+type temporaryAddress struct {
+	Addr      netip.Addr
+	Start     time.Duration
+	Preferred time.Duration // 首选生命周期结束时刻
+	Valid     time.Duration // 有效生命周期结束时刻
+}
+
+// temporaryAddresses 按 RFC 8981 第 3.3.1 节生成连续的临时地址：
+// 接口标识为 64 位随机数，每个地址的 DESYNC_FACTOR 单独随机，
+// 在首选生命周期结束前 REGEN_ADVANCE 生成下一个地址
+func temporaryAddresses(prefix netip.Prefix, count int, preferred, valid time.Duration, rng *rand.Rand) []temporaryAddress {
+	used := make(map[[8]byte]bool)
+	addrs := make([]temporaryAddress, 0, count)
+	start := time.Duration(0)
+	for len(addrs) < count {
+		var iid [8]byte
+		binary.BigEndian.PutUint64(iid[:], rng.Uint64())
+		if isReservedIID(iid) || used[iid] {
+			continue
+		}
+		used[iid] = true
+
+		desync := time.Duration(rng.Int63n(int64(float64(preferred)*maxDesyncFactor) + 1))
+		addr := temporaryAddress{
+			Addr:      withIID(prefix, iid),
+			Start:     start,
+			Preferred: start + preferred - desync,
+			Valid:     start + valid,
+		}
+		addrs = append(addrs, addr)
+		start = max(addr.Preferred-tempRegenAdvance, start)
+	}
+	return addrs
+}
+
+// selectedSLAACModes 返回要显示的模式，未指定时按已有输入推断
+func selectedSLAACModes(cmd *cobra.Command) ([]string, error) {
+	modes, _ := cmd.Flags().GetStringSlice("mode")
+	if len(modes) == 0 {
+		if cmd.Flags().Changed("mac") {
+			modes = append(modes, "eui64")
+		}
+		if cmd.Flags().Changed("secret") {
+			modes = append(modes, "stable")
+			if cmd.Flags().Changed("mac") {
+				modes = append(modes, "linux")
+			}
+		}
+		return append(modes, "temporary"), nil
+	}
+
+	for _, mode := range modes {
+		valid := false
+		for _, known := range slaacModes {
+			valid = valid || mode == known
+		}
+		if !valid {
+			return nil, errors.New(errors.ValidationError,
+				fmt.Sprintf("unknown mode %q, expected %s", mode, strings.Join(slaacModes, ", ")))
+		}
+	}
+	return modes, nil
+}
+
+func printEUI64Address(cmd *cobra.Command, prefix netip.Prefix) bool {
+	macFlag, _ := cmd.Flags().GetString("mac")
+	if macFlag == "" {
+		logger.PrintValidationError("--mac is required for EUI-64 addresses")
+		return false
+	}
+	mac, err := parseMACAddress(macFlag)
+	if err != nil {
+		logger.PrintErrorWithMessage("invalid MAC address", err)
+		return false
+	}
+
+	iid := macToEUI64(mac)
+	fmt.Println("Method: EUI-64 (RFC 4291)")
+	fmt.Println("MAC Address:", mac)
+	fmt.Println("Interface ID:", formatIID(iid))
+	fmt.Println("Address:", withIID(prefix, iid))
+	return true
+}
+
+func printStableAddress(cmd *cobra.Command, prefix netip.Prefix) bool {
+	secretFlag, _ := cmd.Flags().GetString("secret")
+	if secretFlag == "" {
+		logger.PrintValidationError("--secret is required for RFC 7217 stable addresses")
+		return false
+	}
+	secret, err := parseStableSecret(secretFlag)
+	if err != nil {
+		logger.PrintError(err)
+		return false
+	}
+
+	params := stableIIDParams{Prefix: prefix, Secret: secret}
+	params.Iface, _ = cmd.Flags().GetString("iface")
+	params.NetworkID, _ = cmd.Flags().GetString("network-id")
+	params.DAD, _ = cmd.Flags().GetInt("dad")
+	if params.DAD < 0 || params.DAD > 255 {
+		logger.PrintValidationError("DAD counter must be between 0 and 255")
+		return false
+	}
+
+	iid, dad, err := stableIID(params)
+	if err != nil {
+		logger.PrintError(err)
+		return false
+	}
+
+	fmt.Println("Method: Stable (RFC 7217 example function, SHA-256)")
+	fmt.Println("Interface:", params.Iface)
+	if params.NetworkID != "" {
+		fmt.Println("Network ID:", params.NetworkID)
+	}
+	if dad != params.DAD {
+		fmt.Printf("DAD Counter: %d (reserved IID skipped from %d)\n", dad, params.DAD)
+	} else {
+		fmt.Println("DAD Counter:", dad)
+	}
+	fmt.Println("Interface ID:", formatIID(iid))
+	fmt.Println("Address:", withIID(prefix, iid))
+	return true
+}
+
+func printLinuxStableAddress(cmd *cobra.Command, prefix netip.Prefix) bool {
+	secretFlag, _ := cmd.Flags().GetString("secret")
+	macFlag, _ := cmd.Flags().GetString("mac")
+	if secretFlag == "" || macFlag == "" {
+		logger.PrintValidationError("--secret and --mac are required for Linux stable addresses")
+		return false
+	}
+	secret, err := parseStableSecret(secretFlag)
+	if err != nil {
+		logger.PrintError(err)
+		return false
+	}
+	if len(secret) != 16 {
+		logger.PrintValidationError(fmt.Sprintf("Linux stable_secret is 128 bits, got %d", len(secret)*8))
+		return false
+	}
+	mac, err := parseMACAddress(macFlag)
+	if err != nil {
+		logger.PrintErrorWithMessage("invalid MAC address", err)
+		return false
+	}
+	start, _ := cmd.Flags().GetInt("dad")
+	if start < 0 || start > 255 {
+		logger.PrintValidationError("DAD counter must be between 0 and 255")
+		return false
+	}
+
+	iid, dad, err := linuxStableIID(prefix, mac, start, [16]byte(secret))
+	if err != nil {
+		logger.PrintError(err)
+		return false
+	}
+
+	fmt.Println("Method: Stable (Linux kernel, addr_gen_mode 2/3)")
+	fmt.Println("MAC Address:", mac)
+	if dad != start {
+		fmt.Printf("DAD Counter: %d (reserved IID skipped from %d)\n", dad, start)
+	} else {
+		fmt.Println("DAD Counter:", dad)
+	}
+	fmt.Println("Interface ID:", formatIID(iid))
+	fmt.Println("Address:", withIID(prefix, iid))
+	return true
+}
+
+func printTemporaryAddresses(cmd *cobra.Command, prefix netip.Prefix) bool {
+	count, _ := cmd.Flags().GetInt("count")
+	preferred, _ := cmd.Flags().GetDuration("preferred-lifetime")
+	valid, _ := cmd.Flags().GetDuration("valid-lifetime")
+	seed, _ := cmd.Flags().GetInt64("seed")
+
+	if count < 1 {
+		logger.PrintValidationError("count must be at least 1")
+		return false
+	}
+	if preferred <= tempRegenAdvance || valid < preferred {
+		logger.PrintValidationError(fmt.Sprintf(
+			"preferred lifetime must exceed %s and must not exceed the valid lifetime", tempRegenAdvance))
+		return false
+	}
+
+	if !cmd.Flags().Changed("seed") {
+		seed = time.Now().UnixNano()
+	}
+	logger.Debugf("Generating %d temporary addresses with seed %d", count, seed)
+	rng := rand.New(rand.NewSource(seed)) // #nosec G404 -- reproducible test addresses, not real privacy addresses
+
+	fmt.Println("Method: Temporary (RFC 8981)")
+	fmt.Printf("Lifetimes: preferred %s minus up to %.0f%% desync, valid %s, regenerated %s before deprecation\n",
+		preferred, maxDesyncFactor*100, valid, tempRegenAdvance)
+	for _, addr := range temporaryAddresses(prefix, count, preferred, valid, rng) {
+		fmt.Printf("Address: %s (from +%s, preferred until +%s, valid until +%s)\n",
+			addr.Addr, addr.Start.Round(time.Second), addr.Preferred.Round(time.Second), addr.Valid.Round(time.Second))
+	}
+	return true
+}
+
+func runSLAAC(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		logger.PrintValidationError("missing /64 prefix argument")
+		if err := cmd.Help(); err != nil {
+			logger.PrintErrorWithMessage("failed to show help", err)
+		}
+		return
+	}
+
+	prefix, err := netip.ParsePrefix(args[0])
+	if err != nil {
+		logger.PrintErrorWithMessage("failed to parse prefix", err)
+		return
+	}
+	if !prefix.Addr().Is6() || prefix.Bits() != ipv6LANBits {
+		logger.PrintValidationError(fmt.Sprintf("%s is not an IPv6 /64; SLAAC requires a /64 prefix", prefix))
+		return
+	}
+	prefix = prefix.Masked()
+
+	modes, err := selectedSLAACModes(cmd)
+	if err != nil {
+		logger.PrintError(err)
+		return
+	}
+
+	fmt.Println("Prefix:", prefix)
+	for _, mode := range modes {
+		fmt.Println()
+		var ok bool
+		switch mode {
+		case "eui64":
+			ok = printEUI64Address(cmd, prefix)
+		case "stable":
+			ok = printStableAddress(cmd, prefix)
+		case "linux":
+			ok = printLinuxStableAddress(cmd, prefix)
+		case "temporary":
+			ok = printTemporaryAddresses(cmd, prefix)
+		}
+		if !ok {
+			return
+		}
+	}
+
+	logger.Infof("Successfully computed SLAAC addresses for %s", prefix)
+}
@@ -0,0 +1,232 @@
+//go:build unit
+
+/*
+Copyright © 2024-2025 Auska <luodan0709@live.cn>
+
+*/
+
+package cmd
+
+import (
+	"crypto/sha1" // #nosec G505 -- reference for the kernel's SHA-1 block, not used for security
+	"encoding/binary"
+	"encoding/hex"
+	"math/rand"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func mustIID(t *testing.T, s string) [8]byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) != 8 {
+		t.Fatalf("invalid IID %q", s)
+	}
+	return [8]byte(b)
+}
+
+func TestIsReservedIID(t *testing.T) {
+	tests := []struct {
+		iid      string
+		expected bool
+	}{
+		{"0000000000000000", true},
+		{"02005efffe000000", true},
+		{"02005efffe005213", true},
+		{"02005efffeffffff", true},
+		{"02005efe0a000001", false}, // ISATAP
+		{"02005eff00000001", false},
+		{"02005effff000000", false},
+		{"fdffffffffffff80", true},
+		{"fdffffffffffffff", true},
+		{"fdffffffffffff7f", false},
+		{"02005efd00000001", false},
+		{"0211228ffe334455", false},
+		{"0000000000000001", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.iid, func(t *testing.T) {
+			if result := isReservedIID(mustIID(t, tt.iid)); result != tt.expected {
+				t.Errorf("isReservedIID() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseStableSecret(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		wantErr  bool
+	}{
+		{"0123456789abcdef", "0123456789abcdef", false},
+		{"0xABCD", "abcd", false},
+		{"::1", "00000000000000000000000000000001", false},
+		{"2001:db8::", "20010db8000000000000000000000000", false},
+		{"not-hex", "", true},
+		{"", "", true},
+		{"abc", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			result, err := parseStableSecret(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseStableSecret() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if hex.EncodeToString(result) != tt.expected {
+				t.Errorf("parseStableSecret() = %x, want %s", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestStableIID(t *testing.T) {
+	secret, _ := hex.DecodeString("0123456789abcdef0123456789abcdef")
+	base := stableIIDParams{
+		Prefix: netip.MustParsePrefix("2001:db8:1:2::/64"),
+		Iface:  "eth0",
+		Secret: secret,
+	}
+
+	iid, dad, err := stableIID(base)
+	if err != nil {
+		t.Fatalf("stableIID() error = %v", err)
+	}
+	if formatIID(iid) != "60e1:9a78:823d:d540" || dad != 0 {
+		t.Errorf("stableIID() = %s with DAD %d, want 60e1:9a78:823d:d540 with DAD 0", formatIID(iid), dad)
+	}
+	if addr := withIID(base.Prefix, iid).String(); addr != "2001:db8:1:2:60e1:9a78:823d:d540" {
+		t.Errorf("withIID() = %s", addr)
+	}
+
+	// 同一前缀和参数总是得到同一个接口标识，任一输入变化都会得到不同的标识
+	again, _, _ := stableIID(base)
+	if again != iid {
+		t.Error("stableIID() is not stable for identical inputs")
+	}
+	variants := []stableIIDParams{base, base, base, base, base}
+	variants[0].Prefix = netip.MustParsePrefix("2001:db8:1:3::/64")
+	variants[1].Iface = "eth1"
+	variants[2].NetworkID = "office"
+	variants[3].DAD = 1
+	variants[4].Secret = []byte{1}
+	for i, v := range variants {
+		if other, _, _ := stableIID(v); other == iid {
+			t.Errorf("variant %d gave the same IID %s", i, formatIID(other))
+		}
+	}
+
+	// 前缀的低 64 位不参与计算
+	withHost := base
+	withHost.Prefix = netip.MustParsePrefix("2001:db8:1:2::1/64")
+	if other, _, _ := stableIID(withHost); other != iid {
+		t.Error("stableIID() should only use the /64 prefix")
+	}
+}
+
+func TestSHA1Block(t *testing.T) {
+	// 不超过 55 字节的消息按 SHA-1 规则填充后正好是一个分组，压缩一次即为完整摘要
+	for _, msg := range []string{"", "abc", "the quick brown fox jumps over the lazy dog, 55 bytes.."} {
+		var block [64]byte
+		copy(block[:], msg)
+		block[len(msg)] = 0x80
+		binary.BigEndian.PutUint64(block[56:], uint64(len(msg))*8)
+
+		h := sha1Init
+		sha1Block(&h, &block)
+		var got [20]byte
+		for i, word := range h {
+			binary.BigEndian.PutUint32(got[i*4:], word)
+		}
+		if want := sha1.Sum([]byte(msg)); got != want {
+			t.Errorf("sha1Block(%q) = %x, want %x", msg, got, want)
+		}
+	}
+}
+
+func TestLinuxStableIID(t *testing.T) {
+	prefix := netip.MustParsePrefix("2001:db8:1:2::/64")
+	mac := net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55}
+	secret := [16]byte(netip.MustParseAddr("2001:db8::1234").AsSlice())
+
+	iid, dad, err := linuxStableIID(prefix, mac, 0, secret)
+	if err != nil {
+		t.Fatalf("linuxStableIID() error = %v", err)
+	}
+	if formatIID(iid) != "5882:967f:6487:2c31" || dad != 0 {
+		t.Errorf("linuxStableIID() = %s with DAD %d, want 5882:967f:6487:2c31 with DAD 0", formatIID(iid), dad)
+	}
+
+	// 接口标识是单个分组压缩后前两个摘要字的小端字节
+	var block [64]byte
+	copy(block[:16], secret[:])
+	copy(block[16:24], prefix.Addr().AsSlice())
+	copy(block[24:], mac)
+	h := sha1Init
+	sha1Block(&h, &block)
+	if binary.LittleEndian.Uint32(iid[:4]) != h[0] || binary.LittleEndian.Uint32(iid[4:]) != h[1] {
+		t.Errorf("linuxStableIID() = %s, want the digest words %08x %08x in little-endian order", formatIID(iid), h[0], h[1])
+	}
+
+	if other, _, _ := linuxStableIID(netip.MustParsePrefix("2001:db8:1:2::1/64"), mac, 0, secret); other != iid {
+		t.Error("linuxStableIID() should only use the /64 prefix")
+	}
+	for name, other := range map[string][8]byte{
+		"prefix": mustLinuxIID(t, netip.MustParsePrefix("2001:db8:1:3::/64"), mac, 0, secret),
+		"mac":    mustLinuxIID(t, prefix, net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x56}, 0, secret),
+		"dad":    mustLinuxIID(t, prefix, mac, 1, secret),
+		"secret": mustLinuxIID(t, prefix, mac, 0, [16]byte{1}),
+	} {
+		if other == iid {
+			t.Errorf("changing the %s gave the same IID %s", name, formatIID(other))
+		}
+	}
+}
+
+func mustLinuxIID(t *testing.T, prefix netip.Prefix, mac net.HardwareAddr, dad int, secret [16]byte) [8]byte {
+	t.Helper()
+	iid, _, err := linuxStableIID(prefix, mac, dad, secret)
+	if err != nil {
+		t.Fatalf("linuxStableIID() error = %v", err)
+	}
+	return iid
+}
+
+func TestTemporaryAddresses(t *testing.T) {
+	prefix := netip.MustParsePrefix("2001:db8:1:2::/64")
+	addrs := temporaryAddresses(prefix, 5, tempPreferredLifetime, tempValidLifetime, rand.New(rand.NewSource(42)))
+	if len(addrs) != 5 {
+		t.Fatalf("temporaryAddresses() returned %d addresses, want 5", len(addrs))
+	}
+
+	seen := make(map[netip.Addr]bool)
+	minPreferred := tempPreferredLifetime - time.Duration(float64(tempPreferredLifetime)*maxDesyncFactor)
+	for i, addr := range addrs {
+		if !prefix.Contains(addr.Addr) || seen[addr.Addr] {
+			t.Errorf("address %d %s is outside the prefix or repeated", i, addr.Addr)
+		}
+		seen[addr.Addr] = true
+
+		lifetime := addr.Preferred - addr.Start
+		if lifetime > tempPreferredLifetime || lifetime < minPreferred {
+			t.Errorf("address %d preferred lifetime %s outside [%s, %s]", i, lifetime, minPreferred, tempPreferredLifetime)
+		}
+		if addr.Valid-addr.Start != tempValidLifetime {
+			t.Errorf("address %d valid lifetime = %s, want %s", i, addr.Valid-addr.Start, tempValidLifetime)
+		}
+		if i > 0 && addr.Start != addrs[i-1].Preferred-tempRegenAdvance {
+			t.Errorf("address %d starts at %s, want REGEN_ADVANCE before %s", i, addr.Start, addrs[i-1].Preferred)
+		}
+	}
+
+	again := temporaryAddresses(prefix, 5, tempPreferredLifetime, tempValidLifetime, rand.New(rand.NewSource(42)))
+	for i := range addrs {
+		if addrs[i] != again[i] {
+			t.Fatal("temporaryAddresses() is not reproducible with the same seed")
+		}
+	}
+}
@@ -8,6 +8,7 @@ Copyright © 2024-2025 Auska <luodan0709@live.cn>
 package cmd
 
 import (
+	"math/big"
 	"net"
 	"testing"
 
@@ -44,7 +45,7 @@ func TestCalculateCIDRInfo(t *testing.T) {
 				if info.InverseMask != "0.0.0.255" {
 					t.Errorf("InverseMask = %v, want 0.0.0.255", info.InverseMask)
 				}
-				if info.TotalHosts != 254 {
+				if info.TotalHosts.Int64() != 254 {
 					t.Errorf("TotalHosts = %v, want 254", info.TotalHosts)
 				}
 			},
@@ -60,7 +61,7 @@ func TestCalculateCIDRInfo(t *testing.T) {
 				if info.LastIP != "192.168.1.1" {
 					t.Errorf("LastIP = %v, want 192.168.1.1", info.LastIP)
 				}
-				if info.TotalHosts != 1 {
+				if info.TotalHosts.Int64() != 1 {
 					t.Errorf("TotalHosts = %v, want 1", info.TotalHosts)
 				}
 			},
@@ -76,7 +77,7 @@ func TestCalculateCIDRInfo(t *testing.T) {
 				if info.LastIP != "192.168.1.1" {
 					t.Errorf("LastIP = %v, want 192.168.1.1", info.LastIP)
 				}
-				if info.TotalHosts != 2 {
+				if info.TotalHosts.Int64() != 2 {
 					t.Errorf("TotalHosts = %v, want 2", info.TotalHosts)
 				}
 			},
@@ -89,8 +90,101 @@ func TestCalculateCIDRInfo(t *testing.T) {
 				if info.NetworkID != "2001:db8::" {
 					t.Errorf("NetworkID = %v, want 2001:db8::", info.NetworkID)
 				}
-				if info.TotalHosts != -1 {
-					t.Errorf("TotalHosts = %v, want -1 (very large)", info.TotalHosts)
+				if info.TotalAddresses.Cmp(powerOfTwo(96)) != 0 {
+					t.Errorf("TotalAddresses = %v, want 2^96", info.TotalAddresses)
+				}
+				if info.FirstIP != "2001:db8::1" {
+					t.Errorf("FirstIP = %v, want 2001:db8::1", info.FirstIP)
+				}
+				if info.LastIP != "2001:db8:ffff:ffff:ffff:ffff:ffff:ffff" {
+					t.Errorf("LastIP = %v, want 2001:db8:ffff:ffff:ffff:ffff:ffff:ffff", info.LastIP)
+				}
+				if info.Subnets48.Int64() != 65536 {
+					t.Errorf("Subnets48 = %v, want 65536", info.Subnets48)
+				}
+				if info.Subnets64.Cmp(powerOfTwo(32)) != 0 {
+					t.Errorf("Subnets64 = %v, want 2^32", info.Subnets64)
+				}
+				if info.ReservedAnycastFirst != "" {
+					t.Errorf("ReservedAnycastFirst = %v, want empty for /32", info.ReservedAnycastFirst)
+				}
+			},
+		},
+		{
+			name:    "Valid IPv6 /64 network",
+			cidr:    "2001:db8:1:2::/64",
+			wantErr: false,
+			check: func(t *testing.T, info *CIDRInfo) {
+				if info.SubnetRouterAnycast != "2001:db8:1:2::" {
+					t.Errorf("SubnetRouterAnycast = %v, want 2001:db8:1:2::", info.SubnetRouterAnycast)
+				}
+				if info.ReservedAnycastFirst != "2001:db8:1:2:fdff:ffff:ffff:ff80" {
+					t.Errorf("ReservedAnycastFirst = %v, want 2001:db8:1:2:fdff:ffff:ffff:ff80", info.ReservedAnycastFirst)
+				}
+				if info.ReservedAnycastLast != "2001:db8:1:2:fdff:ffff:ffff:ffff" {
+					t.Errorf("ReservedAnycastLast = %v, want 2001:db8:1:2:fdff:ffff:ffff:ffff", info.ReservedAnycastLast)
+				}
+				if info.LastIP != "2001:db8:1:2:ffff:ffff:ffff:ffff" {
+					t.Errorf("LastIP = %v, want 2001:db8:1:2:ffff:ffff:ffff:ffff", info.LastIP)
+				}
+				if info.Subnets48 != nil {
+					t.Errorf("Subnets48 = %v, want nil for /64", info.Subnets48)
+				}
+				want := new(big.Int).Sub(powerOfTwo(64), big.NewInt(129))
+				if info.TotalHosts.Cmp(want) != 0 {
+					t.Errorf("TotalHosts = %v, want %v", info.TotalHosts, want)
+				}
+			},
+		},
+		{
+			name:    "Valid IPv6 /120 network",
+			cidr:    "2001:db8::/120",
+			wantErr: false,
+			check: func(t *testing.T, info *CIDRInfo) {
+				if info.ReservedAnycastFirst != "2001:db8::80" {
+					t.Errorf("ReservedAnycastFirst = %v, want 2001:db8::80", info.ReservedAnycastFirst)
+				}
+				if info.LastIP != "2001:db8::ff" {
+					t.Errorf("LastIP = %v, want 2001:db8::ff", info.LastIP)
+				}
+				if info.TotalHosts.Int64() != 127 {
+					t.Errorf("TotalHosts = %v, want 127", info.TotalHosts)
+				}
+			},
+		},
+		{
+			name:    "Valid IPv6 /127 network",
+			cidr:    "2001:db8::/127",
+			wantErr: false,
+			check: func(t *testing.T, info *CIDRInfo) {
+				if info.FirstIP != "2001:db8::" || info.LastIP != "2001:db8::1" {
+					t.Errorf("Range = %v - %v, want 2001:db8:: - 2001:db8::1", info.FirstIP, info.LastIP)
+				}
+				if info.TotalHosts.Int64() != 2 {
+					t.Errorf("TotalHosts = %v, want 2", info.TotalHosts)
+				}
+			},
+		},
+		{
+			name:    "Valid IPv6 /128 network",
+			cidr:    "2001:db8::5/128",
+			wantErr: false,
+			check: func(t *testing.T, info *CIDRInfo) {
+				if info.FirstIP != "2001:db8::5" || info.LastIP != "2001:db8::5" {
+					t.Errorf("Range = %v - %v, want 2001:db8::5 - 2001:db8::5", info.FirstIP, info.LastIP)
+				}
+				if info.TotalHosts.Int64() != 1 {
+					t.Errorf("TotalHosts = %v, want 1", info.TotalHosts)
+				}
+			},
+		},
+		{
+			name:    "Valid IPv4 /0 network",
+			cidr:    "0.0.0.0/0",
+			wantErr: false,
+			check: func(t *testing.T, info *CIDRInfo) {
+				if info.TotalHosts.Int64() != 4294967294 {
+					t.Errorf("TotalHosts = %v, want 4294967294", info.TotalHosts)
 				}
 			},
 		},
@@ -162,6 +256,28 @@ func TestCalculateInverseMask(t *testing.T) {
 	}
 }
 
+func TestFormatCount(t *testing.T) {
+	tests := []struct {
+		name     string
+		n        *big.Int
+		expected string
+	}{
+		{"Zero", big.NewInt(0), "0"},
+		{"One", big.NewInt(1), "1"},
+		{"Not a power of two", big.NewInt(254), "254"},
+		{"Power of two", big.NewInt(256), "256 (2^8)"},
+		{"Large power of two", powerOfTwo(64), "18446744073709551616 (2^64)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := formatCount(tt.n); result != tt.expected {
+				t.Errorf("formatCount() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
 func TestConvertIPAddress(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -179,14 +295,34 @@ func TestConvertIPAddress(t *testing.T) {
 			args:    []string{"2001:db8::/32"},
 			wantErr: false,
 		},
+		{
+			name:    "IPv4 address with dotted mask",
+			args:    []string{"192.168.1.1", "255.255.255.0"},
+			wantErr: false,
+		},
+		{
+			name:    "Non-contiguous mask",
+			args:    []string{"192.168.1.1", "255.0.255.0"},
+			wantErr: true,
+		},
 		{
 			name:    "Missing argument",
 			args:    []string{},
 			wantErr: true,
 		},
 		{
-			name:    "Invalid CIDR format",
+			name:    "Bare IPv4 address",
 			args:    []string{"192.168.1.0"},
+			wantErr: false,
+		},
+		{
+			name:    "Multiple inputs",
+			args:    []string{"10.0.0.*", "10.0.0.1-50", "2001:db8::1"},
+			wantErr: false,
+		},
+		{
+			name:    "Invalid address",
+			args:    []string{"192.168.1.300"},
 			wantErr: true,
 		},
 	}
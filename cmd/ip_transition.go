@@ -0,0 +1,516 @@
+/*
+Copyright © 2024-2025 Auska <luodan0709@live.cn>
+
+*/
+
+package cmd
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"macconv/pkg/errors"
+	"macconv/pkg/logger"
+	"macconv/pkg/validator"
+)
+
+var (
+	defaultNAT64Prefix = netip.MustParsePrefix("64:ff9b::/96")
+	sixToFourPrefix    = netip.MustParsePrefix("2002::/16")
+	teredoPrefix       = netip.MustParsePrefix("2001::/32")
+)
+
+var ipTransitionCmd = &cobra.Command{
+	Use:   "transition",
+	Short: "Embed or extract IPv4 addresses in IPv6 transition addresses",
+	Long: `
+Embed an IPv4 address into an IPv6 transition address, or extract it back.
+The direction is chosen from the address family of the argument. For example:
+
+	macconv ip transition nat64 192.0.2.33
+	macconv ip transition nat64 2001:db8:122:344::192.0.2.33 --prefix 2001:db8:122:300::/56
+	macconv ip transition 6to4 2002:c000:221::1
+	macconv ip transition teredo 2001:0:4136:e378:8000:63bf:3fff:fdd2
+	macconv ip transition teredo 192.0.2.45 --server 65.54.227.120 --port 40000
+	macconv ip transition isatap fe80::5efe:10.1.2.3
+	macconv ip transition 6rd 10.100.101.102 --prefix 2001:db8::/32 --ipv4-prefix 10.0.0.0/8`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := cmd.Help(); err != nil {
+			logger.PrintErrorWithMessage("failed to show help", err)
+		}
+	},
+}
+
+var nat64Cmd = &cobra.Command{
+	Use:   "nat64",
+	Short: "RFC 6052 IPv4-embedded IPv6 addresses",
+	Run:   runNAT64,
+}
+
+var sixToFourCmd = &cobra.Command{
+	Use:   "6to4",
+	Short: "RFC 3056 6to4 addresses (2002::/16)",
+	Run:   runSixToFour,
+}
+
+var teredoCmd = &cobra.Command{
+	Use:   "teredo",
+	Short: "RFC 4380 Teredo addresses (2001::/32)",
+	Run:   runTeredo,
+}
+
+var isatapCmd = &cobra.Command{
+	Use:   "isatap",
+	Short: "RFC 5214 ISATAP interface identifiers",
+	Run:   runISATAP,
+}
+
+var sixRDCmd = &cobra.Command{
+	Use:   "6rd",
+	Short: "RFC 5969 6rd delegated prefixes",
+	Run:   runSixRD,
+}
+
+func init() {
+	ipCmd.AddCommand(ipTransitionCmd)
+	ipTransitionCmd.AddCommand(nat64Cmd, sixToFourCmd, teredoCmd, isatapCmd, sixRDCmd)
+
+	nat64Cmd.Flags().String("prefix", defaultNAT64Prefix.String(), "NAT64 prefix (/32, /40, /48, /56, /64 or /96)")
+
+	teredoCmd.Flags().String("server", "", "Teredo server IPv4 address (embed only)")
+	teredoCmd.Flags().String("port", "", "Client external UDP port (embed only)")
+	teredoCmd.Flags().Uint16("flags", 0, "Teredo flags field, e.g. 32768 for the cone bit (embed only)")
+
+	isatapCmd.Flags().String("prefix", "fe80::/64", "ISATAP /64 prefix (embed only)")
+
+	sixRDCmd.Flags().String("prefix", "", "6rd prefix assigned by the ISP")
+	sixRDCmd.Flags().String("ipv4-prefix", "0.0.0.0/0", "Common IPv4 prefix omitted from the delegated prefix")
+}
+
+// copyBits 从 src 的第 srcOff 位开始复制 n 位到 dst 的第 dstOff 位
+func copyBits(dst []byte, dstOff int, src []byte, srcOff, n int) {
+	for i := 0; i < n; i++ {
+		s, d := srcOff+i, dstOff+i
+		bit := (src[s/8] >> (7 - s%8)) & 1
+		dst[d/8] = dst[d/8]&^(1<<(7-d%8)) | bit<<(7-d%8)
+	}
+}
+
+// validateNAT64Prefix 检查 RFC 6052 允许的前缀长度，且前缀中 bit 64-71 必须为 0
+func validateNAT64Prefix(prefix netip.Prefix) error {
+	if !prefix.Addr().Is6() || prefix.Addr().Is4In6() {
+		return errors.New(errors.ValidationError, "NAT64 prefix must be IPv6")
+	}
+	switch prefix.Bits() {
+	case 32, 40, 48, 56, 64, 96:
+	default:
+		return errors.New(errors.ValidationError,
+			fmt.Sprintf("NAT64 prefix length /%d not allowed, use /32, /40, /48, /56, /64 or /96", prefix.Bits()))
+	}
+	if prefix.Bits() > 64 && prefix.Addr().As16()[8] != 0 {
+		return errors.New(errors.ValidationError, "bits 64-71 of a NAT64 prefix must be zero (RFC 6052 section 2.2)")
+	}
+	return nil
+}
+
+// nat64Embed 按 RFC 6052 将 IPv4 地址嵌入前缀，跳过保留的第 64-71 位
+func nat64Embed(prefix netip.Prefix, v4 netip.Addr) (netip.Addr, error) {
+	if err := validateNAT64Prefix(prefix); err != nil {
+		return netip.Addr{}, err
+	}
+
+	b := prefix.Masked().Addr().As16()
+	src := v4.As4()
+	offset := prefix.Bits()
+	for i := 0; i < 4; i++ {
+		if offset == 64 {
+			offset += 8
+		}
+		copyBits(b[:], offset, src[:], i*8, 8)
+		offset += 8
+	}
+	return netip.AddrFrom16(b), nil
+}
+
+// nat64DottedForm 返回 /96 前缀嵌入 IPv4 地址的点分写法，如 64:ff9b::192.0.2.33
+// 前 6 组按 RFC 5952 压缩：最长（相同时取最前）的至少两组连续零写成 "::"
+func nat64DottedForm(prefix netip.Prefix, addr netip.Addr) string {
+	b := prefix.Masked().Addr().As16()
+	groups := make([]string, 6)
+	runStart, runLen := -1, 0
+	start := -1 // 当前连续零的起点
+	for i := 0; i < 6; i++ {
+		value := binary.BigEndian.Uint16(b[i*2:])
+		groups[i] = strconv.FormatUint(uint64(value), 16)
+		switch {
+		case value != 0:
+			start = -1
+		case start < 0:
+			start = i
+		}
+		if start >= 0 && i-start+1 > runLen {
+			runStart, runLen = start, i-start+1
+		}
+	}
+
+	if runLen < 2 {
+		return strings.Join(groups, ":") + ":" + addr.String()
+	}
+	head := strings.Join(groups[:runStart], ":") + "::"
+	if tail := groups[runStart+runLen:]; len(tail) > 0 {
+		head += strings.Join(tail, ":") + ":"
+	}
+	return head + addr.String()
+}
+
+// nat64Extract 从 IPv4-embedded IPv6 地址中取出 IPv4 地址
+func nat64Extract(prefix netip.Prefix, v6 netip.Addr) (netip.Addr, error) {
+	if err := validateNAT64Prefix(prefix); err != nil {
+		return netip.Addr{}, err
+	}
+	if !prefix.Contains(v6) {
+		return netip.Addr{}, errors.New(errors.ValidationError, fmt.Sprintf("%s is not inside NAT64 prefix %s", v6, prefix))
+	}
+
+	b := v6.As16()
+	var v4 [4]byte
+	offset := prefix.Bits()
+	for i := 0; i < 4; i++ {
+		if offset == 64 {
+			offset += 8
+		}
+		copyBits(v4[:], i*8, b[:], offset, 8)
+		offset += 8
+	}
+	return netip.AddrFrom4(v4), nil
+}
+
+// sixToFourPrefixFor 返回 IPv4 地址对应的 2002:V4ADDR::/48
+func sixToFourPrefixFor(v4 netip.Addr) netip.Prefix {
+	var b [16]byte
+	b[0], b[1] = 0x20, 0x02
+	src := v4.As4()
+	copy(b[2:6], src[:])
+	return netip.PrefixFrom(netip.AddrFrom16(b), 48)
+}
+
+// sixToFourExtract 从 6to4 地址取出 IPv4 地址
+func sixToFourExtract(v6 netip.Addr) (netip.Addr, error) {
+	if !sixToFourPrefix.Contains(v6) {
+		return netip.Addr{}, errors.New(errors.ValidationError, fmt.Sprintf("%s is not a 6to4 address (2002::/16)", v6))
+	}
+	b := v6.As16()
+	return netip.AddrFrom4([4]byte(b[2:6])), nil
+}
+
+// teredoAddress Teredo 地址各字段
+type teredoAddress struct {
+	Server netip.Addr
+	Flags  uint16
+	Port   uint16
+	Client netip.Addr
+}
+
+// teredoEmbed 构造 Teredo 地址，端口和客户端地址按 RFC 4380 取反混淆
+func teredoEmbed(t teredoAddress) netip.Addr {
+	var b [16]byte
+	b[0], b[1] = 0x20, 0x01
+	server := t.Server.As4()
+	client := t.Client.As4()
+	copy(b[4:8], server[:])
+	b[8], b[9] = byte(t.Flags>>8), byte(t.Flags)
+	port := ^t.Port
+	b[10], b[11] = byte(port>>8), byte(port)
+	for i := 0; i < 4; i++ {
+		b[12+i] = ^client[i]
+	}
+	return netip.AddrFrom16(b)
+}
+
+// teredoExtract 解析 Teredo 地址中的服务器、标志、端口和客户端地址
+func teredoExtract(v6 netip.Addr) (teredoAddress, error) {
+	if !teredoPrefix.Contains(v6) {
+		return teredoAddress{}, errors.New(errors.ValidationError, fmt.Sprintf("%s is not a Teredo address (2001::/32)", v6))
+	}
+	b := v6.As16()
+	var client [4]byte
+	for i := 0; i < 4; i++ {
+		client[i] = ^b[12+i]
+	}
+	return teredoAddress{
+		Server: netip.AddrFrom4([4]byte(b[4:8])),
+		Flags:  uint16(b[8])<<8 | uint16(b[9]),
+		Port:   ^(uint16(b[10])<<8 | uint16(b[11])),
+		Client: netip.AddrFrom4(client),
+	}, nil
+}
+
+// isatapEmbed 构造 ISATAP 地址，全局单播 IPv4 地址置 u 位（0200:5efe），私有地址为 0000:5efe
+func isatapEmbed(prefix netip.Prefix, v4 netip.Addr) (netip.Addr, error) {
+	if prefix.Bits() != 64 || !prefix.Addr().Is6() {
+		return netip.Addr{}, errors.New(errors.ValidationError, "ISATAP prefix must be an IPv6 /64")
+	}
+	b := prefix.Masked().Addr().As16()
+	if !classifyPrefix(netip.PrefixFrom(v4, 32)).Bogon() {
+		b[8] = 0x02
+	}
+	b[10], b[11] = 0x5e, 0xfe
+	src := v4.As4()
+	copy(b[12:], src[:])
+	return netip.AddrFrom16(b), nil
+}
+
+// isatapExtract 从 ISATAP 接口标识中取出 IPv4 地址
+func isatapExtract(v6 netip.Addr) (netip.Addr, error) {
+	b := v6.As16()
+	if b[8]&^0x03 != 0 || b[9] != 0 || b[10] != 0x5e || b[11] != 0xfe {
+		return netip.Addr{}, errors.New(errors.ValidationError,
+			fmt.Sprintf("%s does not have an ISATAP interface identifier (::0:5efe:a.b.c.d)", v6))
+	}
+	return netip.AddrFrom4([4]byte(b[12:16])), nil
+}
+
+// sixRDParams 6rd 域参数
+type sixRDParams struct {
+	Prefix     netip.Prefix
+	IPv4Prefix netip.Prefix
+}
+
+// delegatedLength 返回委派前缀长度
+func (p sixRDParams) delegatedLength() int {
+	return p.Prefix.Bits() + 32 - p.IPv4Prefix.Bits()
+}
+
+func (p sixRDParams) validate() error {
+	if !p.Prefix.Addr().Is6() || !p.IPv4Prefix.Addr().Is4() {
+		return errors.New(errors.ValidationError, "6rd needs an IPv6 --prefix and an IPv4 --ipv4-prefix")
+	}
+	if p.delegatedLength() > 64 {
+		return errors.New(errors.ValidationError, fmt.Sprintf("6rd delegated prefix would be /%d, longer than /64", p.delegatedLength()))
+	}
+	return nil
+}
+
+// sixRDEmbed 将 IPv4 地址去掉公共前缀后拼接到 6rd 前缀之后
+func sixRDEmbed(p sixRDParams, v4 netip.Addr) (netip.Prefix, error) {
+	if err := p.validate(); err != nil {
+		return netip.Prefix{}, err
+	}
+	if !p.IPv4Prefix.Contains(v4) {
+		return netip.Prefix{}, errors.New(errors.ValidationError, fmt.Sprintf("%s is not inside 6rd IPv4 prefix %s", v4, p.IPv4Prefix))
+	}
+
+	b := p.Prefix.Masked().Addr().As16()
+	src := v4.As4()
+	n := 32 - p.IPv4Prefix.Bits()
+	copyBits(b[:], p.Prefix.Bits(), src[:], p.IPv4Prefix.Bits(), n)
+	return netip.PrefixFrom(netip.AddrFrom16(b), p.delegatedLength()), nil
+}
+
+// sixRDExtract 从 6rd 地址中恢复 CE 的 IPv4 地址
+func sixRDExtract(p sixRDParams, v6 netip.Addr) (netip.Addr, error) {
+	if err := p.validate(); err != nil {
+		return netip.Addr{}, err
+	}
+	if !p.Prefix.Contains(v6) {
+		return netip.Addr{}, errors.New(errors.ValidationError, fmt.Sprintf("%s is not inside 6rd prefix %s", v6, p.Prefix))
+	}
+
+	v4 := p.IPv4Prefix.Masked().Addr().As4()
+	src := v6.As16()
+	copyBits(v4[:], p.IPv4Prefix.Bits(), src[:], p.Prefix.Bits(), 32-p.IPv4Prefix.Bits())
+	return netip.AddrFrom4(v4), nil
+}
+
+// transitionArg 解析唯一的地址参数，返回地址以及是否为 IPv4（嵌入方向）
+func transitionArg(cmd *cobra.Command, args []string) (netip.Addr, bool) {
+	if len(args) != 1 {
+		logger.PrintValidationError("expected exactly one IPv4 or IPv6 address")
+		if err := cmd.Help(); err != nil {
+			logger.PrintErrorWithMessage("failed to show help", err)
+		}
+		return netip.Addr{}, false
+	}
+	addr, err := netip.ParseAddr(args[0])
+	if err != nil {
+		logger.PrintErrorWithMessage("failed to parse address", err)
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
+
+func flagPrefix(cmd *cobra.Command, name string) (netip.Prefix, bool) {
+	value, _ := cmd.Flags().GetString(name)
+	prefix, err := netip.ParsePrefix(value)
+	if err != nil {
+		logger.PrintErrorWithMessage(fmt.Sprintf("invalid --%s", name), err)
+		return netip.Prefix{}, false
+	}
+	return prefix, true
+}
+
+func runNAT64(cmd *cobra.Command, args []string) {
+	addr, ok := transitionArg(cmd, args)
+	if !ok {
+		return
+	}
+	prefix, ok := flagPrefix(cmd, "prefix")
+	if !ok {
+		return
+	}
+
+	if addr.Is4() {
+		v6, err := nat64Embed(prefix, addr)
+		if err != nil {
+			logger.PrintErrorWithMessage("failed to embed IPv4 address", err)
+			return
+		}
+		fmt.Println("NAT64 Prefix:", prefix.Masked())
+		fmt.Println("IPv4 Address:", addr)
+		if prefix.Bits() == 96 {
+			// RFC 6052 推荐 /96 前缀使用点分形式书写嵌入的 IPv4 地址
+			fmt.Printf("IPv6 Address: %s (%s)\n", v6, nat64DottedForm(prefix, addr))
+		} else {
+			fmt.Println("IPv6 Address:", v6)
+		}
+		return
+	}
+
+	v4, err := nat64Extract(prefix, addr)
+	if err != nil {
+		logger.PrintErrorWithMessage("failed to extract IPv4 address", err)
+		return
+	}
+	fmt.Println("NAT64 Prefix:", prefix)
+	fmt.Println("IPv6 Address:", addr)
+	fmt.Println("IPv4 Address:", v4)
+}
+
+func runSixToFour(cmd *cobra.Command, args []string) {
+	addr, ok := transitionArg(cmd, args)
+	if !ok {
+		return
+	}
+
+	if addr.Is4() {
+		prefix := sixToFourPrefixFor(addr)
+		fmt.Println("IPv4 Address:", addr)
+		fmt.Println("6to4 Prefix:", prefix)
+		fmt.Println("6to4 Router Address:", prefix.Addr().Next())
+		return
+	}
+
+	v4, err := sixToFourExtract(addr)
+	if err != nil {
+		logger.PrintErrorWithMessage("failed to extract IPv4 address", err)
+		return
+	}
+	fmt.Println("IPv6 Address:", addr)
+	fmt.Println("IPv4 Address:", v4)
+}
+
+func runTeredo(cmd *cobra.Command, args []string) {
+	addr, ok := transitionArg(cmd, args)
+	if !ok {
+		return
+	}
+
+	if addr.Is6() {
+		t, err := teredoExtract(addr)
+		if err != nil {
+			logger.PrintErrorWithMessage("failed to decode Teredo address", err)
+			return
+		}
+		fmt.Println("Teredo Server:", t.Server)
+		fmt.Printf("Flags: 0x%04x (cone: %t)\n", t.Flags, t.Flags&0x8000 != 0)
+		fmt.Println("Client Port:", t.Port)
+		fmt.Println("Client IPv4 Address:", t.Client)
+		return
+	}
+
+	serverStr, _ := cmd.Flags().GetString("server")
+	portStr, _ := cmd.Flags().GetString("port")
+	flags, _ := cmd.Flags().GetUint16("flags")
+
+	server, err := netip.ParseAddr(serverStr)
+	if err != nil || !server.Is4() {
+		logger.PrintValidationError("--server must be an IPv4 address when embedding")
+		return
+	}
+	port, err := validator.ValidatePort(portStr)
+	if err != nil {
+		logger.PrintErrorWithMessage("invalid --port", err)
+		return
+	}
+
+	v6 := teredoEmbed(teredoAddress{Server: server, Flags: flags, Port: uint16(port), Client: addr})
+	fmt.Println("Teredo Address:", v6)
+}
+
+func runISATAP(cmd *cobra.Command, args []string) {
+	addr, ok := transitionArg(cmd, args)
+	if !ok {
+		return
+	}
+
+	if addr.Is6() {
+		v4, err := isatapExtract(addr)
+		if err != nil {
+			logger.PrintErrorWithMessage("failed to extract IPv4 address", err)
+			return
+		}
+		fmt.Println("IPv6 Address:", addr)
+		fmt.Println("IPv4 Address:", v4)
+		return
+	}
+
+	prefix, ok := flagPrefix(cmd, "prefix")
+	if !ok {
+		return
+	}
+	v6, err := isatapEmbed(prefix, addr)
+	if err != nil {
+		logger.PrintErrorWithMessage("failed to build ISATAP address", err)
+		return
+	}
+	fmt.Println("ISATAP Address:", v6)
+}
+
+func runSixRD(cmd *cobra.Command, args []string) {
+	addr, ok := transitionArg(cmd, args)
+	if !ok {
+		return
+	}
+	prefix, ok := flagPrefix(cmd, "prefix")
+	if !ok {
+		return
+	}
+	v4Prefix, ok := flagPrefix(cmd, "ipv4-prefix")
+	if !ok {
+		return
+	}
+	params := sixRDParams{Prefix: prefix.Masked(), IPv4Prefix: v4Prefix.Masked()}
+
+	if addr.Is4() {
+		delegated, err := sixRDEmbed(params, addr)
+		if err != nil {
+			logger.PrintErrorWithMessage("failed to compute 6rd prefix", err)
+			return
+		}
+		fmt.Println("CE IPv4 Address:", addr)
+		fmt.Println("6rd Delegated Prefix:", delegated)
+		return
+	}
+
+	v4, err := sixRDExtract(params, addr)
+	if err != nil {
+		logger.PrintErrorWithMessage("failed to extract IPv4 address", err)
+		return
+	}
+	fmt.Println("IPv6 Address:", addr)
+	fmt.Println("CE IPv4 Address:", v4)
+}
@@ -0,0 +1,190 @@
+//go:build unit
+
+/*
+Copyright © 2024-2025 Auska <luodan0709@live.cn>
+
+*/
+
+package cmd
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestNAT64(t *testing.T) {
+	// RFC 6052 section 2.4 示例
+	tests := []struct {
+		prefix string
+		v6     string
+	}{
+		{"2001:db8::/32", "2001:db8:c000:221::"},
+		{"2001:db8:100::/40", "2001:db8:1c0:2:21::"},
+		{"2001:db8:122::/48", "2001:db8:122:c000:2:2100::"},
+		{"2001:db8:122:300::/56", "2001:db8:122:3c0:0:221::"},
+		{"2001:db8:122:344::/64", "2001:db8:122:344:c0:2:2100:0"},
+		{"2001:db8:122:344::/96", "2001:db8:122:344::c000:221"},
+		{"64:ff9b::/96", "64:ff9b::c000:221"},
+	}
+
+	v4 := netip.MustParseAddr("192.0.2.33")
+	for _, tt := range tests {
+		t.Run(tt.prefix, func(t *testing.T) {
+			prefix := netip.MustParsePrefix(tt.prefix)
+			v6, err := nat64Embed(prefix, v4)
+			if err != nil {
+				t.Fatalf("nat64Embed() error = %v", err)
+			}
+			if v6.String() != tt.v6 {
+				t.Errorf("nat64Embed() = %v, want %v", v6, tt.v6)
+			}
+
+			back, err := nat64Extract(prefix, netip.MustParseAddr(tt.v6))
+			if err != nil || back != v4 {
+				t.Errorf("nat64Extract() = %v, %v, want %v", back, err, v4)
+			}
+		})
+	}
+
+	if _, err := nat64Embed(netip.MustParsePrefix("2001:db8::/33"), v4); err == nil {
+		t.Error("nat64Embed() expected error for /33 prefix")
+	}
+	if _, err := nat64Extract(defaultNAT64Prefix, netip.MustParseAddr("2001:db8::1")); err == nil {
+		t.Error("nat64Extract() expected error for address outside prefix")
+	}
+}
+
+func TestNAT64DottedForm(t *testing.T) {
+	tests := []struct {
+		prefix   string
+		expected string
+	}{
+		{"64:ff9b::/96", "64:ff9b::192.0.2.33"},
+		{"64:ff9b::1/96", "64:ff9b::192.0.2.33"},
+		{"2001:db8:1:2:3:4::/96", "2001:db8:1:2:3:4:192.0.2.33"},
+		{"2001:0:0:3:4:5::/96", "2001::3:4:5:192.0.2.33"},
+		{"2001:db8:0:1:0:0::/96", "2001:db8:0:1::192.0.2.33"},
+		{"2001:0:1:0:0:0::/96", "2001:0:1::192.0.2.33"},
+		{"2001:0:0:1:0:0::/96", "2001::1:0:0:192.0.2.33"},
+		{"2001:db8:0:1:2:3::/96", "2001:db8:0:1:2:3:192.0.2.33"},
+		{"::/96", "::192.0.2.33"},
+	}
+
+	v4 := netip.MustParseAddr("192.0.2.33")
+	for _, tt := range tests {
+		result := nat64DottedForm(netip.MustParsePrefix(tt.prefix), v4)
+		if result != tt.expected {
+			t.Errorf("nat64DottedForm(%s) = %s, want %s", tt.prefix, result, tt.expected)
+		}
+		if _, err := netip.ParseAddr(result); err != nil {
+			t.Errorf("nat64DottedForm(%s) = %s is not a valid address: %v", tt.prefix, result, err)
+		}
+	}
+}
+
+func TestSixToFour(t *testing.T) {
+	prefix := sixToFourPrefixFor(netip.MustParseAddr("192.0.2.4"))
+	if prefix.String() != "2002:c000:204::/48" {
+		t.Errorf("sixToFourPrefixFor() = %v, want 2002:c000:204::/48", prefix)
+	}
+
+	v4, err := sixToFourExtract(netip.MustParseAddr("2002:c000:204:1::1"))
+	if err != nil || v4.String() != "192.0.2.4" {
+		t.Errorf("sixToFourExtract() = %v, %v, want 192.0.2.4", v4, err)
+	}
+
+	if _, err := sixToFourExtract(netip.MustParseAddr("2001:db8::1")); err == nil {
+		t.Error("sixToFourExtract() expected error for non-6to4 address")
+	}
+}
+
+func TestTeredo(t *testing.T) {
+	addr := netip.MustParseAddr("2001:0:4136:e378:8000:63bf:3fff:fdd2")
+	decoded, err := teredoExtract(addr)
+	if err != nil {
+		t.Fatalf("teredoExtract() error = %v", err)
+	}
+	if decoded.Server.String() != "65.54.227.120" {
+		t.Errorf("Server = %v, want 65.54.227.120", decoded.Server)
+	}
+	if decoded.Flags != 0x8000 {
+		t.Errorf("Flags = %#04x, want 0x8000", decoded.Flags)
+	}
+	if decoded.Port != 40000 {
+		t.Errorf("Port = %d, want 40000", decoded.Port)
+	}
+	if decoded.Client.String() != "192.0.2.45" {
+		t.Errorf("Client = %v, want 192.0.2.45", decoded.Client)
+	}
+
+	if teredoEmbed(decoded) != addr {
+		t.Errorf("teredoEmbed() = %v, want %v", teredoEmbed(decoded), addr)
+	}
+}
+
+func TestISATAP(t *testing.T) {
+	tests := []struct {
+		name   string
+		prefix string
+		v4     string
+		v6     string
+	}{
+		{"Private IPv4", "fe80::/64", "10.1.2.3", "fe80::5efe:a01:203"},
+		{"Global IPv4", "2001:db8:1:2::/64", "8.8.8.8", "2001:db8:1:2:200:5efe:808:808"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v6, err := isatapEmbed(netip.MustParsePrefix(tt.prefix), netip.MustParseAddr(tt.v4))
+			if err != nil || v6.String() != tt.v6 {
+				t.Errorf("isatapEmbed() = %v, %v, want %v", v6, err, tt.v6)
+			}
+			v4, err := isatapExtract(netip.MustParseAddr(tt.v6))
+			if err != nil || v4.String() != tt.v4 {
+				t.Errorf("isatapExtract() = %v, %v, want %v", v4, err, tt.v4)
+			}
+		})
+	}
+
+	if _, err := isatapExtract(netip.MustParseAddr("fe80::1")); err == nil {
+		t.Error("isatapExtract() expected error for non-ISATAP address")
+	}
+}
+
+func TestSixRD(t *testing.T) {
+	params := sixRDParams{
+		Prefix:     netip.MustParsePrefix("2001:db8::/32"),
+		IPv4Prefix: netip.MustParsePrefix("10.0.0.0/8"),
+	}
+
+	delegated, err := sixRDEmbed(params, netip.MustParseAddr("10.100.101.102"))
+	if err != nil || delegated.String() != "2001:db8:6465:6600::/56" {
+		t.Errorf("sixRDEmbed() = %v, %v, want 2001:db8:6465:6600::/56", delegated, err)
+	}
+
+	v4, err := sixRDExtract(params, netip.MustParseAddr("2001:db8:6465:6601::1"))
+	if err != nil || v4.String() != "10.100.101.102" {
+		t.Errorf("sixRDExtract() = %v, %v, want 10.100.101.102", v4, err)
+	}
+
+	full := sixRDParams{
+		Prefix:     netip.MustParsePrefix("2001:db8::/28"),
+		IPv4Prefix: netip.MustParsePrefix("0.0.0.0/0"),
+	}
+	delegated, err = sixRDEmbed(full, netip.MustParseAddr("192.0.2.1"))
+	if err != nil || delegated.String() != "2001:dbc:0:2010::/60" {
+		t.Errorf("sixRDEmbed() = %v, %v, want 2001:dbc:0:2010::/60", delegated, err)
+	}
+
+	if _, err := sixRDEmbed(params, netip.MustParseAddr("192.0.2.1")); err == nil {
+		t.Error("sixRDEmbed() expected error for address outside IPv4 prefix")
+	}
+
+	tooLong := sixRDParams{
+		Prefix:     netip.MustParsePrefix("2001:db8::/48"),
+		IPv4Prefix: netip.MustParsePrefix("0.0.0.0/0"),
+	}
+	if _, err := sixRDEmbed(tooLong, netip.MustParseAddr("192.0.2.1")); err == nil {
+		t.Error("sixRDEmbed() expected error for delegated prefix longer than /64")
+	}
+}
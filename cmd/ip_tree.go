@@ -0,0 +1,173 @@
+/*
+Copyright © 2024-2025 Auska <luodan0709@live.cn>
+
+*/
+
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/spf13/cobra"
+	"macconv/pkg/logger"
+)
+
+var ipTreeCmd = &cobra.Command{
+	Use:   "tree [PREFIX...]",
+	Short: "Show prefixes as a containment tree with unallocated gaps",
+	Long: `
+Arrange prefixes into a tree of parents and children. Between the children of
+a parent the unallocated space is shown as well, as a single CIDR when it is
+aligned or as an address range otherwise. Prefixes are given as arguments or
+read from a file (one per line, optional label columns). For example:
+
+	macconv ip tree 10.0.0.0/16 10.0.0.0/24 10.0.4.0/22 10.0.4.0/24
+	macconv ip tree --file address-plan.csv
+	macconv ip tree --file address-plan.csv --depth 2 --no-gaps`,
+	Run: showPrefixTree,
+}
+
+func init() {
+	ipCmd.AddCommand(ipTreeCmd)
+	ipTreeCmd.Flags().StringP("file", "f", "", "Read prefixes from file (\"-\" for stdin)")
+	ipTreeCmd.Flags().Int("depth", 0, "Only print the tree down to this depth, 0 for all levels")
+	ipTreeCmd.Flags().Bool("no-gaps", false, "Do not show unallocated space between children")
+}
+
+// prefixNode 网段树的一个节点，Gap 为 true 时表示父网段中未分配的部分
+type prefixNode struct {
+	Prefix   labeledPrefix
+	Gap      addrRange
+	IsGap    bool
+	Children []*prefixNode
+}
+
+// buildPrefixTree 按包含关系将网段组织成树，返回顶层网段和被忽略的重复网段
+// 按起始地址、前缀长度排序后，用栈保存当前祖先链，每个网段的父节点即栈中最近的包含者
+func buildPrefixTree(prefixes []labeledPrefix) (roots []*prefixNode, duplicates []labeledPrefix) {
+	sorted := make([]labeledPrefix, len(prefixes))
+	copy(sorted, prefixes)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i].Prefix, sorted[j].Prefix
+		if c := a.Addr().Compare(b.Addr()); c != 0 {
+			return c < 0
+		}
+		return a.Bits() < b.Bits()
+	})
+
+	var stack []*prefixNode
+	for _, lp := range sorted {
+		for len(stack) > 0 && !stack[len(stack)-1].Prefix.Prefix.Contains(lp.Prefix.Addr()) {
+			stack = stack[:len(stack)-1]
+		}
+		if len(stack) > 0 && stack[len(stack)-1].Prefix.Prefix == lp.Prefix {
+			duplicates = append(duplicates, lp)
+			continue
+		}
+
+		node := &prefixNode{Prefix: lp}
+		if len(stack) == 0 {
+			roots = append(roots, node)
+		} else {
+			parent := stack[len(stack)-1]
+			parent.Children = append(parent.Children, node)
+		}
+		stack = append(stack, node)
+	}
+	return roots, duplicates
+}
+
+// addGaps 在每个有子网段的节点下插入未分配的部分，与子网段按地址顺序排列
+func addGaps(node *prefixNode) {
+	if len(node.Children) == 0 {
+		return
+	}
+
+	used := make([]addrRange, len(node.Children))
+	for i, child := range node.Children {
+		addGaps(child)
+		used[i] = prefixRange(child.Prefix.Prefix)
+	}
+
+	parent := prefixRange(node.Prefix.Prefix)
+	gaps := rangeGaps(parent.First, parent.Last, used)
+	children := make([]*prefixNode, 0, len(node.Children)+len(gaps))
+	i := 0
+	for _, gap := range gaps {
+		for i < len(node.Children) && node.Children[i].Prefix.Prefix.Addr().Less(gap.First) {
+			children = append(children, node.Children[i])
+			i++
+		}
+		children = append(children, &prefixNode{Gap: gap, IsGap: true})
+	}
+	node.Children = append(children, node.Children[i:]...)
+}
+
+// gapLabel 描述一段未分配空间：能表示为单个 CIDR 时显示 CIDR，否则显示地址范围和所需 CIDR 数量
+func gapLabel(gap addrRange) string {
+	prefixes := rangeToPrefixes(gap)
+	if len(prefixes) == 1 {
+		return fmt.Sprintf("%s  unallocated", prefixes[0])
+	}
+	return fmt.Sprintf("%s - %s  unallocated (%d CIDRs)", gap.First, gap.Last, len(prefixes))
+}
+
+// tree 转换为通用树节点
+func (n *prefixNode) tree() *treeNode {
+	var node *treeNode
+	switch {
+	case n.IsGap:
+		node = &treeNode{Label: gapLabel(n.Gap)}
+	case n.Prefix.Label != "":
+		node = &treeNode{Label: fmt.Sprintf("%s  %s", n.Prefix.Prefix, n.Prefix.Label)}
+	default:
+		node = &treeNode{Label: n.Prefix.Prefix.String()}
+	}
+	for _, child := range n.Children {
+		node.Children = append(node.Children, child.tree())
+	}
+	return node
+}
+
+func showPrefixTree(cmd *cobra.Command, args []string) {
+	file, _ := cmd.Flags().GetString("file")
+	depth, _ := cmd.Flags().GetInt("depth")
+	noGaps, _ := cmd.Flags().GetBool("no-gaps")
+
+	prefixes, err := collectPrefixes(args, file)
+	if err != nil {
+		logger.PrintErrorWithMessage("failed to load prefixes", err)
+		return
+	}
+	if len(prefixes) == 0 {
+		logger.PrintValidationError("no prefixes given, pass them as arguments or use --file")
+		if err := cmd.Help(); err != nil {
+			logger.PrintErrorWithMessage("failed to show help", err)
+		}
+		return
+	}
+
+	roots, duplicates := buildPrefixTree(prefixes)
+	for _, dup := range duplicates {
+		logger.Warnf("duplicate prefix %s ignored", dup)
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	for i, root := range roots {
+		if !noGaps {
+			addGaps(root)
+		}
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		writeTree(w, root.tree(), depth)
+	}
+	if err := w.Flush(); err != nil {
+		logger.Debugf("Failed to flush output: %v", err)
+	}
+
+	logger.Infof("Successfully built tree of %d prefixes", len(prefixes)-len(duplicates))
+}
@@ -0,0 +1,107 @@
+//go:build unit
+
+/*
+Copyright © 2024-2025 Auska <luodan0709@live.cn>
+
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+func labeledPrefixes(specs ...string) []labeledPrefix {
+	prefixes := make([]labeledPrefix, len(specs))
+	for i, spec := range specs {
+		cidr, label, _ := strings.Cut(spec, " ")
+		prefixes[i] = labeledPrefix{Prefix: netip.MustParsePrefix(cidr), Label: label}
+	}
+	return prefixes
+}
+
+func renderPrefixTree(roots []*prefixNode, gaps bool) string {
+	var buf bytes.Buffer
+	for _, root := range roots {
+		if gaps {
+			addGaps(root)
+		}
+		writeTree(&buf, root.tree(), 0)
+	}
+	return buf.String()
+}
+
+func TestBuildPrefixTree(t *testing.T) {
+	roots, duplicates := buildPrefixTree(labeledPrefixes(
+		"10.0.4.0/24", "10.0.0.0/16 corp", "192.168.0.0/24", "10.0.4.0/22 dc1",
+		"10.0.0.0/24", "10.0.4.0/24 copy", "2001:db8::/32",
+	))
+
+	if len(duplicates) != 1 || duplicates[0].Label != "copy" {
+		t.Errorf("duplicates = %v, want [10.0.4.0/24 (copy)]", duplicates)
+	}
+
+	expected := `10.0.0.0/16  corp
+├── 10.0.0.0/24
+└── 10.0.4.0/22  dc1
+    └── 10.0.4.0/24
+192.168.0.0/24
+2001:db8::/32
+`
+	if got := renderPrefixTree(roots, false); got != expected {
+		t.Errorf("tree =\n%s\nwant\n%s", got, expected)
+	}
+}
+
+func TestAddGaps(t *testing.T) {
+	tests := []struct {
+		name     string
+		prefixes []string
+		expected string
+	}{
+		{
+			name:     "Leading, middle and trailing gaps",
+			prefixes: []string{"10.0.0.0/24", "10.0.0.64/26", "10.0.0.192/27"},
+			expected: `10.0.0.0/24
+├── 10.0.0.0/26  unallocated
+├── 10.0.0.64/26
+├── 10.0.0.128/26  unallocated
+├── 10.0.0.192/27
+└── 10.0.0.224/27  unallocated
+`,
+		},
+		{
+			name:     "Unaligned gap",
+			prefixes: []string{"10.0.0.0/16", "10.0.0.0/24"},
+			expected: `10.0.0.0/16
+├── 10.0.0.0/24
+└── 10.0.1.0 - 10.0.255.255  unallocated (8 CIDRs)
+`,
+		},
+		{
+			name:     "Fully allocated",
+			prefixes: []string{"2001:db8::/47", "2001:db8::/48", "2001:db8:1::/48"},
+			expected: `2001:db8::/47
+├── 2001:db8::/48
+└── 2001:db8:1::/48
+`,
+		},
+		{
+			name:     "Leaf has no gaps",
+			prefixes: []string{"10.0.0.0/24"},
+			expected: "10.0.0.0/24\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			roots, _ := buildPrefixTree(labeledPrefixes(tt.prefixes...))
+			if got := renderPrefixTree(roots, true); got != tt.expected {
+				t.Errorf("tree =\n%s\nwant\n%s", got, tt.expected)
+			}
+		})
+	}
+}
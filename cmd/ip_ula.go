@@ -0,0 +1,285 @@
+/*
+Copyright © 2024-2025 Auska <luodan0709@live.cn>
+
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"crypto/sha1" // #nosec G505 -- RFC 4193 specifies SHA-1, not used for security
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"macconv/pkg/errors"
+	"macconv/pkg/logger"
+)
+
+// ntpEpochOffset 1900-01-01 与 1970-01-01 之间的秒数
+const ntpEpochOffset = 2208988800
+
+// ulaSiteBits ULA 站点前缀长度：7 位前缀 + 1 位 L + 40 位全局 ID
+const ulaSiteBits = 48
+
+var (
+	ulaPrefix      = netip.MustParsePrefix("fc00::/7")
+	ulaLocalPrefix = netip.MustParsePrefix("fd00::/8")
+)
+
+// hexWords 常被手工选用的十六进制单词，出现在全局 ID 中说明前缀多半不是随机生成的
+var hexWords = []string{"dead", "beef", "cafe", "babe", "face", "feed", "f00d", "c0de", "d00d", "fade", "deaf", "abba"}
+
+var ipULACmd = &cobra.Command{
+	Use:   "ula [PREFIX]",
+	Short: "Generate or check an RFC 4193 Unique Local Address prefix",
+	Long: `
+Without arguments, generate a ULA /48 with the RFC 4193 algorithm: the
+current time in NTP format and an EUI-64 built from a MAC address are hashed
+with SHA-1 and the low 40 bits become the Global ID. The MAC defaults to the
+first hardware interface; --mac and --time make the result reproducible.
+
+With a prefix or address, check that it is a locally assigned ULA (fd00::/8)
+and that its Global ID looks randomly generated rather than picked by hand,
+such as fd00::/48 or fd12:3456:789a::/48. For example:
+
+	macconv ip ula
+	macconv ip ula --mac 00:11:22:33:44:55 --time 2024-01-01T00:00:00Z
+	macconv ip ula fd00::/48
+	macconv ip ula fd3c:91e2:6b04:10::1`,
+	Run: runULA,
+}
+
+func init() {
+	ipCmd.AddCommand(ipULACmd)
+	ipULACmd.Flags().String("mac", "", "MAC address for the EUI-64 (default: first hardware interface)")
+	ipULACmd.Flags().String("time", "", "Timestamp in RFC 3339 format (default: now)")
+}
+
+// ntpTimestamp 返回 64 位 NTP 时间戳：高 32 位为 1900 年起的秒数，低 32 位为秒的小数部分
+func ntpTimestamp(t time.Time) uint64 {
+	seconds := uint64(t.Unix() + ntpEpochOffset)
+	fraction := uint64(t.Nanosecond()) << 32 / uint64(time.Second)
+	return seconds<<32 | fraction
+}
+
+// ulaGlobalID 按 RFC 4193 3.2.2 计算 40 位全局 ID：SHA-1(NTP 时间戳 || EUI-64) 的最低 40 位
+func ulaGlobalID(t time.Time, eui64 [8]byte) [5]byte {
+	key := make([]byte, 16)
+	binary.BigEndian.PutUint64(key, ntpTimestamp(t))
+	copy(key[8:], eui64[:])
+
+	sum := sha1.Sum(key) // #nosec G401 -- RFC 4193 specifies SHA-1
+	var id [5]byte
+	copy(id[:], sum[len(sum)-5:])
+	return id
+}
+
+// ulaSitePrefix 由全局 ID 组成 fd00::/8 下的 /48 前缀
+func ulaSitePrefix(globalID [5]byte) netip.Prefix {
+	var b [16]byte
+	b[0] = 0xfd
+	copy(b[1:6], globalID[:])
+	return netip.PrefixFrom(netip.AddrFrom16(b), ulaSiteBits)
+}
+
+// defaultMAC 返回第一个非回环、有 MAC 地址的网络接口的 MAC
+func defaultMAC() (net.HardwareAddr, error) {
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return nil, errors.Wrap(errors.NetworkError, "failed to list network interfaces", err)
+	}
+	for _, iface := range interfaces {
+		if iface.Flags&net.FlagLoopback != 0 || len(iface.HardwareAddr) != 6 {
+			continue
+		}
+		if bytes.Equal(iface.HardwareAddr, make(net.HardwareAddr, 6)) {
+			continue
+		}
+		logger.Debugf("Using MAC address of interface %s", iface.Name)
+		return iface.HardwareAddr, nil
+	}
+	return nil, errors.New(errors.NetworkError, "no network interface with a MAC address found; use --mac")
+}
+
+// ulaGlobalIDOf 返回地址中第 8 到 47 位的全局 ID
+func ulaGlobalIDOf(addr netip.Addr) [5]byte {
+	b := addr.As16()
+	var id [5]byte
+	copy(id[:], b[1:6])
+	return id
+}
+
+// checkULAPrefix 检查前缀是否为本地分配的 ULA，并列出全局 ID 看起来不是随机生成的原因
+func checkULAPrefix(prefix netip.Prefix) ([]string, error) {
+	addr := prefix.Addr()
+	if !addr.Is6() || !ulaPrefix.Contains(addr) {
+		return nil, errors.New(errors.ValidationError, fmt.Sprintf("%s is not a Unique Local Address (fc00::/7)", prefix))
+	}
+	if prefix.Bits() < ulaSiteBits {
+		return nil, errors.New(errors.ValidationError,
+			fmt.Sprintf("%s is shorter than a /%d and does not identify a single ULA site", prefix, ulaSiteBits))
+	}
+
+	var problems []string
+	if !ulaLocalPrefix.Contains(addr) {
+		problems = append(problems, "the L bit is not set; fc00::/8 is not defined for local assignment")
+	}
+	return append(problems, globalIDProblems(ulaGlobalIDOf(addr))...), nil
+}
+
+// globalIDProblems 用简单的启发式规则判断 40 位全局 ID 是否像手工选取的
+func globalIDProblems(globalID [5]byte) []string {
+	digits := hex.EncodeToString(globalID[:])
+	var problems []string
+
+	counts := make(map[rune]int)
+	for _, c := range digits {
+		counts[c]++
+	}
+	switch {
+	case counts['0'] == len(digits):
+		return []string{"the Global ID is all zeros"}
+	case len(counts) == 1:
+		return []string{fmt.Sprintf("the Global ID repeats the digit %s", digits[:1])}
+	case counts['0'] >= 6:
+		problems = append(problems, fmt.Sprintf("the Global ID has %d of 10 zero digits", counts['0']))
+	case len(counts) <= 3:
+		problems = append(problems, fmt.Sprintf("the Global ID uses only %d distinct digits", len(counts)))
+	}
+
+	if run := longestHexSequence(digits); len(run) >= 5 {
+		problems = append(problems, fmt.Sprintf("the Global ID contains the sequence %s", run))
+	}
+	for _, word := range hexWords {
+		if strings.Contains(digits, word) {
+			problems = append(problems, fmt.Sprintf("the Global ID contains the word %q", word))
+		}
+	}
+	return problems
+}
+
+// longestHexSequence 返回最长的连续递增或递减十六进制数字序列，如 12345 或 fedc
+func longestHexSequence(digits string) string {
+	best := digits[:1]
+	for _, step := range []int{1, -1} {
+		start := 0
+		for i := 1; i <= len(digits); i++ {
+			if i < len(digits) && hexDigitValue(digits[i])-hexDigitValue(digits[i-1]) == step {
+				continue
+			}
+			if i-start > len(best) {
+				best = digits[start:i]
+			}
+			start = i
+		}
+	}
+	return best
+}
+
+func hexDigitValue(c byte) int {
+	if c >= 'a' {
+		return int(c-'a') + 10
+	}
+	return int(c - '0')
+}
+
+// formatGlobalID 以 "xx:xxxx:xxxx" 分组显示全局 ID，与其在地址中的位置一致
+func formatGlobalID(globalID [5]byte) string {
+	digits := hex.EncodeToString(globalID[:])
+	return digits[:2] + ":" + digits[2:6] + ":" + digits[6:]
+}
+
+func printULASite(prefix netip.Prefix, globalID [5]byte) {
+	last, _ := offsetPrefix(netip.PrefixFrom(prefix.Addr(), ipv6LANBits), 1<<16-1)
+	fmt.Println("ULA Prefix:", prefix)
+	fmt.Println("Global ID:", formatGlobalID(globalID))
+	fmt.Println("Subnet Range:", netip.PrefixFrom(prefix.Addr(), ipv6LANBits), "-", last)
+	fmt.Println("/64 Subnets:", formatCount(powerOfTwo(ipv6LANBits-ulaSiteBits)))
+}
+
+func generateULA(cmd *cobra.Command) {
+	macFlag, _ := cmd.Flags().GetString("mac")
+	timeFlag, _ := cmd.Flags().GetString("time")
+
+	var mac net.HardwareAddr
+	var err error
+	if macFlag != "" {
+		mac, err = parseMACAddress(macFlag)
+	} else {
+		mac, err = defaultMAC()
+	}
+	if err != nil {
+		logger.PrintErrorWithMessage("invalid MAC address", err)
+		return
+	}
+
+	now := time.Now()
+	if timeFlag != "" {
+		if now, err = time.Parse(time.RFC3339Nano, timeFlag); err != nil {
+			logger.PrintErrorWithMessage("invalid timestamp", errors.Wrap(errors.ParseError, timeFlag, err))
+			return
+		}
+	}
+
+	eui64 := macToEUI64(mac)
+	globalID := ulaGlobalID(now, eui64)
+	prefix := ulaSitePrefix(globalID)
+
+	printULASite(prefix, globalID)
+	fmt.Println("MAC Address:", mac)
+	fmt.Println("EUI-64:", net.HardwareAddr(eui64[:]))
+	fmt.Printf("NTP Timestamp: 0x%016x (%s)\n", ntpTimestamp(now), now.UTC().Format(time.RFC3339Nano))
+
+	logger.Infof("Successfully generated ULA prefix %s", prefix)
+}
+
+func runULA(cmd *cobra.Command, args []string) {
+	if len(args) == 0 {
+		generateULA(cmd)
+		return
+	}
+	if len(args) > 1 {
+		logger.PrintValidationError("expected at most one prefix")
+		if err := cmd.Help(); err != nil {
+			logger.PrintErrorWithMessage("failed to show help", err)
+		}
+		return
+	}
+
+	prefix, err := parsePrefix(args[0])
+	if err != nil {
+		logger.PrintErrorWithMessage("failed to parse ULA prefix", err)
+		return
+	}
+
+	problems, err := checkULAPrefix(prefix)
+	if err != nil {
+		logger.PrintError(err)
+		return
+	}
+
+	site := netip.PrefixFrom(prefix.Addr(), ulaSiteBits).Masked()
+	printULASite(site, ulaGlobalIDOf(site.Addr()))
+	if ulaLocalPrefix.Contains(site.Addr()) {
+		fmt.Println("Locally Assigned: yes")
+	} else {
+		fmt.Println("Locally Assigned: no")
+	}
+	if len(problems) == 0 {
+		fmt.Println("Looks Random: yes")
+	} else {
+		fmt.Println("Looks Random: no")
+		for _, problem := range problems {
+			fmt.Println(" -", problem)
+		}
+		logger.Warnf("%s does not look like an RFC 4193 prefix; generate one with \"macconv ip ula\"", site)
+	}
+
+	logger.Infof("Successfully checked ULA prefix %s", site)
+}
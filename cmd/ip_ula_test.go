@@ -0,0 +1,89 @@
+//go:build unit
+
+/*
+Copyright © 2024-2025 Auska <luodan0709@live.cn>
+
+*/
+
+package cmd
+
+import (
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNTPTimestamp(t *testing.T) {
+	tests := []struct {
+		time     time.Time
+		expected uint64
+	}{
+		{time.Date(1900, 1, 1, 0, 0, 0, 0, time.UTC), 0},
+		{time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), 0xe93c7f0000000000},
+		{time.Date(2024, 1, 1, 0, 0, 0, 500000000, time.UTC), 0xe93c7f0080000000},
+	}
+
+	for _, tt := range tests {
+		if result := ntpTimestamp(tt.time); result != tt.expected {
+			t.Errorf("ntpTimestamp(%v) = %#x, want %#x", tt.time, result, tt.expected)
+		}
+	}
+}
+
+func TestULAGlobalID(t *testing.T) {
+	mac, err := parseMACAddress("00:11:22:33:44:55")
+	if err != nil {
+		t.Fatal(err)
+	}
+	globalID := ulaGlobalID(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), macToEUI64(mac))
+
+	if result := formatGlobalID(globalID); result != "ea:8454:06c9" {
+		t.Errorf("ulaGlobalID() = %s, want ea:8454:06c9", result)
+	}
+	if result := ulaSitePrefix(globalID).String(); result != "fdea:8454:6c9::/48" {
+		t.Errorf("ulaSitePrefix() = %s, want fdea:8454:6c9::/48", result)
+	}
+	if ulaGlobalIDOf(ulaSitePrefix(globalID).Addr()) != globalID {
+		t.Error("ulaGlobalIDOf() does not round-trip the Global ID")
+	}
+}
+
+func TestCheckULAPrefix(t *testing.T) {
+	tests := []struct {
+		prefix   string
+		problems []string
+		wantErr  bool
+	}{
+		{"fdea:8454:6c9::/48", nil, false},
+		{"fd3c:91e2:6b04:10::1/128", nil, false},
+		{"fd00::/48", []string{"all zeros"}, false},
+		{"fdff:ffff:ffff::/48", []string{"repeats the digit f"}, false},
+		{"fd00:0:1::/48", []string{"9 of 10 zero digits"}, false},
+		{"fd11:2211:2211::/48", []string{"only 2 distinct digits"}, false},
+		{"fd12:3456:789a::/48", []string{"sequence 123456789a"}, false},
+		{"fd9f:edcb:a170::/48", []string{"sequence fedcba"}, false},
+		{"fd00:dead:beef::/48", []string{`"dead"`, `"beef"`}, false},
+		{"fc3c:91e2:6b04::/48", []string{"L bit is not set"}, false},
+		{"fd00::/8", nil, true},
+		{"2001:db8::/48", nil, true},
+		{"10.0.0.0/8", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.prefix, func(t *testing.T) {
+			problems, err := checkULAPrefix(netip.MustParsePrefix(tt.prefix))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("checkULAPrefix() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(problems) != len(tt.problems) {
+				t.Fatalf("checkULAPrefix() = %q, want %d problems matching %q", problems, len(tt.problems), tt.problems)
+			}
+			for i, want := range tt.problems {
+				if !strings.Contains(problems[i], want) {
+					t.Errorf("problem %d = %q, want containing %q", i, problems[i], want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,438 @@
+/*
+Copyright © 2024-2025 Auska <luodan0709@live.cn>
+
+*/
+
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math/big"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"macconv/pkg/errors"
+	"macconv/pkg/logger"
+	"macconv/pkg/validator"
+)
+
+const defaultZoneLimit = 65536
+
+var ipZoneCmd = &cobra.Command{
+	Use:   "zone CIDR",
+	Short: "Generate reverse DNS zones and forward records for a CIDR",
+	Long: `
+Generate BIND-format reverse zones (in-addr.arpa or ip6.arpa) with one PTR
+record per host, named from a template. IPv4 blocks are split into /8, /16 or
+/24 zones and IPv6 blocks into nibble-aligned zones; IPv4 blocks longer than
+/24 use RFC 2317 classless delegation and also print the CNAME and NS records
+to add to the parent /24 zone. For example:
+
+	macconv ip zone 192.0.2.0/22 --template "host-{a}-{b}-{c}-{d}.example.net" --ns ns1.example.net
+	macconv ip zone 192.0.2.64/26 --template "{d}.dyn.example.net" --ns ns1.example.net --forward
+	macconv ip zone 2001:db8:1::/48 --hosts 2001:db8:1:10::/120 --template "v6-{ip}.example.net" --ns ns1.example.net
+	macconv ip zone 10.1.0.0/16 --template "h{n}.example.net" --ns ns1.example.net --output zones/
+
+Template placeholders:
+	{a} {b} {c} {d}  IPv4 octets
+	{ip}             the address with "." or ":" replaced by "-" (IPv6 is fully expanded)
+	{hex}            the address as hex digits
+	{n}              index of the host within the generated list, starting at 1`,
+	Run: generateReverseZone,
+}
+
+func init() {
+	ipCmd.AddCommand(ipZoneCmd)
+	ipZoneCmd.Flags().StringP("template", "t", "", "Host name template for PTR targets")
+	ipZoneCmd.Flags().StringSlice("ns", nil, "Authoritative name servers (first one is used in the SOA)")
+	ipZoneCmd.Flags().String("email", "", "SOA responsible mailbox (default: hostmaster in the first name server's domain)")
+	ipZoneCmd.Flags().Uint32("serial", 0, "SOA serial (default: YYYYMMDD01)")
+	ipZoneCmd.Flags().Uint32("ttl", 3600, "Default TTL")
+	ipZoneCmd.Flags().String("hosts", "", "Only generate records for addresses in this CIDR")
+	ipZoneCmd.Flags().Bool("all", false, "Include network, broadcast and anycast addresses")
+	ipZoneCmd.Flags().Bool("forward", false, "Also generate matching A/AAAA records")
+	ipZoneCmd.Flags().StringP("output", "o", "", "Write one file per zone into this directory instead of stdout")
+	ipZoneCmd.Flags().Int64("limit", defaultZoneLimit, "Maximum number of records to generate, 0 for no limit")
+}
+
+// zoneSOA 生成 SOA 和 NS 记录所需的参数
+type zoneSOA struct {
+	NameServers []string
+	Email       string
+	Serial      uint32
+	TTL         uint32
+}
+
+// zoneRecord 一条 PTR 记录及其对应的正向名称
+type zoneRecord struct {
+	Addr netip.Addr
+	Name string
+}
+
+// reverseZone 一个反向解析区域
+type reverseZone struct {
+	Prefix    netip.Prefix
+	Origin    string
+	Classless bool
+	Records   []zoneRecord
+}
+
+// reverseZonePrefixes 将网段拆分为按反向域名边界对齐的区域：
+// IPv4 按 8 位、IPv6 按 4 位对齐，长于 /24 的 IPv4 网段保持原样（RFC 2317）
+func reverseZonePrefixes(prefix netip.Prefix) []netip.Prefix {
+	prefix = prefix.Masked()
+	step := 4
+	if prefix.Addr().Is4() {
+		if prefix.Bits() > 24 {
+			return []netip.Prefix{prefix}
+		}
+		step = 8
+	}
+
+	bits := (prefix.Bits() + step - 1) / step * step
+	count := 1 << (bits - prefix.Bits())
+	zones := make([]netip.Prefix, 0, count)
+	zone := netip.PrefixFrom(prefix.Addr(), bits)
+	for i := 0; i < count; i++ {
+		zones = append(zones, zone)
+		if next, err := offsetPrefix(zone, 1); err == nil {
+			zone = next
+		}
+	}
+	return zones
+}
+
+// reverseZoneOrigin 返回区域的 $ORIGIN，RFC 2317 区域使用 "first/bits.c.b.a.in-addr.arpa."
+func reverseZoneOrigin(prefix netip.Prefix) string {
+	name := reversePointerName(prefix.Addr())
+	labels := strings.Split(strings.TrimSuffix(name, "."), ".")
+
+	if prefix.Addr().Is4() {
+		if prefix.Bits() > 24 {
+			parent := strings.Join(labels[1:], ".")
+			return fmt.Sprintf("%s/%d.%s.", labels[0], prefix.Bits(), parent)
+		}
+		return strings.Join(labels[4-prefix.Bits()/8:], ".") + "."
+	}
+	return strings.Join(labels[32-prefix.Bits()/4:], ".") + "."
+}
+
+// relativeOwner 返回地址在所属区域中的相对名称，地址本身就是区域（如 IPv6 /128）时为 @
+func (z *reverseZone) relativeOwner(addr netip.Addr) string {
+	if z.Classless {
+		return strconv.Itoa(int(addr.As4()[3]))
+	}
+	name := reversePointerName(addr)
+	if name == z.Origin {
+		return "@"
+	}
+	return strings.TrimSuffix(name, "."+z.Origin)
+}
+
+// expandHostTemplate 展开主机名模板并补全结尾的点
+func expandHostTemplate(tmpl string, addr netip.Addr, index int64) string {
+	var ip, hex string
+	pairs := []string{"{n}", strconv.FormatInt(index, 10)}
+
+	if addr.Is4() {
+		b := addr.As4()
+		ip = strings.ReplaceAll(addr.String(), ".", "-")
+		hex = fmt.Sprintf("%02x%02x%02x%02x", b[0], b[1], b[2], b[3])
+		pairs = append(pairs,
+			"{a}", strconv.Itoa(int(b[0])), "{b}", strconv.Itoa(int(b[1])),
+			"{c}", strconv.Itoa(int(b[2])), "{d}", strconv.Itoa(int(b[3])))
+	} else {
+		ip = strings.ReplaceAll(addr.StringExpanded(), ":", "-")
+		hex = fmt.Sprintf("%032x", addrToInt(addr))
+	}
+	pairs = append(pairs, "{ip}", ip, "{hex}", hex)
+
+	name := strings.NewReplacer(pairs...).Replace(tmpl)
+	if !strings.HasSuffix(name, ".") {
+		name += "."
+	}
+	return name
+}
+
+// validateHostTemplate 检查模板展开后是否为合法的主机名
+func validateHostTemplate(tmpl string, sample netip.Addr) error {
+	if tmpl == "" {
+		return errors.New(errors.ValidationError, "--template is required")
+	}
+	name := expandHostTemplate(tmpl, sample, 1)
+	if strings.ContainsAny(name, "{}") {
+		return errors.New(errors.ValidationError, fmt.Sprintf("template %q has unknown placeholders for this address family", tmpl))
+	}
+	for _, label := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+		if label == "" || len(label) > 63 {
+			return errors.New(errors.ValidationError, fmt.Sprintf("template produces an invalid host name: %s", name))
+		}
+	}
+	return nil
+}
+
+// buildReverseZones 拆分区域并为 [first, last] 中的每个地址生成记录
+func buildReverseZones(prefix netip.Prefix, first, last netip.Addr, tmpl string, limit int64) ([]*reverseZone, error) {
+	count := strideCount(first, last, big.NewInt(1))
+	if limit > 0 && count.Cmp(big.NewInt(limit)) > 0 {
+		return nil, errors.New(errors.ValidationError,
+			fmt.Sprintf("%s records exceed the limit of %d; narrow them with --hosts or raise --limit", count, limit))
+	}
+
+	var zones []*reverseZone
+	for _, p := range reverseZonePrefixes(prefix) {
+		zones = append(zones, &reverseZone{
+			Prefix:    p,
+			Origin:    reverseZoneOrigin(p),
+			Classless: p.Addr().Is4() && p.Bits() > 24,
+		})
+	}
+
+	var index int64
+	zone := 0
+	enumerateHosts(first, last, big.NewInt(1), func(addr netip.Addr) {
+		index++
+		for !zones[zone].Prefix.Contains(addr) {
+			zone++
+		}
+		zones[zone].Records = append(zones[zone].Records, zoneRecord{Addr: addr, Name: expandHostTemplate(tmpl, addr, index)})
+	})
+
+	// 只保留包含记录的区域
+	kept := zones[:0]
+	for _, z := range zones {
+		if len(z.Records) > 0 {
+			kept = append(kept, z)
+		}
+	}
+	return kept, nil
+}
+
+// fqdn 补全结尾的点
+func fqdn(name string) string {
+	if strings.HasSuffix(name, ".") {
+		return name
+	}
+	return name + "."
+}
+
+// defaultZoneEmail 默认使用第一个名称服务器所在域的 hostmaster
+func defaultZoneEmail(ns string) string {
+	name := fqdn(ns)
+	if i := strings.Index(name, "."); i >= 0 && i < len(name)-1 {
+		return "hostmaster." + name[i+1:]
+	}
+	return "hostmaster." + name
+}
+
+// writeSOA 输出 $TTL、$ORIGIN、SOA 和 NS 记录
+func writeSOA(w io.Writer, origin string, soa zoneSOA) {
+	fmt.Fprintf(w, "$TTL %d\n", soa.TTL)
+	fmt.Fprintf(w, "$ORIGIN %s\n", origin)
+	fmt.Fprintf(w, "@\tIN\tSOA\t%s %s (\n", fqdn(soa.NameServers[0]), fqdn(soa.Email))
+	fmt.Fprintf(w, "\t\t%d ; serial\n", soa.Serial)
+	fmt.Fprintf(w, "\t\t%d ; refresh\n", soa.TTL)
+	fmt.Fprintf(w, "\t\t%d ; retry\n", 900)
+	fmt.Fprintf(w, "\t\t%d ; expire\n", 1209600)
+	fmt.Fprintf(w, "\t\t%d ) ; minimum\n", soa.TTL)
+	for _, ns := range soa.NameServers {
+		fmt.Fprintf(w, "@\tIN\tNS\t%s\n", fqdn(ns))
+	}
+}
+
+// writeReverseZone 输出一个完整的反向区域文件
+func writeReverseZone(w io.Writer, z *reverseZone, soa zoneSOA) {
+	writeSOA(w, z.Origin, soa)
+	fmt.Fprintln(w)
+	for _, r := range z.Records {
+		fmt.Fprintf(w, "%s\tIN\tPTR\t%s\n", z.relativeOwner(r.Addr), r.Name)
+	}
+}
+
+// writeClasslessDelegation 输出需要添加到父 /24 区域中的 NS 和 CNAME 记录（RFC 2317）
+func writeClasslessDelegation(w io.Writer, z *reverseZone, soa zoneSOA) {
+	parent := netip.PrefixFrom(z.Prefix.Addr(), 24).Masked()
+	fmt.Fprintf(w, "; Add to the parent zone %s\n", reverseZoneOrigin(parent))
+	for _, ns := range soa.NameServers {
+		fmt.Fprintf(w, "%s\tIN\tNS\t%s\n", z.Origin, fqdn(ns))
+	}
+	for _, r := range z.Records {
+		owner := z.relativeOwner(r.Addr)
+		fmt.Fprintf(w, "%s\tIN\tCNAME\t%s.%s\n", owner, owner, z.Origin)
+	}
+}
+
+// writeForwardRecords 输出与 PTR 记录对应的 A/AAAA 记录
+func writeForwardRecords(w io.Writer, zones []*reverseZone) {
+	for _, z := range zones {
+		for _, r := range z.Records {
+			rrType := "A"
+			if r.Addr.Is6() {
+				rrType = "AAAA"
+			}
+			fmt.Fprintf(w, "%s\tIN\t%s\t%s\n", r.Name, rrType, r.Addr)
+		}
+	}
+}
+
+// zoneFileName 将区域名转换为文件名
+func zoneFileName(origin string) string {
+	return strings.ReplaceAll(strings.TrimSuffix(origin, "."), "/", "-") + ".zone"
+}
+
+// writeZoneFile 创建文件并调用 write 写入内容
+func writeZoneFile(path string, write func(w io.Writer)) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return errors.Wrap(errors.FileSystemError, fmt.Sprintf("failed to create %s", path), err)
+	}
+	w := bufio.NewWriter(f)
+	write(w)
+	if err := w.Flush(); err != nil {
+		_ = f.Close()
+		return errors.Wrap(errors.FileSystemError, fmt.Sprintf("failed to write %s", path), err)
+	}
+	if err := f.Close(); err != nil {
+		return errors.Wrap(errors.FileSystemError, fmt.Sprintf("failed to write %s", path), err)
+	}
+	logger.Debugf("Wrote %s", path)
+	return nil
+}
+
+func generateReverseZone(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		logger.PrintValidationError("missing CIDR argument")
+		if err := cmd.Help(); err != nil {
+			logger.PrintErrorWithMessage("failed to show help", err)
+		}
+		return
+	}
+
+	tmpl, _ := cmd.Flags().GetString("template")
+	nameServers, _ := cmd.Flags().GetStringSlice("ns")
+	email, _ := cmd.Flags().GetString("email")
+	serial, _ := cmd.Flags().GetUint32("serial")
+	ttl, _ := cmd.Flags().GetUint32("ttl")
+	hosts, _ := cmd.Flags().GetString("hosts")
+	includeAll, _ := cmd.Flags().GetBool("all")
+	forward, _ := cmd.Flags().GetBool("forward")
+	output, _ := cmd.Flags().GetString("output")
+	limit, _ := cmd.Flags().GetInt64("limit")
+
+	prefix, err := parsePrefix(args[0])
+	if err != nil {
+		logger.PrintErrorWithMessage("failed to parse CIDR address", err)
+		return
+	}
+	if err := validateHostTemplate(tmpl, prefix.Addr()); err != nil {
+		logger.PrintError(err)
+		return
+	}
+	if len(nameServers) == 0 {
+		logger.PrintValidationError("at least one --ns is required")
+		return
+	}
+
+	if hosts == "" {
+		hosts = prefix.String()
+	}
+	hostPrefix, err := parsePrefix(hosts)
+	if err != nil {
+		logger.PrintErrorWithMessage("failed to parse --hosts", err)
+		return
+	}
+	if hostPrefix.Bits() < prefix.Bits() || !prefix.Contains(hostPrefix.Addr()) {
+		logger.PrintValidationError(fmt.Sprintf("--hosts %s is not inside %s", hostPrefix, prefix))
+		return
+	}
+	first, last, err := hostRange(hostPrefix.String(), includeAll)
+	if err != nil {
+		logger.PrintErrorWithMessage("failed to calculate host range", err)
+		return
+	}
+
+	zones, err := buildReverseZones(prefix, first, last, tmpl, limit)
+	if err != nil {
+		logger.PrintError(err)
+		return
+	}
+
+	soa := zoneSOA{NameServers: nameServers, Email: email, Serial: serial, TTL: ttl}
+	if soa.Email == "" {
+		soa.Email = defaultZoneEmail(nameServers[0])
+	}
+	if soa.Serial == 0 {
+		serial, _ := strconv.ParseUint(time.Now().Format("20060102")+"01", 10, 32)
+		soa.Serial = uint32(serial)
+	}
+
+	if output != "" {
+		if err := writeZoneFiles(output, zones, soa, forward); err != nil {
+			logger.PrintErrorWithMessage("failed to write zone files", err)
+			return
+		}
+	} else {
+		w := bufio.NewWriter(os.Stdout)
+		for i, z := range zones {
+			if i > 0 {
+				fmt.Fprintln(w)
+			}
+			fmt.Fprintf(w, "; Zone %s (%s)\n", z.Origin, z.Prefix)
+			writeReverseZone(w, z, soa)
+			if z.Classless {
+				fmt.Fprintln(w)
+				writeClasslessDelegation(w, z, soa)
+			}
+		}
+		if forward {
+			fmt.Fprintln(w)
+			fmt.Fprintln(w, "; Forward records")
+			writeForwardRecords(w, zones)
+		}
+		if err := w.Flush(); err != nil {
+			logger.Debugf("Failed to flush output: %v", err)
+		}
+	}
+
+	logger.Infof("Successfully generated %d reverse zones for %s", len(zones), prefix)
+}
+
+// writeZoneFiles 在 dir 中为每个区域写入一个文件，另写入委派和正向记录
+func writeZoneFiles(dir string, zones []*reverseZone, soa zoneSOA, forward bool) error {
+	if err := validator.ValidateFilePath(dir); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return errors.Wrap(errors.FileSystemError, fmt.Sprintf("failed to create %s", dir), err)
+	}
+
+	for _, z := range zones {
+		if err := writeZoneFile(filepath.Join(dir, zoneFileName(z.Origin)), func(w io.Writer) {
+			writeReverseZone(w, z, soa)
+		}); err != nil {
+			return err
+		}
+		if z.Classless {
+			name := strings.TrimSuffix(zoneFileName(z.Origin), ".zone") + ".delegation"
+			if err := writeZoneFile(filepath.Join(dir, name), func(w io.Writer) {
+				writeClasslessDelegation(w, z, soa)
+			}); err != nil {
+				return err
+			}
+		}
+	}
+
+	if forward {
+		return writeZoneFile(filepath.Join(dir, "forward.records"), func(w io.Writer) {
+			writeForwardRecords(w, zones)
+		})
+	}
+	return nil
+}
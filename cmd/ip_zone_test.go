@@ -0,0 +1,168 @@
+//go:build unit
+
+/*
+Copyright © 2024-2025 Auska <luodan0709@live.cn>
+
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+func TestReverseZonePrefixes(t *testing.T) {
+	tests := []struct {
+		prefix   string
+		expected []string
+	}{
+		{"192.0.2.0/24", []string{"192.0.2.0/24"}},
+		{"192.0.0.0/22", []string{"192.0.0.0/24", "192.0.1.0/24", "192.0.2.0/24", "192.0.3.0/24"}},
+		{"10.0.0.0/15", []string{"10.0.0.0/16", "10.1.0.0/16"}},
+		{"192.0.2.64/26", []string{"192.0.2.64/26"}},
+		{"2001:db8::/48", []string{"2001:db8::/48"}},
+		{"2001:db8::/47", []string{"2001:db8::/48", "2001:db8:1::/48"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.prefix, func(t *testing.T) {
+			result := reverseZonePrefixes(netip.MustParsePrefix(tt.prefix))
+			if len(result) != len(tt.expected) {
+				t.Fatalf("reverseZonePrefixes() = %v, want %v", result, tt.expected)
+			}
+			for i := range result {
+				if result[i].String() != tt.expected[i] {
+					t.Errorf("reverseZonePrefixes()[%d] = %v, want %v", i, result[i], tt.expected[i])
+				}
+			}
+		})
+	}
+}
+
+func TestReverseZoneOrigin(t *testing.T) {
+	tests := []struct {
+		prefix   string
+		expected string
+	}{
+		{"192.0.2.0/24", "2.0.192.in-addr.arpa."},
+		{"10.1.0.0/16", "1.10.in-addr.arpa."},
+		{"10.0.0.0/8", "10.in-addr.arpa."},
+		{"192.0.2.64/26", "64/26.2.0.192.in-addr.arpa."},
+		{"2001:db8::/32", "8.b.d.0.1.0.0.2.ip6.arpa."},
+		{"2001:db8:1::/48", "1.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa."},
+	}
+
+	for _, tt := range tests {
+		if result := reverseZoneOrigin(netip.MustParsePrefix(tt.prefix)); result != tt.expected {
+			t.Errorf("reverseZoneOrigin(%s) = %s, want %s", tt.prefix, result, tt.expected)
+		}
+	}
+}
+
+func TestExpandHostTemplate(t *testing.T) {
+	tests := []struct {
+		tmpl     string
+		addr     string
+		expected string
+	}{
+		{"host-{a}-{b}-{c}-{d}.example.net", "192.0.2.10", "host-192-0-2-10.example.net."},
+		{"{ip}.example.net.", "10.0.0.1", "10-0-0-1.example.net."},
+		{"h{n}.example.net", "10.0.0.1", "h7.example.net."},
+		{"x{hex}.example.net", "10.0.0.1", "x0a000001.example.net."},
+		{"v6-{ip}.example.net", "2001:db8::1", "v6-2001-0db8-0000-0000-0000-0000-0000-0001.example.net."},
+	}
+
+	for _, tt := range tests {
+		if result := expandHostTemplate(tt.tmpl, netip.MustParseAddr(tt.addr), 7); result != tt.expected {
+			t.Errorf("expandHostTemplate(%q) = %s, want %s", tt.tmpl, result, tt.expected)
+		}
+	}
+}
+
+func TestValidateHostTemplate(t *testing.T) {
+	v4 := netip.MustParseAddr("192.0.2.1")
+	v6 := netip.MustParseAddr("2001:db8::1")
+
+	if err := validateHostTemplate("host-{d}.example.net", v4); err != nil {
+		t.Errorf("valid template rejected: %v", err)
+	}
+	if err := validateHostTemplate("", v4); err == nil {
+		t.Error("empty template should be rejected")
+	}
+	if err := validateHostTemplate("host-{d}.example.net", v6); err == nil {
+		t.Error("IPv4 placeholders should be rejected for IPv6")
+	}
+	if err := validateHostTemplate("host..example.net", v4); err == nil {
+		t.Error("empty label should be rejected")
+	}
+}
+
+func TestBuildReverseZones(t *testing.T) {
+	prefix := netip.MustParsePrefix("10.0.0.0/23")
+	zones, err := buildReverseZones(prefix, netip.MustParseAddr("10.0.0.254"), netip.MustParseAddr("10.0.1.1"), "h{n}.example.net", 0)
+	if err != nil {
+		t.Fatalf("buildReverseZones() error = %v", err)
+	}
+	if len(zones) != 2 || len(zones[0].Records) != 2 || len(zones[1].Records) != 2 {
+		t.Fatalf("buildReverseZones() = %+v, want two zones with two records each", zones)
+	}
+	if owner := zones[1].relativeOwner(zones[1].Records[1].Addr); owner != "1" {
+		t.Errorf("relativeOwner() = %s, want 1", owner)
+	}
+	if zones[1].Records[1].Name != "h4.example.net." {
+		t.Errorf("record name = %s, want h4.example.net.", zones[1].Records[1].Name)
+	}
+
+	if _, err := buildReverseZones(prefix, prefix.Addr(), prefixLastAddr(prefix), "h.example.net", 100); err == nil {
+		t.Error("expected limit error")
+	}
+}
+
+func TestReverseZoneOwnerIsOrigin(t *testing.T) {
+	prefix := netip.MustParsePrefix("2001:db8:1::/126")
+	zones, err := buildReverseZones(prefix, prefix.Addr(), prefixLastAddr(prefix), "h{n}.example.net", 0)
+	if err != nil || len(zones) != 4 {
+		t.Fatalf("buildReverseZones() = %v, %v, want four /128 zones", zones, err)
+	}
+	soa := zoneSOA{NameServers: []string{"ns1.example.net"}, Email: defaultZoneEmail("ns1.example.net"), Serial: 1, TTL: 300}
+
+	var zone bytes.Buffer
+	writeReverseZone(&zone, zones[1], soa)
+	if !strings.Contains(zone.String(), "\n@\tIN\tPTR\th2.example.net.\n") {
+		t.Errorf("zone output missing @ owner:\n%s", zone.String())
+	}
+}
+
+func TestWriteClassless(t *testing.T) {
+	prefix := netip.MustParsePrefix("192.0.2.64/30")
+	zones, err := buildReverseZones(prefix, netip.MustParseAddr("192.0.2.65"), netip.MustParseAddr("192.0.2.66"), "{d}.example.net", 0)
+	if err != nil || len(zones) != 1 {
+		t.Fatalf("buildReverseZones() = %v, %v", zones, err)
+	}
+	soa := zoneSOA{NameServers: []string{"ns1.example.net"}, Email: defaultZoneEmail("ns1.example.net"), Serial: 1, TTL: 300}
+
+	var zone, delegation bytes.Buffer
+	writeReverseZone(&zone, zones[0], soa)
+	writeClasslessDelegation(&delegation, zones[0], soa)
+
+	for _, want := range []string{
+		"$ORIGIN 64/30.2.0.192.in-addr.arpa.",
+		"SOA\tns1.example.net. hostmaster.example.net. (",
+		"65\tIN\tPTR\t65.example.net.",
+	} {
+		if !strings.Contains(zone.String(), want) {
+			t.Errorf("zone output missing %q:\n%s", want, zone.String())
+		}
+	}
+	for _, want := range []string{
+		"64/30.2.0.192.in-addr.arpa.\tIN\tNS\tns1.example.net.",
+		"66\tIN\tCNAME\t66.64/30.2.0.192.in-addr.arpa.",
+	} {
+		if !strings.Contains(delegation.String(), want) {
+			t.Errorf("delegation output missing %q:\n%s", want, delegation.String())
+		}
+	}
+}
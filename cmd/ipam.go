@@ -0,0 +1,534 @@
+/*
+Copyright © 2024-2025 Auska <luodan0709@live.cn>
+
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/spf13/cobra"
+	"macconv/pkg/errors"
+	"macconv/pkg/logger"
+	"macconv/pkg/validator"
+)
+
+const defaultIPAMFile = "ipam.json"
+
+var ipamCmd = &cobra.Command{
+	Use:   "ipam",
+	Short: "Track address pools and allocations in a local file",
+	Long: `
+A small IP address manager backed by a local JSON file (only JSON is
+supported). Declare pools, allocate the next free subnet or host, reserve or
+release prefixes and report utilization. For example:
+
+	macconv ipam pool add dc1 10.10.0.0/16 --description "DC1 servers"
+	macconv ipam allocate dc1 --size 24 --description "vlan 110"
+	macconv ipam allocate dc1 --host --description "gw"
+	macconv ipam reserve dc1 10.10.200.0/22 --description "future"
+	macconv ipam release dc1 10.10.0.0/24
+	macconv ipam list
+	macconv ipam list dc1`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := cmd.Help(); err != nil {
+			logger.PrintErrorWithMessage("failed to show help", err)
+		}
+	},
+}
+
+var ipamPoolCmd = &cobra.Command{
+	Use:   "pool",
+	Short: "Manage address pools",
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := cmd.Help(); err != nil {
+			logger.PrintErrorWithMessage("failed to show help", err)
+		}
+	},
+}
+
+var ipamPoolAddCmd = &cobra.Command{
+	Use:   "add NAME CIDR",
+	Short: "Declare a new pool",
+	Run:   ipamPoolAdd,
+}
+
+var ipamPoolRemoveCmd = &cobra.Command{
+	Use:   "remove NAME",
+	Short: "Remove an empty pool",
+	Run:   ipamPoolRemove,
+}
+
+var ipamAllocateCmd = &cobra.Command{
+	Use:   "allocate POOL",
+	Short: "Allocate the next free subnet or host",
+	Run:   ipamAllocate,
+}
+
+var ipamReserveCmd = &cobra.Command{
+	Use:   "reserve POOL CIDR",
+	Short: "Reserve a specific prefix or address",
+	Run:   ipamReserve,
+}
+
+var ipamReleaseCmd = &cobra.Command{
+	Use:   "release POOL CIDR",
+	Short: "Release an allocation",
+	Run:   ipamRelease,
+}
+
+var ipamListCmd = &cobra.Command{
+	Use:   "list [POOL]",
+	Short: "Show pool utilization and allocations",
+	Run:   ipamList,
+}
+
+func init() {
+	rootCmd.AddCommand(ipamCmd)
+	ipamCmd.AddCommand(ipamPoolCmd, ipamAllocateCmd, ipamReserveCmd, ipamReleaseCmd, ipamListCmd)
+	ipamPoolCmd.AddCommand(ipamPoolAddCmd, ipamPoolRemoveCmd)
+
+	ipamCmd.PersistentFlags().String("db", defaultIPAMFile, "IPAM database file (JSON)")
+	ipamPoolAddCmd.Flags().StringP("description", "d", "", "Pool description")
+	ipamAllocateCmd.Flags().Int("size", 0, "Prefix length of the subnet to allocate")
+	ipamAllocateCmd.Flags().Bool("host", false, "Allocate a single host address")
+	ipamAllocateCmd.Flags().StringP("description", "d", "", "Allocation description")
+	ipamReserveCmd.Flags().StringP("description", "d", "", "Reservation description")
+}
+
+// ipamAllocation 池中的一条分配记录
+type ipamAllocation struct {
+	Prefix      netip.Prefix `json:"prefix"`
+	Description string       `json:"description,omitempty"`
+	Created     time.Time    `json:"created"`
+}
+
+// ipamPool 地址池
+type ipamPool struct {
+	Name        string           `json:"name"`
+	Prefix      netip.Prefix     `json:"prefix"`
+	Description string           `json:"description,omitempty"`
+	Allocations []ipamAllocation `json:"allocations"`
+}
+
+// ipamDB IPAM 数据文件内容
+type ipamDB struct {
+	Pools []*ipamPool `json:"pools"`
+}
+
+// loadIPAM 读取数据文件，文件不存在时返回空数据库
+func loadIPAM(path string) (*ipamDB, error) {
+	if err := validator.ValidateFilePath(path); err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &ipamDB{}, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(errors.FileSystemError, fmt.Sprintf("failed to read %s", path), err)
+	}
+
+	var db ipamDB
+	if err := json.Unmarshal(data, &db); err != nil {
+		return nil, errors.Wrap(errors.ParseError, fmt.Sprintf("invalid IPAM file %s", path), err)
+	}
+	return &db, nil
+}
+
+// saveIPAM 先写临时文件再重命名，避免中断时损坏数据文件
+func saveIPAM(path string, db *ipamDB) error {
+	data, err := json.MarshalIndent(db, "", "  ")
+	if err != nil {
+		return errors.Wrap(errors.ParseError, "failed to encode IPAM data", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".ipam-*.json")
+	if err != nil {
+		return errors.Wrap(errors.FileSystemError, "failed to create temporary file", err)
+	}
+	tmpName := tmp.Name()
+
+	// CreateTemp 以 0600 创建文件，重命名前沿用原文件的权限
+	mode := os.FileMode(0o644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmpName)
+		return errors.Wrap(errors.FileSystemError, "failed to set IPAM file permissions", err)
+	}
+
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmpName)
+		return errors.Wrap(errors.FileSystemError, "failed to write IPAM file", err)
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmpName)
+		return errors.Wrap(errors.FileSystemError, "failed to write IPAM file", err)
+	}
+	if err := os.Rename(tmpName, path); err != nil {
+		_ = os.Remove(tmpName)
+		return errors.Wrap(errors.FileSystemError, fmt.Sprintf("failed to replace %s", path), err)
+	}
+	return nil
+}
+
+func (db *ipamDB) pool(name string) (*ipamPool, error) {
+	for _, p := range db.Pools {
+		if p.Name == name {
+			return p, nil
+		}
+	}
+	return nil, errors.New(errors.ValidationError, fmt.Sprintf("pool %q not found", name))
+}
+
+// addPool 添加地址池，名称和网段都不能与已有池冲突
+func (db *ipamDB) addPool(name string, prefix netip.Prefix, description string) error {
+	if name == "" {
+		return errors.New(errors.ValidationError, "pool name must not be empty")
+	}
+	for _, p := range db.Pools {
+		if p.Name == name {
+			return errors.New(errors.ValidationError, fmt.Sprintf("pool %q already exists", name))
+		}
+		if p.Prefix.Overlaps(prefix) {
+			return errors.New(errors.ValidationError, fmt.Sprintf("%s overlaps pool %q (%s)", prefix, p.Name, p.Prefix))
+		}
+	}
+
+	db.Pools = append(db.Pools, &ipamPool{Name: name, Prefix: prefix, Description: description})
+	return nil
+}
+
+// removePool 删除没有分配记录的地址池
+func (db *ipamDB) removePool(name string) error {
+	for i, p := range db.Pools {
+		if p.Name != name {
+			continue
+		}
+		if len(p.Allocations) > 0 {
+			return errors.New(errors.ValidationError, fmt.Sprintf("pool %q still has %d allocations", name, len(p.Allocations)))
+		}
+		db.Pools = append(db.Pools[:i], db.Pools[i+1:]...)
+		return nil
+	}
+	return errors.New(errors.ValidationError, fmt.Sprintf("pool %q not found", name))
+}
+
+// sortedRanges 返回按起始地址排序的已分配范围
+func (p *ipamPool) sortedRanges() []addrRange {
+	ranges := make([]addrRange, len(p.Allocations))
+	for i, a := range p.Allocations {
+		ranges[i] = prefixRange(a.Prefix)
+	}
+	sort.Slice(ranges, func(i, j int) bool {
+		return ranges[i].First.Less(ranges[j].First)
+	})
+	return ranges
+}
+
+// reserve 登记指定网段，必须位于池内且不与已有分配重叠
+func (p *ipamPool) reserve(prefix netip.Prefix, description string, now time.Time) error {
+	prefix = prefix.Masked()
+	if prefix.Bits() < p.Prefix.Bits() || !p.Prefix.Contains(prefix.Addr()) {
+		return errors.New(errors.ValidationError, fmt.Sprintf("%s is not inside pool %q (%s)", prefix, p.Name, p.Prefix))
+	}
+	for _, a := range p.Allocations {
+		if a.Prefix.Overlaps(prefix) {
+			return errors.New(errors.ValidationError, fmt.Sprintf("%s overlaps existing allocation %s", prefix, a.Prefix))
+		}
+	}
+
+	p.Allocations = append(p.Allocations, ipamAllocation{Prefix: prefix, Description: description, Created: now})
+	sort.Slice(p.Allocations, func(i, j int) bool {
+		return p.Allocations[i].Prefix.Addr().Less(p.Allocations[j].Prefix.Addr())
+	})
+	return nil
+}
+
+// release 删除与 prefix 完全相同的分配记录
+func (p *ipamPool) release(prefix netip.Prefix) error {
+	prefix = prefix.Masked()
+	for i, a := range p.Allocations {
+		if a.Prefix == prefix {
+			p.Allocations = append(p.Allocations[:i], p.Allocations[i+1:]...)
+			return nil
+		}
+	}
+	return errors.New(errors.ValidationError, fmt.Sprintf("%s is not allocated in pool %q", prefix, p.Name))
+}
+
+// findFreePrefix 在 [lo, hi] 中找到第一个未被占用、按 bits 对齐的网段
+// used 必须按起始地址排序且互不重叠
+func findFreePrefix(lo, hi netip.Addr, bits int, used []addrRange) (netip.Prefix, bool) {
+	cursor := lo
+	i := 0
+	for {
+		candidate := netip.PrefixFrom(cursor, bits).Masked()
+		if candidate.Addr() != cursor {
+			next, err := offsetPrefix(candidate, 1)
+			if err != nil {
+				return netip.Prefix{}, false
+			}
+			candidate = next
+		}
+		r := prefixRange(candidate)
+		if r.Last.Compare(hi) > 0 {
+			return netip.Prefix{}, false
+		}
+
+		// 跳过所有结束在候选网段之前的分配
+		for i < len(used) && used[i].Last.Less(r.First) {
+			i++
+		}
+		if i == len(used) || r.Last.Less(used[i].First) {
+			return candidate, true
+		}
+
+		cursor = used[i].Last.Next()
+		if !cursor.IsValid() {
+			return netip.Prefix{}, false
+		}
+	}
+}
+
+// allocate 分配下一个空闲网段，bits 等于地址位数时分配单个主机地址
+func (p *ipamPool) allocate(bits int, description string, now time.Time) (netip.Prefix, error) {
+	if bits < p.Prefix.Bits() || bits > p.Prefix.Addr().BitLen() {
+		return netip.Prefix{}, errors.New(errors.ValidationError,
+			fmt.Sprintf("size /%d does not fit in pool %q (%s)", bits, p.Name, p.Prefix))
+	}
+
+	lo, hi := p.Prefix.Addr(), prefixLastAddr(p.Prefix)
+	if bits == p.Prefix.Addr().BitLen() {
+		// 分配主机地址时跳过网络地址、广播地址等不可用地址
+		first, last, err := hostRange(p.Prefix.String(), false)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+		lo, hi = first, last
+	}
+
+	prefix, ok := findFreePrefix(lo, hi, bits, p.sortedRanges())
+	if !ok {
+		return netip.Prefix{}, errors.New(errors.ValidationError, fmt.Sprintf("no free /%d left in pool %q", bits, p.Name))
+	}
+	if err := p.reserve(prefix, description, now); err != nil {
+		return netip.Prefix{}, err
+	}
+	return prefix, nil
+}
+
+// utilization 返回已分配地址数和地址总数
+func (p *ipamPool) utilization() (used, total *big.Int) {
+	used = new(big.Int)
+	for _, a := range p.Allocations {
+		used.Add(used, prefixSize(a.Prefix))
+	}
+	return used, prefixSize(p.Prefix)
+}
+
+// formatPercent 以两位小数输出 used/total 百分比
+func formatPercent(used, total *big.Int) string {
+	if total.Sign() == 0 {
+		return "0.00%"
+	}
+	ratio := new(big.Rat).SetFrac(new(big.Int).Mul(used, big.NewInt(100)), total)
+	return ratio.FloatString(2) + "%"
+}
+
+// withIPAM 加载数据库，执行修改后保存
+func withIPAM(cmd *cobra.Command, modify func(db *ipamDB) error) {
+	path, _ := cmd.Flags().GetString("db")
+	db, err := loadIPAM(path)
+	if err != nil {
+		logger.PrintErrorWithMessage("failed to load IPAM file", err)
+		return
+	}
+	if err := modify(db); err != nil {
+		logger.PrintError(err)
+		return
+	}
+	if err := saveIPAM(path, db); err != nil {
+		logger.PrintErrorWithMessage("failed to save IPAM file", err)
+		return
+	}
+	logger.Debugf("Saved IPAM file %s", path)
+}
+
+func ipamArgs(cmd *cobra.Command, args []string, n int) bool {
+	if len(args) != n {
+		logger.PrintValidationError(fmt.Sprintf("expected %d arguments", n))
+		if err := cmd.Help(); err != nil {
+			logger.PrintErrorWithMessage("failed to show help", err)
+		}
+		return false
+	}
+	return true
+}
+
+func ipamPoolAdd(cmd *cobra.Command, args []string) {
+	if !ipamArgs(cmd, args, 2) {
+		return
+	}
+	description, _ := cmd.Flags().GetString("description")
+	prefix, err := parsePrefix(args[1])
+	if err != nil {
+		logger.PrintErrorWithMessage("failed to parse CIDR address", err)
+		return
+	}
+
+	withIPAM(cmd, func(db *ipamDB) error {
+		if err := db.addPool(args[0], prefix, description); err != nil {
+			return err
+		}
+		fmt.Printf("Pool %s added: %s\n", args[0], prefix)
+		return nil
+	})
+}
+
+func ipamPoolRemove(cmd *cobra.Command, args []string) {
+	if !ipamArgs(cmd, args, 1) {
+		return
+	}
+	withIPAM(cmd, func(db *ipamDB) error {
+		if err := db.removePool(args[0]); err != nil {
+			return err
+		}
+		fmt.Printf("Pool %s removed\n", args[0])
+		return nil
+	})
+}
+
+func ipamAllocate(cmd *cobra.Command, args []string) {
+	if !ipamArgs(cmd, args, 1) {
+		return
+	}
+	size, _ := cmd.Flags().GetInt("size")
+	host, _ := cmd.Flags().GetBool("host")
+	description, _ := cmd.Flags().GetString("description")
+
+	if host == (size != 0) {
+		logger.PrintValidationError("specify exactly one of --size or --host")
+		return
+	}
+
+	withIPAM(cmd, func(db *ipamDB) error {
+		pool, err := db.pool(args[0])
+		if err != nil {
+			return err
+		}
+		bits := size
+		if host {
+			bits = pool.Prefix.Addr().BitLen()
+		}
+		prefix, err := pool.allocate(bits, description, time.Now().UTC())
+		if err != nil {
+			return err
+		}
+		if host {
+			fmt.Println(prefix.Addr())
+		} else {
+			fmt.Println(prefix)
+		}
+		return nil
+	})
+}
+
+func ipamReserve(cmd *cobra.Command, args []string) {
+	if !ipamArgs(cmd, args, 2) {
+		return
+	}
+	description, _ := cmd.Flags().GetString("description")
+	prefix, err := parsePrefix(args[1])
+	if err != nil {
+		logger.PrintErrorWithMessage("failed to parse CIDR address", err)
+		return
+	}
+
+	withIPAM(cmd, func(db *ipamDB) error {
+		pool, err := db.pool(args[0])
+		if err != nil {
+			return err
+		}
+		if err := pool.reserve(prefix, description, time.Now().UTC()); err != nil {
+			return err
+		}
+		fmt.Printf("Reserved %s in pool %s\n", prefix, pool.Name)
+		return nil
+	})
+}
+
+func ipamRelease(cmd *cobra.Command, args []string) {
+	if !ipamArgs(cmd, args, 2) {
+		return
+	}
+	prefix, err := parsePrefix(args[1])
+	if err != nil {
+		logger.PrintErrorWithMessage("failed to parse CIDR address", err)
+		return
+	}
+
+	withIPAM(cmd, func(db *ipamDB) error {
+		pool, err := db.pool(args[0])
+		if err != nil {
+			return err
+		}
+		if err := pool.release(prefix); err != nil {
+			return err
+		}
+		fmt.Printf("Released %s from pool %s\n", prefix, pool.Name)
+		return nil
+	})
+}
+
+func ipamList(cmd *cobra.Command, args []string) {
+	path, _ := cmd.Flags().GetString("db")
+	db, err := loadIPAM(path)
+	if err != nil {
+		logger.PrintErrorWithMessage("failed to load IPAM file", err)
+		return
+	}
+
+	if len(args) == 1 {
+		pool, err := db.pool(args[0])
+		if err != nil {
+			logger.PrintError(err)
+			return
+		}
+		printIPAMPool(pool)
+		return
+	}
+
+	for _, pool := range db.Pools {
+		used, total := pool.utilization()
+		fmt.Printf("%-16s %-22s %s/%s (%s) %d allocations  %s\n",
+			pool.Name, pool.Prefix, used, total, formatPercent(used, total), len(pool.Allocations), pool.Description)
+	}
+}
+
+func printIPAMPool(pool *ipamPool) {
+	used, total := pool.utilization()
+	fmt.Println("Pool:", pool.Name)
+	fmt.Println("Prefix:", pool.Prefix)
+	if pool.Description != "" {
+		fmt.Println("Description:", pool.Description)
+	}
+	fmt.Printf("Utilization: %s/%s (%s)\n", used, total, formatPercent(used, total))
+	fmt.Println("Allocations:", len(pool.Allocations))
+	for _, a := range pool.Allocations {
+		fmt.Printf("  %-22s %s  %s\n", a.Prefix, a.Created.Format(time.RFC3339), a.Description)
+	}
+}
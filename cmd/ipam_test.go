@@ -0,0 +1,188 @@
+//go:build unit
+
+/*
+Copyright © 2024-2025 Auska <luodan0709@live.cn>
+
+*/
+
+package cmd
+
+import (
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFindFreePrefix(t *testing.T) {
+	used := []addrRange{
+		prefixRange(netip.MustParsePrefix("10.0.0.0/24")),
+		prefixRange(netip.MustParsePrefix("10.0.1.0/26")),
+		prefixRange(netip.MustParsePrefix("10.0.2.0/24")),
+	}
+	lo := netip.MustParseAddr("10.0.0.0")
+	hi := netip.MustParseAddr("10.0.3.255")
+
+	tests := []struct {
+		bits     int
+		expected string
+		found    bool
+	}{
+		{26, "10.0.1.64/26", true},
+		{25, "10.0.1.128/25", true},
+		{24, "10.0.3.0/24", true},
+		{23, "", false},
+		{32, "10.0.1.64/32", true},
+	}
+
+	for _, tt := range tests {
+		result, ok := findFreePrefix(lo, hi, tt.bits, used)
+		if ok != tt.found {
+			t.Fatalf("findFreePrefix(/%d) found = %v, want %v", tt.bits, ok, tt.found)
+		}
+		if ok && result.String() != tt.expected {
+			t.Errorf("findFreePrefix(/%d) = %v, want %v", tt.bits, result, tt.expected)
+		}
+	}
+}
+
+func TestIPAMPoolAllocate(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	pool := &ipamPool{Name: "lab", Prefix: netip.MustParsePrefix("192.168.0.0/24")}
+
+	host, err := pool.allocate(32, "gateway", now)
+	if err != nil || host.String() != "192.168.0.1/32" {
+		t.Fatalf("allocate host = %v, %v; want 192.168.0.1/32", host, err)
+	}
+
+	subnet, err := pool.allocate(26, "", now)
+	if err != nil || subnet.String() != "192.168.0.64/26" {
+		t.Fatalf("allocate /26 = %v, %v; want 192.168.0.64/26", subnet, err)
+	}
+
+	if _, err := pool.allocate(24, "", now); err == nil {
+		t.Error("allocate /24 in a used /24 should fail")
+	}
+	if _, err := pool.allocate(16, "", now); err == nil {
+		t.Error("allocate /16 in a /24 pool should fail")
+	}
+
+	if err := pool.reserve(netip.MustParsePrefix("192.168.0.96/27"), "", now); err == nil {
+		t.Error("reserve overlapping prefix should fail")
+	}
+	if err := pool.reserve(netip.MustParsePrefix("192.168.1.0/27"), "", now); err == nil {
+		t.Error("reserve outside pool should fail")
+	}
+
+	if err := pool.release(netip.MustParsePrefix("192.168.0.64/26")); err != nil {
+		t.Fatalf("release() error = %v", err)
+	}
+	if err := pool.release(netip.MustParsePrefix("192.168.0.64/26")); err == nil {
+		t.Error("releasing twice should fail")
+	}
+
+	used, total := pool.utilization()
+	if used.Int64() != 1 || total.Int64() != 256 {
+		t.Errorf("utilization() = %v/%v, want 1/256", used, total)
+	}
+}
+
+func TestIPAMDBPools(t *testing.T) {
+	db := &ipamDB{}
+	if err := db.addPool("a", netip.MustParsePrefix("10.0.0.0/16"), ""); err != nil {
+		t.Fatalf("addPool() error = %v", err)
+	}
+	if err := db.addPool("a", netip.MustParsePrefix("10.1.0.0/16"), ""); err == nil {
+		t.Error("duplicate pool name should fail")
+	}
+	if err := db.addPool("b", netip.MustParsePrefix("10.0.128.0/17"), ""); err == nil {
+		t.Error("overlapping pool should fail")
+	}
+	if err := db.addPool("v6", netip.MustParsePrefix("2001:db8::/48"), ""); err != nil {
+		t.Fatalf("addPool(v6) error = %v", err)
+	}
+
+	pool, err := db.pool("v6")
+	if err != nil {
+		t.Fatalf("pool() error = %v", err)
+	}
+	if _, err := pool.allocate(64, "", time.Now()); err != nil {
+		t.Fatalf("allocate /64 error = %v", err)
+	}
+	if err := db.removePool("v6"); err == nil {
+		t.Error("removing a pool with allocations should fail")
+	}
+	if err := db.removePool("a"); err != nil {
+		t.Errorf("removePool() error = %v", err)
+	}
+}
+
+func TestIPAMSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ipam.json")
+
+	db, err := loadIPAM(path)
+	if err != nil || len(db.Pools) != 0 {
+		t.Fatalf("loadIPAM(missing) = %v, %v; want empty database", db, err)
+	}
+
+	if err := db.addPool("dc1", netip.MustParsePrefix("10.10.0.0/16"), "servers"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Pools[0].reserve(netip.MustParsePrefix("10.10.1.0/24"), "vlan 110", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if err := saveIPAM(path, db); err != nil {
+		t.Fatalf("saveIPAM() error = %v", err)
+	}
+
+	loaded, err := loadIPAM(path)
+	if err != nil {
+		t.Fatalf("loadIPAM() error = %v", err)
+	}
+	if len(loaded.Pools) != 1 || len(loaded.Pools[0].Allocations) != 1 {
+		t.Fatalf("loaded %+v, want one pool with one allocation", loaded)
+	}
+	a := loaded.Pools[0].Allocations[0]
+	if a.Prefix.String() != "10.10.1.0/24" || a.Description != "vlan 110" {
+		t.Errorf("loaded allocation = %+v", a)
+	}
+}
+
+func fileMode(t *testing.T, path string) os.FileMode {
+	t.Helper()
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("os.Stat() error = %v", err)
+	}
+	return info.Mode().Perm()
+}
+
+func TestIPAMSaveKeepsMode(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ipam.json")
+	db := &ipamDB{}
+
+	if err := saveIPAM(path, db); err != nil {
+		t.Fatalf("saveIPAM() error = %v", err)
+	}
+	if mode := fileMode(t, path); mode != 0o644 {
+		t.Errorf("new IPAM file mode = %v, want 0644", mode)
+	}
+
+	if err := os.Chmod(path, 0o640); err != nil {
+		t.Fatal(err)
+	}
+	if err := saveIPAM(path, db); err != nil {
+		t.Fatalf("saveIPAM() error = %v", err)
+	}
+	if mode := fileMode(t, path); mode != 0o640 {
+		t.Errorf("rewritten IPAM file mode = %v, want 0640", mode)
+	}
+}
+
+func TestFormatPercent(t *testing.T) {
+	used, total := prefixSize(netip.MustParsePrefix("10.0.0.0/26")), prefixSize(netip.MustParsePrefix("10.0.0.0/24"))
+	if got := formatPercent(used, total); got != "25.00%" {
+		t.Errorf("formatPercent() = %s, want 25.00%%", got)
+	}
+}
@@ -0,0 +1,207 @@
+/*
+Copyright © 2024-2025 Auska <luodan0709@live.cn>
+
+*/
+
+package cmd
+
+import (
+	"math/big"
+	"net/netip"
+	"sort"
+)
+
+// addrToInt 将 IP 地址转换为大整数
+func addrToInt(addr netip.Addr) *big.Int {
+	b := addr.AsSlice()
+	return new(big.Int).SetBytes(b)
+}
+
+// intToAddr 将大整数转换为指定位宽（32 或 128）的 IP 地址，超出范围时 ok 为 false
+func intToAddr(n *big.Int, bitLen int) (netip.Addr, bool) {
+	if n.Sign() < 0 || n.BitLen() > bitLen {
+		return netip.Addr{}, false
+	}
+
+	b := make([]byte, bitLen/8)
+	n.FillBytes(b)
+	addr, ok := netip.AddrFromSlice(b)
+	return addr, ok
+}
+
+// addrAdd 返回 addr + delta，越过地址空间边界时 ok 为 false
+func addrAdd(addr netip.Addr, delta *big.Int) (netip.Addr, bool) {
+	n := addrToInt(addr)
+	n.Add(n, delta)
+	return intToAddr(n, addr.BitLen())
+}
+
+// addrAddInt64 是 addrAdd 的 int64 版本
+func addrAddInt64(addr netip.Addr, delta int64) (netip.Addr, bool) {
+	return addrAdd(addr, big.NewInt(delta))
+}
+
+// powerOfTwo 返回 2^n
+func powerOfTwo(n int) *big.Int {
+	return new(big.Int).Lsh(big.NewInt(1), uint(n))
+}
+
+// prefixSize 返回网段包含的地址总数
+func prefixSize(prefix netip.Prefix) *big.Int {
+	return powerOfTwo(prefix.Addr().BitLen() - prefix.Bits())
+}
+
+// prefixLastAddr 返回网段的最后一个地址
+func prefixLastAddr(prefix netip.Prefix) netip.Addr {
+	prefix = prefix.Masked()
+	b := prefix.Addr().AsSlice()
+	hostBits := len(b)*8 - prefix.Bits()
+	for i := len(b) - 1; i >= 0 && hostBits > 0; i-- {
+		if hostBits >= 8 {
+			b[i] = 0xff
+			hostBits -= 8
+		} else {
+			b[i] |= byte(1<<hostBits) - 1
+			hostBits = 0
+		}
+	}
+	addr, _ := netip.AddrFromSlice(b)
+	return addr
+}
+
+// addrRange 闭区间 [First, Last] 表示的地址范围
+type addrRange struct {
+	First netip.Addr
+	Last  netip.Addr
+}
+
+// prefixRange 返回网段对应的地址范围
+func prefixRange(prefix netip.Prefix) addrRange {
+	prefix = prefix.Masked()
+	return addrRange{First: prefix.Addr(), Last: prefixLastAddr(prefix)}
+}
+
+// size 返回范围包含的地址数
+func (r addrRange) size() *big.Int {
+	n := new(big.Int).Sub(addrToInt(r.Last), addrToInt(r.First))
+	return n.Add(n, big.NewInt(1))
+}
+
+// rangeRelation 两个地址范围之间的关系
+type rangeRelation string
+
+const (
+	relationEqual     rangeRelation = "equal"
+	relationContains  rangeRelation = "contains"
+	relationContained rangeRelation = "contained"
+	relationOverlap   rangeRelation = "overlap"
+	relationAdjacent  rangeRelation = "adjacent"
+	relationDisjoint  rangeRelation = "disjoint"
+)
+
+// relate 返回 r 相对于 other 的关系，不同地址族视为不相交
+func (r addrRange) relate(other addrRange) rangeRelation {
+	if r.First.BitLen() != other.First.BitLen() {
+		return relationDisjoint
+	}
+
+	firstCmp := r.First.Compare(other.First)
+	lastCmp := r.Last.Compare(other.Last)
+	switch {
+	case firstCmp == 0 && lastCmp == 0:
+		return relationEqual
+	case firstCmp <= 0 && lastCmp >= 0:
+		return relationContains
+	case firstCmp >= 0 && lastCmp <= 0:
+		return relationContained
+	case r.First.Compare(other.Last) <= 0 && other.First.Compare(r.Last) <= 0:
+		return relationOverlap
+	case r.Last.Next() == other.First || other.Last.Next() == r.First:
+		return relationAdjacent
+	default:
+		return relationDisjoint
+	}
+}
+
+// rangeToPrefixes 将地址范围拆分为覆盖它的最少 CIDR 网段
+func rangeToPrefixes(r addrRange) []netip.Prefix {
+	bitLen := r.First.BitLen()
+	start := addrToInt(r.First)
+	end := addrToInt(r.Last)
+
+	var prefixes []netip.Prefix
+	for start.Cmp(end) <= 0 {
+		hostBits := bitLen
+		if start.Sign() != 0 {
+			hostBits = int(start.TrailingZeroBits())
+		}
+
+		span := new(big.Int).Sub(end, start)
+		span.Add(span, big.NewInt(1))
+		for hostBits > 0 && powerOfTwo(hostBits).Cmp(span) > 0 {
+			hostBits--
+		}
+
+		addr, _ := intToAddr(start, bitLen)
+		prefixes = append(prefixes, netip.PrefixFrom(addr, bitLen-hostBits))
+		start.Add(start, powerOfTwo(hostBits))
+	}
+	return prefixes
+}
+
+// mergeRanges 按起始地址排序并合并重叠或相邻的范围，不同地址族分别合并
+func mergeRanges(ranges []addrRange) []addrRange {
+	sorted := append([]addrRange(nil), ranges...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].First.Less(sorted[j].First)
+	})
+
+	var merged []addrRange
+	for _, r := range sorted {
+		if n := len(merged); n > 0 {
+			last := &merged[n-1]
+			if last.First.BitLen() == r.First.BitLen() {
+				next := last.Last.Next()
+				if !next.IsValid() || r.First.Compare(next) <= 0 {
+					if r.Last.Compare(last.Last) > 0 {
+						last.Last = r.Last
+					}
+					continue
+				}
+			}
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
+
+// rangeGaps 返回 [lo, hi] 中未被 used 覆盖的部分，used 必须是 mergeRanges 的结果
+func rangeGaps(lo, hi netip.Addr, used []addrRange) []addrRange {
+	var gaps []addrRange
+	cursor := lo
+	for _, r := range used {
+		if r.First.BitLen() != lo.BitLen() || r.Last.Less(cursor) {
+			continue
+		}
+		if r.First.Compare(hi) > 0 {
+			break
+		}
+		if cursor.Less(r.First) {
+			gaps = append(gaps, addrRange{First: cursor, Last: r.First.Prev()})
+		}
+		cursor = r.Last.Next()
+		if !cursor.IsValid() || cursor.Compare(hi) > 0 {
+			return gaps
+		}
+	}
+	return append(gaps, addrRange{First: cursor, Last: hi})
+}
+
+// subtractRanges 返回 ranges 中未被 remove 覆盖的部分，两者都必须是 mergeRanges 的结果
+func subtractRanges(ranges, remove []addrRange) []addrRange {
+	var result []addrRange
+	for _, r := range ranges {
+		result = append(result, rangeGaps(r.First, r.Last, remove)...)
+	}
+	return result
+}
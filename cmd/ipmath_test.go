@@ -0,0 +1,212 @@
+//go:build unit
+
+/*
+Copyright © 2024-2025 Auska <luodan0709@live.cn>
+
+*/
+
+package cmd
+
+import (
+	"math/big"
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+func TestAddrAdd(t *testing.T) {
+	tests := []struct {
+		name     string
+		addr     string
+		delta    int64
+		expected string
+		ok       bool
+	}{
+		{"IPv4 increment", "192.168.1.255", 1, "192.168.2.0", true},
+		{"IPv4 decrement", "10.0.0.0", -1, "9.255.255.255", true},
+		{"IPv4 overflow", "255.255.255.255", 1, "", false},
+		{"IPv4 underflow", "0.0.0.0", -1, "", false},
+		{"IPv6 carry", "2001:db8::ffff", 1, "2001:db8::1:0", true},
+		{"IPv6 overflow", "ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff", 1, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, ok := addrAddInt64(netip.MustParseAddr(tt.addr), tt.delta)
+			if ok != tt.ok {
+				t.Fatalf("addrAddInt64() ok = %v, want %v", ok, tt.ok)
+			}
+			if ok && result.String() != tt.expected {
+				t.Errorf("addrAddInt64() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestAddrToInt(t *testing.T) {
+	n := addrToInt(netip.MustParseAddr("192.168.1.1"))
+	if n.Int64() != 3232235777 {
+		t.Errorf("addrToInt() = %v, want 3232235777", n)
+	}
+
+	addr, ok := intToAddr(big.NewInt(3232235777), 32)
+	if !ok || addr.String() != "192.168.1.1" {
+		t.Errorf("intToAddr() = %v, %v, want 192.168.1.1", addr, ok)
+	}
+
+	addr, ok = intToAddr(big.NewInt(1), 128)
+	if !ok || addr.String() != "::1" {
+		t.Errorf("intToAddr() = %v, %v, want ::1", addr, ok)
+	}
+}
+
+func TestPrefixLastAddr(t *testing.T) {
+	tests := []struct {
+		prefix   string
+		expected string
+	}{
+		{"192.168.1.0/24", "192.168.1.255"},
+		{"10.0.0.0/13", "10.7.255.255"},
+		{"10.0.0.5/32", "10.0.0.5"},
+		{"0.0.0.0/0", "255.255.255.255"},
+		{"2001:db8::/33", "2001:db8:7fff:ffff:ffff:ffff:ffff:ffff"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.prefix, func(t *testing.T) {
+			result := prefixLastAddr(netip.MustParsePrefix(tt.prefix))
+			if result.String() != tt.expected {
+				t.Errorf("prefixLastAddr() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRangeToPrefixes(t *testing.T) {
+	tests := []struct {
+		first    string
+		last     string
+		expected []string
+	}{
+		{"192.168.1.0", "192.168.1.255", []string{"192.168.1.0/24"}},
+		{"192.168.1.10", "192.168.1.20", []string{"192.168.1.10/31", "192.168.1.12/30", "192.168.1.16/30", "192.168.1.20/32"}},
+		{"10.0.0.5", "10.0.0.5", []string{"10.0.0.5/32"}},
+		{"0.0.0.0", "255.255.255.255", []string{"0.0.0.0/0"}},
+		{"255.255.255.254", "255.255.255.255", []string{"255.255.255.254/31"}},
+		{"2001:db8::", "2001:db8::2", []string{"2001:db8::/127", "2001:db8::2/128"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.first+"-"+tt.last, func(t *testing.T) {
+			r := addrRange{First: netip.MustParseAddr(tt.first), Last: netip.MustParseAddr(tt.last)}
+			result := rangeToPrefixes(r)
+			if len(result) != len(tt.expected) {
+				t.Fatalf("rangeToPrefixes() = %v, want %v", result, tt.expected)
+			}
+			for i := range result {
+				if result[i].String() != tt.expected[i] {
+					t.Errorf("rangeToPrefixes()[%d] = %v, want %v", i, result[i], tt.expected[i])
+				}
+			}
+		})
+	}
+}
+
+// parseRanges 将 "a-b" 列表解析为地址范围，测试辅助函数
+func parseRanges(t *testing.T, specs ...string) []addrRange {
+	t.Helper()
+	ranges := make([]addrRange, 0, len(specs))
+	for _, spec := range specs {
+		r, err := parseAddrRange(spec)
+		if err != nil {
+			t.Fatalf("parseAddrRange(%q) error = %v", spec, err)
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges
+}
+
+func formatRanges(ranges []addrRange) []string {
+	out := make([]string, len(ranges))
+	for i, r := range ranges {
+		out[i] = r.First.String() + "-" + r.Last.String()
+	}
+	return out
+}
+
+func TestMergeRanges(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    []string
+		expected []string
+	}{
+		{"Empty", nil, []string{}},
+		{"Overlapping", []string{"10.0.0.10-10.0.0.20", "10.0.0.0-10.0.0.15"}, []string{"10.0.0.0-10.0.0.20"}},
+		{"Adjacent", []string{"10.0.0.0-10.0.0.9", "10.0.0.10-10.0.0.20"}, []string{"10.0.0.0-10.0.0.20"}},
+		{"Contained", []string{"10.0.0.0-10.0.0.255", "10.0.0.5-10.0.0.6"}, []string{"10.0.0.0-10.0.0.255"}},
+		{"Disjoint", []string{"10.0.1.0-10.0.1.255", "10.0.0.0-10.0.0.9"}, []string{"10.0.0.0-10.0.0.9", "10.0.1.0-10.0.1.255"}},
+		{"End of space", []string{"255.255.255.0-255.255.255.255", "255.255.255.200-255.255.255.255"}, []string{"255.255.255.0-255.255.255.255"}},
+		{"Mixed families", []string{"::-::ff", "0.0.0.0-0.0.0.255"}, []string{"0.0.0.0-0.0.0.255", "::-::ff"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := formatRanges(mergeRanges(parseRanges(t, tt.input...)))
+			if strings.Join(result, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("mergeRanges() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRangeGaps(t *testing.T) {
+	tests := []struct {
+		name     string
+		used     []string
+		expected []string
+	}{
+		{"Nothing used", nil, []string{"10.0.0.0-10.0.0.255"}},
+		{"Middle used", []string{"10.0.0.64-10.0.0.127"}, []string{"10.0.0.0-10.0.0.63", "10.0.0.128-10.0.0.255"}},
+		{"Edges used", []string{"9.0.0.0-10.0.0.9", "10.0.0.250-11.0.0.0"}, []string{"10.0.0.10-10.0.0.249"}},
+		{"All used", []string{"10.0.0.0-10.0.0.255"}, []string{}},
+		{"Outside", []string{"10.0.1.0-10.0.1.255", "::-::1"}, []string{"10.0.0.0-10.0.0.255"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			used := mergeRanges(parseRanges(t, tt.used...))
+			result := formatRanges(rangeGaps(netip.MustParseAddr("10.0.0.0"), netip.MustParseAddr("10.0.0.255"), used))
+			if strings.Join(result, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("rangeGaps() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSubtractRanges(t *testing.T) {
+	tests := []struct {
+		name     string
+		ranges   []string
+		remove   []string
+		expected []string
+	}{
+		{"Nothing removed", []string{"10.0.0.0-10.0.0.255"}, nil, []string{"10.0.0.0-10.0.0.255"}},
+		{"Hole punched", []string{"10.0.0.0-10.0.0.255"}, []string{"10.0.0.10-10.0.0.19"}, []string{"10.0.0.0-10.0.0.9", "10.0.0.20-10.0.0.255"}},
+		{"Spanning removal", []string{"10.0.0.0-10.0.0.9", "10.0.0.20-10.0.0.29"}, []string{"10.0.0.5-10.0.0.24"},
+			[]string{"10.0.0.0-10.0.0.4", "10.0.0.25-10.0.0.29"}},
+		{"All removed", []string{"10.0.0.0-10.0.0.9"}, []string{"10.0.0.0-10.0.0.255"}, []string{}},
+		{"Mixed families", []string{"10.0.0.0-10.0.0.9", "2001:db8::-2001:db8::ff"}, []string{"10.0.0.0-10.0.0.9", "2001:db8::80-2001:db8::ff"},
+			[]string{"2001:db8::-2001:db8::7f"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ranges := mergeRanges(parseRanges(t, tt.ranges...))
+			remove := mergeRanges(parseRanges(t, tt.remove...))
+			result := formatRanges(subtractRanges(ranges, remove))
+			if strings.Join(result, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("subtractRanges() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
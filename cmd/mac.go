@@ -6,10 +6,13 @@ Copyright © 2024-2025 Auska <luodan0709@live.cn>
 package cmd
 
 import (
+	"encoding/hex"
 	"fmt"
+	"net"
 	"strings"
 
 	"github.com/spf13/cobra"
+	"macconv/pkg/errors"
 	"macconv/pkg/logger"
 	"macconv/pkg/validator"
 )
@@ -35,6 +38,24 @@ func normalizeMACAddress(mac string) string {
 	return strings.ToLower(mac)
 }
 
+// parseMACAddress 解析任意分隔符写法的 MAC 地址
+func parseMACAddress(s string) (net.HardwareAddr, error) {
+	mac := normalizeMACAddress(s)
+	if err := validator.ValidateMACAddress(mac); err != nil {
+		return nil, err
+	}
+	b, err := hex.DecodeString(mac)
+	if err != nil {
+		return nil, errors.Wrap(errors.ParseError, fmt.Sprintf("invalid MAC address: %s", s), err)
+	}
+	return net.HardwareAddr(b), nil
+}
+
+// macToEUI64 按 RFC 4291 附录 A 生成修改后的 EUI-64：在中间插入 FFFE 并翻转 U/L 位
+func macToEUI64(mac net.HardwareAddr) [8]byte {
+	return [8]byte{mac[0] ^ 0x02, mac[1], mac[2], 0xff, 0xfe, mac[3], mac[4], mac[5]}
+}
+
 func getMacAddress(cmd *cobra.Command, args []string) {
 	if len(args) != 1 {
 		logger.PrintValidationError("missing MAC address argument")
@@ -8,6 +8,7 @@ Copyright © 2024-2025 Auska <luodan0709@live.cn>
 package cmd
 
 import (
+	"net"
 	"testing"
 
 	"github.com/spf13/cobra"
@@ -103,3 +104,33 @@ func TestGetMacAddress(t *testing.T) {
 		})
 	}
 }
+
+func TestMACToEUI64(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		wantErr  bool
+	}{
+		{"00:11:22:33:44:55", "02:11:22:ff:fe:33:44:55", false},
+		{"0211.2233.4455", "00:11:22:ff:fe:33:44:55", false},
+		{"AA-BB-CC-DD-EE-FF", "a8:bb:cc:ff:fe:dd:ee:ff", false},
+		{"00:11:22:33:44", "", true},
+		{"00:11:22:33:44:zz", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			mac, err := parseMACAddress(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseMACAddress() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			eui64 := macToEUI64(mac)
+			if result := net.HardwareAddr(eui64[:]).String(); result != tt.expected {
+				t.Errorf("macToEUI64() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,300 @@
+/*
+Copyright © 2024-2025 Auska <luodan0709@live.cn>
+
+*/
+
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"macconv/pkg/errors"
+	"macconv/pkg/logger"
+	"macconv/pkg/validator"
+)
+
+// labeledPrefix 带可选标签（站点、VLAN、VPC 名称等）的网段
+type labeledPrefix struct {
+	Prefix netip.Prefix
+	Label  string
+}
+
+// String 返回 "前缀 (标签)" 形式的描述
+func (lp labeledPrefix) String() string {
+	if lp.Label == "" {
+		return lp.Prefix.String()
+	}
+	return fmt.Sprintf("%s (%s)", lp.Prefix, lp.Label)
+}
+
+// parsePrefix 解析 CIDR，裸 IP 视为 /32 或 /128，结果总是规范化为网络地址
+func parsePrefix(s string) (netip.Prefix, error) {
+	s = strings.TrimSpace(s)
+	if strings.Contains(s, "/") {
+		prefix, err := netip.ParsePrefix(s)
+		if err != nil {
+			return netip.Prefix{}, errors.Wrap(errors.ParseError, fmt.Sprintf("invalid CIDR: %s", s), err)
+		}
+		return prefix.Masked(), nil
+	}
+
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, errors.Wrap(errors.ParseError, fmt.Sprintf("invalid IP address: %s", s), err)
+	}
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// parseAddr 解析单个 IP 地址，去掉 IPv4-mapped 前缀以便与 IPv4 网段比较
+func parseAddr(s string) (netip.Addr, error) {
+	addr, err := netip.ParseAddr(strings.TrimSpace(s))
+	if err != nil {
+		return netip.Addr{}, errors.Wrap(errors.ParseError, fmt.Sprintf("invalid IP address: %s", s), err)
+	}
+	return addr.Unmap(), nil
+}
+
+// parseAddrRange 解析 "起始-结束" 形式的地址范围
+// 结束部分可以只写最后一段：10.0.0.1-50 的结束地址为 10.0.0.50，2001:db8::10-ff 为 2001:db8::ff
+func parseAddrRange(s string) (addrRange, error) {
+	first, last, ok := strings.Cut(strings.TrimSpace(s), "-")
+	if !ok {
+		return addrRange{}, errors.New(errors.ParseError, fmt.Sprintf("invalid address range: %s", s))
+	}
+
+	r := addrRange{}
+	var err error
+	if r.First, err = parseAddr(first); err != nil {
+		return addrRange{}, err
+	}
+	last = strings.TrimSpace(last)
+	if strings.ContainsAny(last, ".:") {
+		r.Last, err = parseAddr(last)
+	} else {
+		r.Last, err = replaceLastGroup(r.First, last)
+	}
+	if err != nil {
+		return addrRange{}, err
+	}
+	if r.First.BitLen() != r.Last.BitLen() {
+		return addrRange{}, errors.New(errors.ValidationError, fmt.Sprintf("range %s mixes address families", s))
+	}
+	if r.Last.Less(r.First) {
+		return addrRange{}, errors.New(errors.ValidationError, fmt.Sprintf("range %s ends before it starts", s))
+	}
+	return r, nil
+}
+
+// replaceLastGroup 用 value 替换地址的最后一个字节（IPv4，十进制）或十六位段（IPv6，十六进制）
+func replaceLastGroup(addr netip.Addr, value string) (netip.Addr, error) {
+	b := addr.AsSlice()
+	if addr.Is4() {
+		n, err := strconv.ParseUint(value, 10, 8)
+		if err != nil {
+			return netip.Addr{}, errors.Wrap(errors.ParseError, fmt.Sprintf("invalid last octet: %s", value), err)
+		}
+		b[3] = byte(n)
+	} else {
+		n, err := strconv.ParseUint(value, 16, 16)
+		if err != nil {
+			return netip.Addr{}, errors.Wrap(errors.ParseError, fmt.Sprintf("invalid last hextet: %s", value), err)
+		}
+		b[14], b[15] = byte(n>>8), byte(n)
+	}
+	result, _ := netip.AddrFromSlice(b)
+	return result, nil
+}
+
+// parseIPv4Glob 解析 10.0.0.* 或 10.*.*.* 形式的通配写法，* 只能出现在末尾的字节
+func parseIPv4Glob(s string) (netip.Prefix, error) {
+	parts := strings.Split(strings.TrimSpace(s), ".")
+	if len(parts) != 4 {
+		return netip.Prefix{}, errors.New(errors.ParseError, fmt.Sprintf("invalid wildcard address %s, expected four octets such as 10.0.0.*", s))
+	}
+
+	var b [4]byte
+	bits := 32
+	for i, part := range parts {
+		if part == "*" {
+			if bits == 32 {
+				bits = i * 8
+			}
+			continue
+		}
+		if bits != 32 {
+			return netip.Prefix{}, errors.New(errors.ValidationError,
+				fmt.Sprintf("invalid wildcard address %s, * is only allowed in trailing octets", s))
+		}
+		n, err := strconv.ParseUint(part, 10, 8)
+		if err != nil {
+			return netip.Prefix{}, errors.Wrap(errors.ParseError, fmt.Sprintf("invalid wildcard address %s", s), err)
+		}
+		b[i] = byte(n)
+	}
+	return netip.PrefixFrom(netip.AddrFrom4(b), bits), nil
+}
+
+// parsePrefixes 解析 CIDR、裸 IP、通配写法或地址范围，范围被拆分为最少的 CIDR 网段
+func parsePrefixes(s string) ([]netip.Prefix, error) {
+	if strings.Contains(s, "*") {
+		prefix, err := parseIPv4Glob(s)
+		if err != nil {
+			return nil, err
+		}
+		return []netip.Prefix{prefix}, nil
+	}
+	if strings.Contains(s, "-") {
+		r, err := parseAddrRange(s)
+		if err != nil {
+			return nil, err
+		}
+		return rangeToPrefixes(r), nil
+	}
+
+	prefix, err := parsePrefix(s)
+	if err != nil {
+		return nil, err
+	}
+	return []netip.Prefix{prefix}, nil
+}
+
+// splitPrefixLine 拆分一行网段列表，支持 CSV（逗号分隔）和空白分隔两种格式
+func splitPrefixLine(line string) []string {
+	var fields []string
+	if strings.Contains(line, ",") {
+		for _, field := range strings.Split(line, ",") {
+			field = strings.Trim(strings.TrimSpace(field), `"`)
+			if field != "" {
+				fields = append(fields, field)
+			}
+		}
+		return fields
+	}
+	return strings.Fields(line)
+}
+
+// looksLikeAddress 判断字段是否像地址或网段：含数字且含 "."、":" 或 "/"
+func looksLikeAddress(field string) bool {
+	return strings.ContainsAny(field, "0123456789") && strings.ContainsAny(field, ".:/")
+}
+
+// scanList 逐行读取列表并对每行的字段调用 parse，what 用于错误信息
+// 空行和 # 开头的注释行被忽略；第一条记录无法解析且没有像地址的字段时视为 CSV 表头并跳过
+func scanList(r io.Reader, what string, parse func(fields []string) error) error {
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	seenRecord := false
+
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := splitPrefixLine(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		if err := parse(fields); err != nil {
+			if !seenRecord && !slices.ContainsFunc(fields, looksLikeAddress) {
+				logger.Debugf("Skipping header line %d: %s", lineNo, line)
+				seenRecord = true
+				continue
+			}
+			return errors.Wrap(errors.ParseError, fmt.Sprintf("line %d", lineNo), err)
+		}
+		seenRecord = true
+	}
+
+	if err := scanner.Err(); err != nil {
+		return errors.Wrap(errors.FileSystemError, "failed to read "+what, err)
+	}
+	return nil
+}
+
+// readListFile 打开文件并交给 read 读取，路径为 "-" 时读取标准输入
+func readListFile(path string, read func(r io.Reader) error) error {
+	if path == "-" {
+		return read(os.Stdin)
+	}
+
+	if err := validator.ValidateFilePath(path); err != nil {
+		return err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return errors.Wrap(errors.FileSystemError, fmt.Sprintf("failed to open %s", path), err)
+	}
+	defer func() {
+		if closeErr := f.Close(); closeErr != nil {
+			logger.Debugf("Error closing %s: %v", path, closeErr)
+		}
+	}()
+
+	return read(f)
+}
+
+// parsePrefixList 读取网段列表，每行第一列为 CIDR、通配写法或地址范围，其余列作为标签
+func parsePrefixList(r io.Reader) ([]labeledPrefix, error) {
+	var prefixes []labeledPrefix
+	err := scanList(r, "prefix list", func(fields []string) error {
+		parsed, err := parsePrefixes(fields[0])
+		if err != nil {
+			return err
+		}
+		label := strings.Join(fields[1:], " ")
+		for _, prefix := range parsed {
+			prefixes = append(prefixes, labeledPrefix{Prefix: prefix, Label: label})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return prefixes, nil
+}
+
+// readPrefixFile 从文件读取网段列表，路径为 "-" 时读取标准输入
+func readPrefixFile(path string) ([]labeledPrefix, error) {
+	var prefixes []labeledPrefix
+	err := readListFile(path, func(r io.Reader) error {
+		var err error
+		prefixes, err = parsePrefixList(r)
+		return err
+	})
+	return prefixes, err
+}
+
+// collectPrefixes 合并命令行参数与文件中的网段，通配写法和地址范围被转换为 CIDR
+func collectPrefixes(args []string, file string) ([]labeledPrefix, error) {
+	prefixes := make([]labeledPrefix, 0, len(args))
+	for _, arg := range args {
+		parsed, err := parsePrefixes(arg)
+		if err != nil {
+			return nil, err
+		}
+		for _, prefix := range parsed {
+			prefixes = append(prefixes, labeledPrefix{Prefix: prefix})
+		}
+	}
+
+	if file != "" {
+		fromFile, err := readPrefixFile(file)
+		if err != nil {
+			return nil, err
+		}
+		prefixes = append(prefixes, fromFile...)
+	}
+
+	return prefixes, nil
+}
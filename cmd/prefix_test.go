@@ -0,0 +1,160 @@
+//go:build unit
+
+/*
+Copyright © 2024-2025 Auska <luodan0709@live.cn>
+
+*/
+
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParsePrefix(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+		wantErr  bool
+	}{
+		{
+			name:     "IPv4 CIDR",
+			input:    "10.0.0.0/8",
+			expected: "10.0.0.0/8",
+		},
+		{
+			name:     "IPv4 CIDR with host bits",
+			input:    "192.168.1.77/24",
+			expected: "192.168.1.0/24",
+		},
+		{
+			name:     "Bare IPv4 address",
+			input:    "192.168.1.1",
+			expected: "192.168.1.1/32",
+		},
+		{
+			name:     "Bare IPv6 address",
+			input:    "2001:db8::1",
+			expected: "2001:db8::1/128",
+		},
+		{
+			name:    "Invalid prefix length",
+			input:   "10.0.0.0/33",
+			wantErr: true,
+		},
+		{
+			name:    "Garbage",
+			input:   "not-a-cidr",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prefix, err := parsePrefix(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parsePrefix() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && prefix.String() != tt.expected {
+				t.Errorf("parsePrefix() = %v, want %v", prefix, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParsePrefixList(t *testing.T) {
+	input := `prefix,site,vlan
+# comment line
+10.0.0.0/8,corp
+10.1.0.0/16,"site-a",100
+
+192.168.0.0/24 lab bench
+`
+	prefixes, err := parsePrefixList(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("parsePrefixList() error = %v", err)
+	}
+
+	expected := []struct {
+		prefix string
+		label  string
+	}{
+		{"10.0.0.0/8", "corp"},
+		{"10.1.0.0/16", "site-a 100"},
+		{"192.168.0.0/24", "lab bench"},
+	}
+
+	if len(prefixes) != len(expected) {
+		t.Fatalf("parsePrefixList() returned %d prefixes, want %d", len(prefixes), len(expected))
+	}
+	for i, want := range expected {
+		if prefixes[i].Prefix.String() != want.prefix {
+			t.Errorf("prefixes[%d].Prefix = %v, want %v", i, prefixes[i].Prefix, want.prefix)
+		}
+		if prefixes[i].Label != want.label {
+			t.Errorf("prefixes[%d].Label = %q, want %q", i, prefixes[i].Label, want.label)
+		}
+	}
+}
+
+func TestParsePrefixListInvalidLine(t *testing.T) {
+	for _, input := range []string{
+		"10.0.0.0/8\nbogus\n",
+		"10.0.0.0/33\n10.1.0.0/16\n", // 第一行的拼写错误不能当作表头跳过
+		"# ranges\n192.168.1.0/24,lab\n10.0.0.300/32\n",
+		"ipv4 prefix,site\n10.0.0.0/8\nbogus\n", // 表头只跳过一次
+	} {
+		if _, err := parsePrefixList(strings.NewReader(input)); err == nil {
+			t.Errorf("parsePrefixList(%q) expected error for invalid line", input)
+		}
+	}
+
+	prefixes, err := parsePrefixList(strings.NewReader("IPv6 Prefix,Site\n2001:db8::/32,lab\n"))
+	if err != nil || len(prefixes) != 1 {
+		t.Errorf("parsePrefixList() with header = %v, %v, want one prefix", prefixes, err)
+	}
+}
+
+func TestParsePrefixes(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+		wantErr  bool
+	}{
+		{"10.0.0.0/8", []string{"10.0.0.0/8"}, false},
+		{"10.0.0.1", []string{"10.0.0.1/32"}, false},
+		{"10.0.0.0-10.0.1.255", []string{"10.0.0.0/23"}, false},
+		{" 10.0.0.1 - 10.0.0.2 ", []string{"10.0.0.1/32", "10.0.0.2/32"}, false},
+		{"10.0.0.9-10.0.0.1", nil, true},
+		{"10.0.0.1-2001:db8::1", nil, true},
+		{"10.0.0.1-", nil, true},
+		{"10.0.0.4-7", []string{"10.0.0.4/30"}, false},
+		{"10.0.0.1-256", nil, true},
+		{"2001:db8::10-1f", []string{"2001:db8::10/124"}, false},
+		{"10.0.0.*", []string{"10.0.0.0/24"}, false},
+		{"172.16.*.*", []string{"172.16.0.0/16"}, false},
+		{"*.*.*.*", []string{"0.0.0.0/0"}, false},
+		{"10.*.0.1", nil, true},
+		{"10.0.*", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			result, err := parsePrefixes(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parsePrefixes() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(result) != len(tt.expected) {
+				t.Fatalf("parsePrefixes() = %v, want %v", result, tt.expected)
+			}
+			for i := range result {
+				if result[i].String() != tt.expected[i] {
+					t.Errorf("parsePrefixes()[%d] = %v, want %v", i, result[i], tt.expected[i])
+				}
+			}
+		})
+	}
+}
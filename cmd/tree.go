@@ -0,0 +1,42 @@
+/*
+Copyright © 2024-2025 Auska <luodan0709@live.cn>
+
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+)
+
+// treeNode 以树形输出的一个节点
+type treeNode struct {
+	Label    string
+	Children []*treeNode
+}
+
+// writeTree 以 ├── / └── 连线输出树，maxDepth 大于 0 时只输出到该深度，
+// 被省略的子节点以数量提示代替
+func writeTree(w io.Writer, root *treeNode, maxDepth int) {
+	fmt.Fprintln(w, root.Label)
+	writeTreeChildren(w, root, "", 1, maxDepth)
+}
+
+func writeTreeChildren(w io.Writer, node *treeNode, indent string, depth, maxDepth int) {
+	if maxDepth > 0 && depth > maxDepth {
+		if len(node.Children) > 0 {
+			fmt.Fprintf(w, "%s└── ... %d not shown\n", indent, len(node.Children))
+		}
+		return
+	}
+
+	for i, child := range node.Children {
+		branch, next := "├── ", "│   "
+		if i == len(node.Children)-1 {
+			branch, next = "└── ", "    "
+		}
+		fmt.Fprintf(w, "%s%s%s\n", indent, branch, child.Label)
+		writeTreeChildren(w, child, indent+next, depth+1, maxDepth)
+	}
+}
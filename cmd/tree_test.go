@@ -0,0 +1,43 @@
+//go:build unit
+
+/*
+Copyright © 2024-2025 Auska <luodan0709@live.cn>
+
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriteTree(t *testing.T) {
+	root := &treeNode{Label: "root", Children: []*treeNode{
+		{Label: "a", Children: []*treeNode{{Label: "a1"}, {Label: "a2"}}},
+		{Label: "b", Children: []*treeNode{{Label: "b1"}}},
+	}}
+
+	var buf bytes.Buffer
+	writeTree(&buf, root, 0)
+	expected := "root\n" +
+		"├── a\n" +
+		"│   ├── a1\n" +
+		"│   └── a2\n" +
+		"└── b\n" +
+		"    └── b1\n"
+	if buf.String() != expected {
+		t.Errorf("writeTree() =\n%s\nwant\n%s", buf.String(), expected)
+	}
+
+	buf.Reset()
+	writeTree(&buf, root, 1)
+	expected = "root\n" +
+		"├── a\n" +
+		"│   └── ... 2 not shown\n" +
+		"└── b\n" +
+		"    └── ... 1 not shown\n"
+	if buf.String() != expected {
+		t.Errorf("writeTree(depth 1) =\n%s\nwant\n%s", buf.String(), expected)
+	}
+}